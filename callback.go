@@ -0,0 +1,597 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/util"
+)
+
+// The default timeout of each callback attempt
+const defaultCallbackTimeout = 10 * time.Second
+
+// The default number of callbacks which can be sent concurrently
+const defaultCallbackWorkers = 100
+
+// The maximum number of redirects which are followed by an attempt, same as the default of http client
+const maxCallbackRedirects = 10
+
+// Defines the limits of callback retry, so that a stub cannot keep a worker busy for too long
+const (
+	maxCallbackAttempts = 10
+	maxCallbackBackoff  = time.Minute
+)
+
+// Callback defines an outbound http request which is sent after the stub is matched
+// This is to emulate webhooks. For example: a payment gateway notifies the payment result to the merchant
+// URL, header values and body template are go templates which are executed with the same data as response template
+type Callback struct {
+	URL string `json:"url" yaml:"url"`
+
+	// Method is optional, default is POST
+	Method string `json:"method,omitempty" yaml:"method"`
+
+	Header map[string]string `json:"header,omitempty" yaml:"header"`
+
+	// Body is the raw body which is sent if body template is not defined
+	Body Body `json:"body,omitempty" yaml:"body"`
+
+	// BodyTemplate renders body from the incoming request. For example: {"order_id": "{{ .JSONBody.id }}"}
+	BodyTemplate string `json:"body_template,omitempty" yaml:"body_template"`
+
+	// Delay is the duration to wait after the response is written
+	Delay time.Duration `json:"delay,omitempty" swaggertype:"primitive,integer" yaml:"delay"`
+
+	// Timeout is the timeout of each attempt, default is 10 seconds
+	Timeout time.Duration `json:"timeout,omitempty" swaggertype:"primitive,integer" yaml:"timeout"`
+
+	// Retry is optional, the callback is sent only once if it is not defined
+	Retry *CallbackRetry `json:"retry,omitempty" yaml:"retry"`
+}
+
+// CallbackRetry defines the retry settings of callback
+// An attempt is failed if the request cannot be sent or the status code is not 2xx
+type CallbackRetry struct {
+	// MaxAttempts is the maximum number of attempts including the first one, must not be greater than 10
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+
+	// Backoff is the duration to wait between attempts, must not be greater than 1 minute
+	Backoff time.Duration `json:"backoff,omitempty" swaggertype:"primitive,integer" yaml:"backoff"`
+}
+
+// NewCallback is convenient constructor to initialize a POST callback
+func NewCallback(url string) *Callback {
+	return &Callback{URL: url, Method: http.MethodPost}
+}
+
+// WithMethod sets method
+func (c *Callback) WithMethod(method string) *Callback {
+	c.Method = method
+	return c
+}
+
+// WithHeader sets header, value can be a go template
+func (c *Callback) WithHeader(name string, value string) *Callback {
+	if c.Header == nil {
+		c.Header = map[string]string{}
+	}
+
+	c.Header[name] = value
+	return c
+}
+
+// WithBody sets the raw body with content type
+func (c *Callback) WithBody(contentType string, body []byte) *Callback {
+	c.Body = body
+	return c.WithHeader(HeaderContentType, contentType)
+}
+
+// WithJSONBody sets the JSON encoded body
+func (c *Callback) WithJSONBody(v interface{}) *Callback {
+	_, b := MustToJSON(v)
+	return c.WithBody(ContentTypeJSON, b)
+}
+
+// WithBodyTemplate sets the go template to render body
+func (c *Callback) WithBodyTemplate(script string) *Callback {
+	c.BodyTemplate = script
+	return c
+}
+
+// WithDelay sets the duration to wait after the response is written
+func (c *Callback) WithDelay(d time.Duration) *Callback {
+	c.Delay = d
+	return c
+}
+
+// WithTimeout sets the timeout of each attempt
+func (c *Callback) WithTimeout(d time.Duration) *Callback {
+	c.Timeout = d
+	return c
+}
+
+// WithRetry sets the maximum number of attempts and the duration between attempts
+func (c *Callback) WithRetry(maxAttempts int, backoff time.Duration) *Callback {
+	c.Retry = &CallbackRetry{MaxAttempts: maxAttempts, Backoff: backoff}
+	return c
+}
+
+// Validate returns a non-nil error if invalid
+func (c *Callback) Validate(ctx context.Context) error {
+	var err error
+	switch {
+	case c == nil:
+		err = errors.New("callback must not be nil")
+	case len(c.URL) == 0:
+		err = errors.New("missing callback url")
+	case c.Delay < 0 || c.Timeout < 0:
+		err = errors.New("delay and timeout of callback must not be negative")
+	case c.Retry != nil && (c.Retry.MaxAttempts < 1 || c.Retry.Backoff < 0):
+		err = errors.New("max attempts of callback must be positive and backoff must not be negative")
+	case c.Retry != nil && (c.Retry.MaxAttempts > maxCallbackAttempts || c.Retry.Backoff > maxCallbackBackoff):
+		err = fmt.Errorf("max attempts of callback must not be greater than %d and backoff must not be greater than %s", maxCallbackAttempts, maxCallbackBackoff)
+	}
+
+	if err != nil {
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// Clone clones callback
+func (c *Callback) Clone() *Callback {
+	if c == nil {
+		return nil
+	}
+
+	nc := *c
+	if c.Header != nil {
+		nc.Header = make(map[string]string, len(c.Header))
+		for k, v := range c.Header {
+			nc.Header[k] = v
+		}
+	}
+
+	if c.Body != nil {
+		nc.Body = append(Body(nil), c.Body...)
+	}
+
+	if c.Retry != nil {
+		retry := *c.Retry
+		nc.Retry = &retry
+	}
+
+	return &nc
+}
+
+func cloneCallbacks(callbacks []*Callback) []*Callback {
+	if callbacks == nil {
+		return nil
+	}
+
+	cloned := make([]*Callback, len(callbacks))
+	for i, c := range callbacks {
+		cloned[i] = c.Clone()
+	}
+
+	return cloned
+}
+
+func (c *Callback) maxAttempts() int {
+	if c.Retry == nil || c.Retry.MaxAttempts < 1 {
+		return 1
+	}
+
+	return c.Retry.MaxAttempts
+}
+
+func (c *Callback) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultCallbackTimeout
+	}
+
+	return c.Timeout
+}
+
+// render executes the templates of callback, then returns the request to be sent
+func (c *Callback) render(ctx context.Context, data *TemplateData) (*CallbackResult, error) {
+	result := &CallbackResult{Method: c.Method, Header: make(map[string]string, len(c.Header)), Body: c.Body}
+	if len(result.Method) == 0 {
+		result.Method = http.MethodPost
+	}
+
+	callbackURL, err := executeText(ctx, "Callback URL", c.URL, data)
+	if err != nil {
+		return nil, err
+	}
+
+	result.URL = string(callbackURL)
+	if _, err := url.ParseRequestURI(result.URL); err != nil {
+		log.Error(ctx, "invalid callback url", err)
+		return nil, err
+	}
+
+	for name, value := range c.Header {
+		v, err := executeText(ctx, "Callback Header", value, data)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Header[name] = string(v)
+	}
+
+	if len(c.BodyTemplate) > 0 {
+		if result.Body, err = executeText(ctx, "Callback Body", c.BodyTemplate, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := result.Header[HeaderContentType]; !ok && json.Valid(result.Body) {
+		result.Header[HeaderContentType] = ContentTypeJSON
+	}
+
+	return result, nil
+}
+
+// CallbackResult records the delivery of a callback
+type CallbackResult struct {
+	ID        int64  `json:"id" yaml:"id"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Tag       string `json:"tag" yaml:"tag"`
+	StubID    int64  `json:"stub_id" yaml:"stub_id"`
+
+	// IncomingRequestID is the id of the request which triggers the callback
+	IncomingRequestID int64 `json:"incoming_request_id" yaml:"incoming_request_id"`
+
+	URL    string            `json:"url" yaml:"url"`
+	Method string            `json:"method" yaml:"method"`
+	Header map[string]string `json:"header" yaml:"header" gorm:"serializer:json"`
+	Body   []byte            `json:"body" yaml:"body"`
+
+	// StatusCode is the status code of the last attempt, it is zero if the request cannot be sent
+	StatusCode int `json:"status_code" yaml:"status_code"`
+	Attempts   int `json:"attempts" yaml:"attempts"`
+
+	// Delivered is true if an attempt receives a 2xx status code
+	Delivered bool `json:"delivered" yaml:"delivered"`
+
+	// Error is the error of the last attempt
+	Error string `json:"error,omitempty" yaml:"error"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" yaml:"updated_at"`
+}
+
+// CallbackResults holds a list of callback results
+type CallbackResults struct {
+	Callbacks []*CallbackResult `json:"callbacks" yaml:"callbacks"`
+}
+
+// CallbackQueryOption defines query option for callback results
+type CallbackQueryOption struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+
+	// StubID and IncomingRequestID are optional filters
+	StubID            int64 `json:"stub_id" yaml:"stub_id"`
+	IncomingRequestID int64 `json:"incoming_request_id" yaml:"incoming_request_id"`
+
+	Limit int `json:"limit" yaml:"limit"`
+}
+
+type pendingCallback struct {
+	callback *Callback
+	result   *CallbackResult
+}
+
+// CallbackDispatcher sends the callbacks of a matched stub in background
+// The templates are rendered when the request is matched because the request body is not available after the request is completed
+type CallbackDispatcher struct {
+	sender    *CallbackSender
+	callbacks []*pendingCallback
+}
+
+// NewCallbackDispatcher renders the callbacks of stub. Returns nil if the stub does not define any callback
+func NewCallbackDispatcher(ctx context.Context, sender *CallbackSender, stub *Stub, data *TemplateData) (*CallbackDispatcher, error) {
+	if len(stub.Callbacks) == 0 {
+		return nil, nil
+	}
+
+	if sender == nil {
+		log.Error(ctx, "callback sender is not set, skip callbacks of stub", stub.ID)
+		return nil, nil
+	}
+
+	d := &CallbackDispatcher{sender: sender, callbacks: make([]*pendingCallback, 0, len(stub.Callbacks))}
+	for _, c := range stub.Callbacks {
+		result, err := c.render(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Tag = stub.Tag
+		result.StubID = stub.ID
+		d.callbacks = append(d.callbacks, &pendingCallback{callback: c, result: result})
+	}
+
+	return d, nil
+}
+
+// Dispatch sends the callbacks in background, then records the results
+// The incoming request must be saved before dispatching, so that the results are linked to the request
+func (d *CallbackDispatcher) Dispatch(ctx context.Context, r *IncomingRequest) {
+	if d == nil {
+		return
+	}
+
+	for _, c := range d.callbacks {
+		c.result.Namespace = r.Namespace
+		c.result.IncomingRequestID = r.ID
+
+		d.sender.dispatch(ctx, c)
+	}
+}
+
+// CallbackHosts is the list of hosts which callbacks can be sent to
+// A host can be a wildcard which matches any sub domains. For example: *.partner.com. A single * matches any host
+type CallbackHosts []string
+
+// ParseCallbackHosts parses a comma separated list of hosts. For example: localhost,*.partner.com
+func ParseCallbackHosts(s string) CallbackHosts {
+	hosts := CallbackHosts{}
+	for _, host := range strings.Split(s, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if len(host) > 0 {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+// Allow returns true if the host is in the list, host must not contain port
+func (h CallbackHosts) Allow(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range h {
+		if allowed == "*" || allowed == host {
+			return true
+		}
+
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CallbackSender sends callbacks in background with a bounded number of workers
+// It is shared by handlers of a server and must be closed when the server is shut down
+type CallbackSender struct {
+	store        StubStore
+	client       *http.Client
+	allowedHosts CallbackHosts
+	workers      chan struct{}
+
+	// ctx is cancelled when the sender is closed, so that pending callbacks stop waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewCallbackSender returns a new instance with the default number of workers
+// Callbacks can be sent to any host, use WithAllowedHosts to restrict the hosts
+func NewCallbackSender(store StubStore) *CallbackSender {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &CallbackSender{
+		store:        store,
+		allowedHosts: CallbackHosts{"*"},
+		workers:      make(chan struct{}, defaultCallbackWorkers),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	s.client = &http.Client{CheckRedirect: s.checkRedirect}
+	return s
+}
+
+// WithAllowedHosts sets the hosts which callbacks can be sent to. Callbacks to other hosts are not sent
+// This is to prevent a stub from making the server send requests to internal services
+func (s *CallbackSender) WithAllowedHosts(hosts CallbackHosts) *CallbackSender {
+	s.allowedHosts = hosts
+	return s
+}
+
+// WithWorkers sets the maximum number of callbacks which are sent concurrently
+// Callbacks are rejected if all workers are busy. This must be set before the sender is used
+func (s *CallbackSender) WithWorkers(n int) *CallbackSender {
+	if n > 0 {
+		s.workers = make(chan struct{}, n)
+	}
+
+	return s
+}
+
+// Close cancels the pending callbacks, then waits until their results are recorded
+// Callbacks which are dispatched after closing are skipped
+func (s *CallbackSender) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *CallbackSender) dispatch(ctx context.Context, c *pendingCallback) {
+	ctx = context.WithoutCancel(ctx)
+
+	if host := getURLHost(c.result.URL); !s.allowedHosts.Allow(host) {
+		c.result.Error = fmt.Sprintf("callback host %s is not allowed", host)
+		log.Error(ctx, "cannot send callback", c.result.URL, c.result.Error)
+		s.saveResult(ctx, c.result)
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		log.Info(ctx, "sender is closed, skip callback", c.result.URL)
+		return
+	}
+
+	select {
+	case s.workers <- struct{}{}:
+	default:
+		s.mu.Unlock()
+		c.result.Error = "too many pending callbacks"
+		log.Error(ctx, "cannot send callback", c.result.URL, c.result.Error)
+		s.saveResult(ctx, c.result)
+		return
+	}
+
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			<-s.workers
+			s.wg.Done()
+		}()
+
+		s.send(ctx, c)
+	}()
+}
+
+func (s *CallbackSender) send(ctx context.Context, c *pendingCallback) {
+	// The context keeps the values of request for logging, but it is cancelled when the sender is closed
+	sendCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	result := c.result
+	if err := Sleep(sendCtx, c.callback.Delay); err != nil {
+		result.Error = err.Error()
+		s.saveResult(ctx, result)
+		return
+	}
+
+	for result.Attempts < c.callback.maxAttempts() {
+		if result.Attempts > 0 && c.callback.Retry != nil {
+			if err := Sleep(sendCtx, c.callback.Retry.Backoff); err != nil {
+				result.Error = err.Error()
+				break
+			}
+		}
+
+		result.Attempts++
+		result.StatusCode, result.Error = 0, ""
+
+		statusCode, err := s.sendAttempt(sendCtx, result, c.callback.timeout())
+		result.StatusCode = statusCode
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		if statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+			result.Delivered = true
+			break
+		}
+	}
+
+	log.Info(ctx, "sent callback", result.Method, result.URL, "delivered", result.Delivered, "attempts", result.Attempts)
+	s.saveResult(ctx, result)
+}
+
+func (s *CallbackSender) saveResult(ctx context.Context, result *CallbackResult) {
+	if err := s.store.CreateCallbackResult(ctx, result); err != nil {
+		log.Error(ctx, "cannot save callback result", err)
+	}
+}
+
+// checkRedirect stops redirecting to a host which is not allowed
+func (s *CallbackSender) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxCallbackRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxCallbackRedirects)
+	}
+
+	if !s.allowedHosts.Allow(req.URL.Hostname()) {
+		return fmt.Errorf("callback host %s is not allowed", req.URL.Hostname())
+	}
+
+	return nil
+}
+
+// sendAttempt sends an attempt and returns the status code
+func (s *CallbackSender) sendAttempt(ctx context.Context, r *CallbackResult, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		log.Error(ctx, err)
+		return 0, err
+	}
+
+	if id := log.GetID(ctx); len(id) > 0 {
+		req.Header.Set("X-REQUEST-ID", id)
+	}
+
+	for name, value := range r.Header {
+		req.Header.Set(name, value)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		log.Error(ctx, err)
+		return 0, err
+	}
+	defer util.CloseSilently(ctx, res.Body.Close)
+
+	// Drain body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		log.Info(ctx, "callback", r.URL, "returns status", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// validateCallbacks returns an error if any callback of stub is invalid
+func (s *Stub) validateCallbacks(ctx context.Context) error {
+	for _, c := range s.Callbacks {
+		if err := c.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getURLHost returns the host of url without port, returns empty if url is invalid
+func getURLHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
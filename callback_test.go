@@ -0,0 +1,301 @@
+package rio
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungdv136/rio/internal/netkit"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallback_Validate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.NoError(t, NewCallback("http://localhost/webhook").WithRetry(3, time.Second).Validate(ctx))
+	require.NoError(t, NewCallback("http://localhost/webhook").WithRetry(maxCallbackAttempts, maxCallbackBackoff).Validate(ctx))
+
+	invalidCallbacks := []*Callback{
+		nil,
+		NewCallback(""),
+		NewCallback("http://localhost/webhook").WithDelay(-time.Second),
+		NewCallback("http://localhost/webhook").WithRetry(0, time.Second),
+		NewCallback("http://localhost/webhook").WithRetry(3, -time.Second),
+		NewCallback("http://localhost/webhook").WithRetry(maxCallbackAttempts+1, time.Second),
+		NewCallback("http://localhost/webhook").WithRetry(3, maxCallbackBackoff+time.Second),
+	}
+
+	for _, c := range invalidCallbacks {
+		require.Error(t, c.Validate(ctx))
+	}
+
+	stub := NewStub().For(http.MethodPost, EqualTo("/payment")).WillReturn(NewResponse()).WithCallback(NewCallback(""))
+	require.Error(t, stub.Validate(ctx))
+
+	// Callbacks of the cloned stub are not shared
+	stub.Callbacks[0].URL = "http://localhost/webhook"
+	cloned := stub.Clone()
+	cloned.Callbacks[0].WithHeader("X-Signature", "abc")
+	require.Empty(t, stub.Callbacks[0].Header)
+}
+
+func TestCallback_Render(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/payment/123", strings.NewReader(`{"amount": 100, "callback_url": "http://merchant/notify"}`))
+	require.NoError(t, err)
+
+	c := NewCallback("{{ .JSONBody.callback_url }}").
+		WithMethod(http.MethodPut).
+		WithHeader("X-Payment-Id", "{{ .PathParams.id }}").
+		WithBodyTemplate(`{"id": "{{ .PathParams.id }}", "amount": {{ .JSONBody.amount }}, "status": "paid"}`)
+
+	result, err := c.render(ctx, &TemplateData{Request: request, PathParams: map[string]string{"id": "123"}})
+	require.NoError(t, err)
+	require.Equal(t, "http://merchant/notify", result.URL)
+	require.Equal(t, http.MethodPut, result.Method)
+	require.Equal(t, map[string]string{"X-Payment-Id": "123", HeaderContentType: ContentTypeJSON}, result.Header)
+	require.JSONEq(t, `{"id": "123", "amount": 100, "status": "paid"}`, string(result.Body))
+
+	_, err = NewCallback("{{ .Unknown }").render(ctx, &TemplateData{Request: request})
+	require.Error(t, err)
+}
+
+func TestLocalServer_Callback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	merchantServer := NewLocalServerWithReporter(t)
+	server := NewLocalServerWithReporter(t)
+
+	// The merchant fails the first notification, so the callback is retried
+	require.NoError(t, NewStub().For(http.MethodPost, Contains("notify")).
+		WillReturnSequence(SequentialResponses(NewResponse().WithStatusCode(http.StatusInternalServerError), NewResponse())).
+		Send(ctx, merchantServer))
+
+	callback := NewCallback(merchantServer.GetURL(ctx)+"/notify").
+		WithBodyTemplate(`{"id": "{{ .PathParams.id }}", "status": "paid"}`).
+		WithDelay(10*time.Millisecond).
+		WithRetry(3, 10*time.Millisecond)
+
+	stub := NewStub().For(http.MethodPost).WithPath("/payment/{id}").
+		WillReturn(JSONResponse(types.Map{"status": "pending"}).WithStatusCode(http.StatusAccepted)).
+		WithCallback(callback)
+	require.NoError(t, stub.Send(ctx, server))
+
+	res, err := netkit.PostJSON[types.Map](ctx, server.GetURL(ctx)+"/payment/123", types.Map{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	requests, err := server.GetIncomingRequests(ctx, &IncomingQueryOption{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+
+	var callbacks []*CallbackResult
+	require.Eventually(t, func() bool {
+		callbacks, err = server.GetCallbacks(ctx, &CallbackQueryOption{StubID: stub.ID})
+		require.NoError(t, err)
+		return len(callbacks) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, callbacks, 1)
+	require.True(t, callbacks[0].Delivered)
+	require.Equal(t, 2, callbacks[0].Attempts)
+	require.Equal(t, http.StatusOK, callbacks[0].StatusCode)
+	require.Equal(t, requests[0].ID, callbacks[0].IncomingRequestID)
+
+	notifications, err := merchantServer.GetIncomingRequests(ctx, &IncomingQueryOption{})
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	require.JSONEq(t, `{"id": "123", "status": "paid"}`, string(notifications[0].Body))
+}
+
+func TestLocalServer_CallbackNotDelivered(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t)
+
+	// There is no stub for the callback, so the server itself returns not found
+	stub := NewStub().For(http.MethodGet, Contains("order")).
+		WillReturn(JSONResponse(types.Map{"status": "created"})).
+		WithCallback(NewCallback(server.GetURL(ctx)+"/not_found").WithRetry(2, 0))
+	require.NoError(t, stub.Send(ctx, server))
+
+	res, err := netkit.Get[types.Map](ctx, server.GetURL(ctx)+"/order")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var callbacks []*CallbackResult
+	require.Eventually(t, func() bool {
+		callbacks, err = server.GetCallbacks(ctx, &CallbackQueryOption{})
+		require.NoError(t, err)
+		return len(callbacks) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.False(t, callbacks[0].Delivered)
+	require.Equal(t, 2, callbacks[0].Attempts)
+	require.Equal(t, http.StatusNotFound, callbacks[0].StatusCode)
+}
+
+func TestCallbackSender_Close(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	sender := NewCallbackSender(store).WithWorkers(1)
+
+	stub := NewStub().For(http.MethodGet, Contains("order")).
+		WillReturn(NewResponse()).
+		WithCallback(NewCallback("http://localhost/webhook").WithDelay(time.Hour))
+
+	dispatch := func(id int64) {
+		callbacks, err := NewCallbackDispatcher(ctx, sender, stub, &TemplateData{})
+		require.NoError(t, err)
+		callbacks.Dispatch(ctx, &IncomingRequest{ID: id})
+	}
+
+	// The only worker is waiting for the delay, so the second callback is rejected
+	dispatch(1)
+	dispatch(2)
+
+	results, err := store.GetCallbackResults(ctx, &CallbackQueryOption{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, int64(2), results[0].IncomingRequestID)
+	require.Equal(t, "too many pending callbacks", results[0].Error)
+
+	// Closing cancels the pending callback and waits until its result is recorded
+	sender.Close()
+	results, err = store.GetCallbackResults(ctx, &CallbackQueryOption{IncomingRequestID: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.False(t, results[0].Delivered)
+	require.Zero(t, results[0].Attempts)
+	require.Equal(t, context.Canceled.Error(), results[0].Error)
+
+	// Callbacks are skipped after closing
+	dispatch(3)
+	results, err = store.GetCallbackResults(ctx, &CallbackQueryOption{IncomingRequestID: 3})
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestLocalServer_CallbackSkipped(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServer()
+
+	// Callbacks are not sent if the response cannot be written or the client cancels the request while waiting for delay
+	require.NoError(t, server.Create(ctx,
+		NewStub().For(http.MethodGet, Contains("missing_file")).
+			WillReturn(NewResponse().WithFileBody(ContentTypeJSON, "not_found")).
+			WithCallback(NewCallback(server.GetURL(ctx)+"/webhook")),
+		NewStub().For(http.MethodGet, Contains("slow")).
+			WillReturn(NewResponse()).
+			ShouldDelay(time.Hour).
+			WithCallback(NewCallback(server.GetURL(ctx)+"/webhook")),
+	))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.GetURL(ctx)+"/missing_file", nil)
+	require.NoError(t, err)
+
+	res, err := netkit.SendRequest(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	_, err = netkit.Get[types.Map](timeoutCtx, server.GetURL(ctx)+"/slow")
+	require.Error(t, err)
+
+	// Closing waits for the pending requests, then drains the callbacks
+	server.Close(ctx)
+
+	requests, err := server.GetIncomingRequests(ctx, &IncomingQueryOption{})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	callbacks, err := server.GetCallbacks(ctx, &CallbackQueryOption{})
+	require.NoError(t, err)
+	require.Empty(t, callbacks)
+}
+
+func TestNewCallbackDispatcher_WithoutSender(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stub := NewStub().For(http.MethodGet, Contains("animal")).WithCallback(NewCallback("http://merchant/notify"))
+
+	// Callbacks are skipped if the sender is not set, for example a handler which is created without WithCallbackSender
+	dispatcher, err := NewCallbackDispatcher(ctx, nil, stub, &TemplateData{})
+	require.NoError(t, err)
+	require.Nil(t, dispatcher)
+}
+
+func TestCallbackHosts_Allow(t *testing.T) {
+	t.Parallel()
+
+	hosts := ParseCallbackHosts(" localhost , *.Partner.com,")
+	require.Equal(t, CallbackHosts{"localhost", "*.partner.com"}, hosts)
+	require.True(t, hosts.Allow("localhost"))
+	require.True(t, hosts.Allow("api.partner.com"))
+	require.True(t, hosts.Allow("API.Partner.com."))
+	require.False(t, hosts.Allow("partner.com"))
+	require.False(t, hosts.Allow("evilpartner.com"))
+	require.False(t, hosts.Allow("169.254.169.254"))
+
+	require.False(t, ParseCallbackHosts("").Allow("localhost"))
+	require.True(t, ParseCallbackHosts("*").Allow("localhost"))
+}
+
+func TestCallbackSender_AllowedHosts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	merchantServer := NewLocalServerWithReporter(t)
+	store := NewStubMemory()
+	sender := NewCallbackSender(store).WithAllowedHosts(ParseCallbackHosts("127.0.0.1"))
+	t.Cleanup(sender.Close)
+
+	// The merchant redirects to a host which is not allowed
+	redirectURL := strings.Replace(merchantServer.GetURL(ctx), "127.0.0.1", "localhost", 1) + "/internal"
+	require.NoError(t, NewStub().For(http.MethodPost, Contains("redirect")).
+		WillReturn(NewResponse().WithStatusCode(http.StatusFound).WithHeader("Location", redirectURL)).
+		Send(ctx, merchantServer))
+
+	stub := NewStub().For(http.MethodGet, Contains("order")).
+		WillReturn(NewResponse()).
+		WithCallback(
+			NewCallback("http://localhost/webhook"),
+			NewCallback(merchantServer.GetURL(ctx)+"/redirect"),
+		)
+
+	callbacks, err := NewCallbackDispatcher(ctx, sender, stub, &TemplateData{})
+	require.NoError(t, err)
+	callbacks.Dispatch(ctx, &IncomingRequest{ID: 1})
+
+	var results []*CallbackResult
+	require.Eventually(t, func() bool {
+		results, err = store.GetCallbackResults(ctx, &CallbackQueryOption{})
+		require.NoError(t, err)
+		return len(results) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, r := range results {
+		require.False(t, r.Delivered)
+		require.Contains(t, r.Error, "callback host localhost is not allowed")
+	}
+
+	notifications, err := merchantServer.GetIncomingRequests(ctx, &IncomingQueryOption{})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+}
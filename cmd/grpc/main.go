@@ -17,12 +17,24 @@ func main() {
 		panic(err)
 	}
 
+	// The embedded SQLite database is migrated if the grpc server is started alone
+	// Other databases are shared with the http server, which runs the migrations
+	if cfg.DBType == config.DBTypeSQLite {
+		if err := setup.Migrate(ctx, cfg, "."); err != nil {
+			panic(err)
+		}
+	}
+
 	stubStore, err := setup.ProvideStubStore(ctx, cfg)
 	if err != nil {
 		panic(err)
 	}
 
-	service := xgrpc.NewServer(stubStore, fileStore, xgrpc.NewServiceDescriptor(fileStore))
+	// The pending callbacks are drained after the server is stopped
+	callbackSender := setup.ProvideCallbackSender(cfg, stubStore)
+	defer callbackSender.Close()
+
+	service := xgrpc.NewServer(stubStore, fileStore, xgrpc.NewServiceDescriptor(fileStore)).WithCallbackSender(callbackSender)
 	if err := service.Start(ctx, cfg.ServerAddress); err != nil {
 		panic(err)
 	}
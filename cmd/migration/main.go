@@ -13,8 +13,19 @@ func main() {
 	migrationFile := flag.String("file", "", "name of the migration file to run")
 	flag.Parse()
 
-	dbConfig := config.NewDBConfig()
-	if err := database.Migrate(ctx, dbConfig, *migrationFile); err != nil {
-		panic(err)
+	cfg := config.NewConfig()
+	switch cfg.DBType {
+	case config.DBTypePostgres:
+		if err := database.MigratePostgres(ctx, cfg.Postgres, *migrationFile); err != nil {
+			panic(err)
+		}
+	case config.DBTypeSQLite:
+		if err := database.MigrateSQLite(ctx, cfg.SQLite, *migrationFile); err != nil {
+			panic(err)
+		}
+	default:
+		if err := database.Migrate(ctx, cfg.DB, *migrationFile); err != nil {
+			panic(err)
+		}
 	}
 }
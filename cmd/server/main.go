@@ -6,8 +6,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/hungdv136/rio/internal/api"
 	"github.com/hungdv136/rio/internal/config"
-	"github.com/hungdv136/rio/internal/database"
+	xgrpc "github.com/hungdv136/rio/internal/grpc"
 	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/setup"
 )
 
 // @title       HTTP Mock
@@ -19,17 +20,43 @@ func main() {
 	api.SetupContext()
 
 	ctx := context.Background()
-	app, err := api.NewApp(ctx, config.NewConfig())
+	cfg := config.NewConfig()
+
+	// Stub store, file storage and callback sender are shared with the grpc server if it is started in the same process
+	stubStore, err := setup.ProvideStubStore(ctx, cfg)
 	if err != nil {
 		log.Error(ctx, err)
 		panic(err)
 	}
 
-	dbConfig := config.NewDBConfig()
-	if err := database.Migrate(ctx, dbConfig, "schema/migration"); err != nil {
+	fileStorage, err := setup.ProvideFileStorage(ctx, cfg)
+	if err != nil {
+		log.Error(ctx, err)
 		panic(err)
 	}
 
+	// The http server drains the callbacks of both servers when it is shut down
+	callbackSender := setup.ProvideCallbackSender(cfg, stubStore)
+
+	app, err := api.NewApp(ctx, cfg, api.WithStubStore(stubStore), api.WithFileStorage(fileStorage), api.WithCallbackSender(callbackSender))
+	if err != nil {
+		log.Error(ctx, err)
+		panic(err)
+	}
+
+	if err := setup.Migrate(ctx, cfg, "."); err != nil {
+		panic(err)
+	}
+
+	if len(cfg.GrpcServerAddress) > 0 {
+		grpcServer := xgrpc.NewServer(stubStore, fileStorage, xgrpc.NewServiceDescriptor(fileStorage)).WithCallbackSender(callbackSender)
+		if err := grpcServer.StartAsync(ctx, cfg.GrpcServerAddress); err != nil {
+			panic(err)
+		}
+
+		log.Info(ctx, "started grpc server", cfg.GrpcServerAddress)
+	}
+
 	if err := app.Start(ctx); err != nil {
 		log.Error(ctx, err)
 		panic(err)
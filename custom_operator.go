@@ -0,0 +1,92 @@
+package rio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var customOperators = &operatorRegistry{}
+
+// RegisterOption is an option of custom operator
+type RegisterOption func(o *customOperator)
+
+// ValidateValue sets the function to validate operator value when a stub is created
+func ValidateValue(validate func(value interface{}) error) RegisterOption {
+	return func(o *customOperator) {
+		o.validate = validate
+	}
+}
+
+// RegisterOperator registers a custom operator which can be used in stubs as built-in operators
+// This is only usable in processes that embed the handler (for example: LocalServer or NewHandler)
+// since the stand-alone mock server does not know custom operators of clients
+// It is safe to call concurrently, but operators should be registered at init time before stubs are created
+func RegisterOperator(name OperatorName, matchFunc MatchingFunc, opts ...RegisterOption) error {
+	if len(name) == 0 {
+		return errors.New("operator name is required")
+	}
+
+	if matchFunc == nil {
+		return fmt.Errorf("matching function of operator %s is required", name)
+	}
+
+	if _, ok := matchingFunctions[name]; ok {
+		return fmt.Errorf("operator %s is a built-in operator", name)
+	}
+
+	o := &customOperator{match: matchFunc}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return customOperators.register(name, o)
+}
+
+// MustRegisterOperator registers custom operator and panics if there is an error
+func MustRegisterOperator(name OperatorName, matchFunc MatchingFunc, opts ...RegisterOption) {
+	if err := RegisterOperator(name, matchFunc, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// CustomOperator creates a custom operator with the given name and expected value
+func CustomOperator(name OperatorName, value interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: name, Value: value}
+	}
+}
+
+type customOperator struct {
+	match    MatchingFunc
+	validate func(value interface{}) error
+}
+
+type operatorRegistry struct {
+	operators map[OperatorName]*customOperator
+	l         sync.RWMutex
+}
+
+func (r *operatorRegistry) register(name OperatorName, o *customOperator) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if _, ok := r.operators[name]; ok {
+		return fmt.Errorf("operator %s is already registered", name)
+	}
+
+	if r.operators == nil {
+		r.operators = map[OperatorName]*customOperator{}
+	}
+
+	r.operators[name] = o
+	return nil
+}
+
+func (r *operatorRegistry) get(name OperatorName) (*customOperator, bool) {
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	o, ok := r.operators[name]
+	return o, ok
+}
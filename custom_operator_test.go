@@ -0,0 +1,126 @@
+package rio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRegisterOperator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const opDivisibleBy OperatorName = "test_divisible_by"
+
+	err := RegisterOperator(opDivisibleBy, func(ctx context.Context, op Operator, value interface{}) (bool, error) {
+		actual, ok := getInt64(value)
+		if !ok {
+			return false, fmt.Errorf("unsupported data type %T", value)
+		}
+
+		divisor, _ := getInt64(op.Value)
+		return actual%divisor == 0, nil
+	}, ValidateValue(func(value interface{}) error {
+		if divisor, ok := getInt64(value); !ok || divisor == 0 {
+			return errors.New("divisor must be a non-zero integer")
+		}
+
+		return nil
+	}))
+	require.NoError(t, err)
+
+	t.Run("match", func(t *testing.T) {
+		t.Parallel()
+
+		matched, err := Match(ctx, CustomOperator(opDivisibleBy, 3)(), 9)
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		matched, err = Match(ctx, CustomOperator(opDivisibleBy, 3)(), "10")
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		matched, err = Match(ctx, Not(CustomOperator(opDivisibleBy, 3))(), 10)
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+
+		require.True(t, CustomOperator(opDivisibleBy, 3)().IsValid())
+		require.NoError(t, validateOp(ctx, CustomOperator(opDivisibleBy, 3)()))
+		require.Error(t, validateOp(ctx, CustomOperator(opDivisibleBy, 0)()))
+		require.Error(t, validateOp(ctx, AnyOf(CustomOperator(opDivisibleBy, "abc"))()))
+	})
+
+	t.Run("round_trip", func(t *testing.T) {
+		t.Parallel()
+
+		stub := NewStub().For(http.MethodGet, Contains("animal")).WithQuery("id", CustomOperator(opDivisibleBy, 3))
+
+		data, err := json.Marshal(stub)
+		require.NoError(t, err)
+		fromJSON := &Stub{}
+		require.NoError(t, json.Unmarshal(data, fromJSON))
+		require.NoError(t, fromJSON.Validate(ctx))
+
+		data, err = yaml.Marshal(stub.Request)
+		require.NoError(t, err)
+		fromYAML := &Stub{Request: &RequestMatching{}, Response: NewResponse()}
+		require.NoError(t, yaml.Unmarshal(data, fromYAML.Request))
+		require.NoError(t, fromYAML.Request.Validate(ctx))
+
+		for _, s := range []*Stub{fromJSON, fromYAML} {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.com/animal?id=12", nil)
+			require.NoError(t, err)
+
+			matched, err := matchHTTPRequest(ctx, s, req)
+			require.NoError(t, err)
+			require.True(t, matched)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		matchFunc := func(context.Context, Operator, interface{}) (bool, error) { return true, nil }
+		require.Error(t, RegisterOperator("", matchFunc))
+		require.Error(t, RegisterOperator("test_nil_func", nil))
+		require.Error(t, RegisterOperator(OpEqualTo, matchFunc))
+		require.Error(t, RegisterOperator(OpNot, matchFunc))
+		require.Error(t, RegisterOperator(opDivisibleBy, matchFunc))
+		require.Panics(t, func() { MustRegisterOperator(opDivisibleBy, matchFunc) })
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		t.Parallel()
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			name := OperatorName(fmt.Sprintf("test_concurrent_%d", i))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				MustRegisterOperator(name, func(_ context.Context, _ Operator, value interface{}) (bool, error) {
+					return strings.HasPrefix(fmt.Sprint(value), string(name)), nil
+				})
+
+				matched, err := Match(ctx, CustomOperator(name, nil)(), string(name))
+				require.NoError(t, err)
+				require.True(t, matched)
+			}()
+		}
+
+		wg.Wait()
+	})
+}
@@ -0,0 +1,247 @@
+package rio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2" // nolint:gosec
+	"net/http"
+	"time"
+
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// Defines the supported distributions of delay
+const (
+	// DelayFixed is a fixed duration plus a random jitter in range [-jitter, +jitter]
+	DelayFixed DelayType = "fixed"
+
+	// DelayUniform is a random duration in range [min, max]
+	DelayUniform DelayType = "uniform"
+
+	// DelayLogNormal is a random duration of log-normal distribution with the median and sigma
+	// This is close to the latency of real services which has a long tail
+	DelayLogNormal DelayType = "lognormal"
+)
+
+// The number of chunks if it is not defined for body trickle
+const defaultTrickleChunks = 10
+
+// DelayType is alias for the type of delay distribution
+type DelayType string
+
+// Delay defines a random distribution of delay duration
+type Delay struct {
+	Type DelayType `json:"type" yaml:"type"`
+
+	// Duration and Jitter are used for fixed distribution
+	Duration time.Duration `json:"duration,omitempty" swaggertype:"primitive,integer" yaml:"duration"`
+	Jitter   time.Duration `json:"jitter,omitempty" swaggertype:"primitive,integer" yaml:"jitter"`
+
+	// Min and Max are used for uniform distribution
+	Min time.Duration `json:"min,omitempty" swaggertype:"primitive,integer" yaml:"min"`
+	Max time.Duration `json:"max,omitempty" swaggertype:"primitive,integer" yaml:"max"`
+
+	// Median and Sigma are used for log-normal distribution
+	Median time.Duration `json:"median,omitempty" swaggertype:"primitive,integer" yaml:"median"`
+	Sigma  float64       `json:"sigma,omitempty" yaml:"sigma"`
+}
+
+// FixedDelay returns a fixed delay with random jitter in range [-jitter, +jitter]
+func FixedDelay(d time.Duration, jitter time.Duration) *Delay {
+	return &Delay{Type: DelayFixed, Duration: d, Jitter: jitter}
+}
+
+// UniformDelay returns a delay which is distributed uniformly in range [min, max]
+func UniformDelay(min time.Duration, max time.Duration) *Delay {
+	return &Delay{Type: DelayUniform, Min: min, Max: max}
+}
+
+// LogNormalDelay returns a delay of log-normal distribution
+// Half of the delays are less than median, sigma controls the length of tail
+func LogNormalDelay(median time.Duration, sigma float64) *Delay {
+	return &Delay{Type: DelayLogNormal, Median: median, Sigma: sigma}
+}
+
+// Validate returns a non-nil error if invalid
+func (d *Delay) Validate(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	var err error
+	switch d.Type {
+	case DelayFixed:
+		if d.Duration < 0 || d.Jitter < 0 {
+			err = errors.New("duration and jitter must not be negative")
+		}
+	case DelayUniform:
+		if d.Min < 0 || d.Max < d.Min {
+			err = errors.New("min must not be negative and max must not be less than min")
+		}
+	case DelayLogNormal:
+		if d.Median <= 0 || d.Sigma < 0 {
+			err = errors.New("median must be positive and sigma must not be negative")
+		}
+	default:
+		err = fmt.Errorf("unsupported delay type %s", d.Type)
+	}
+
+	if err != nil {
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// Sample returns a random duration of the distribution
+func (d *Delay) Sample() time.Duration {
+	if d == nil {
+		return 0
+	}
+
+	switch d.Type {
+	case DelayFixed:
+		if d.Jitter <= 0 {
+			return d.Duration
+		}
+
+		return max(0, d.Duration+time.Duration(rand.Int64N(int64(2*d.Jitter)+1))-d.Jitter)
+	case DelayUniform:
+		return d.Min + time.Duration(rand.Int64N(int64(d.Max-d.Min)+1))
+	case DelayLogNormal:
+		v := float64(d.Median) * math.Exp(d.Sigma*rand.NormFloat64())
+		if v >= math.MaxInt64 {
+			return time.Duration(math.MaxInt64)
+		}
+
+		return time.Duration(v)
+	}
+
+	return 0
+}
+
+// Clone clones delay
+func (d *Delay) Clone() *Delay {
+	if d == nil {
+		return nil
+	}
+
+	nd := *d
+	return &nd
+}
+
+// Trickle defines how the body is paced over a duration
+// The body is split into chunks, the chunks are written one by one with the same interval
+type Trickle struct {
+	Duration time.Duration `json:"duration" swaggertype:"primitive,integer" yaml:"duration"`
+
+	// Optional. The number of chunks, default is 10
+	Chunks int `json:"chunks,omitempty" yaml:"chunks"`
+}
+
+// Validate returns a non-nil error if invalid
+func (t *Trickle) Validate(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	if t.Duration <= 0 || t.Chunks < 0 {
+		err := errors.New("trickle duration must be positive and chunks must not be negative")
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// Clone clones trickle
+func (t *Trickle) Clone() *Trickle {
+	if t == nil {
+		return nil
+	}
+
+	nt := *t
+	return &nt
+}
+
+func (t *Trickle) chunks() int {
+	if t.Chunks <= 0 {
+		return defaultTrickleChunks
+	}
+
+	return t.Chunks
+}
+
+// trickleWriter writes the body in chunks and waits between the chunks
+type trickleWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	trickle *Trickle
+}
+
+func newTrickleWriter(ctx context.Context, w http.ResponseWriter, t *Trickle) *trickleWriter {
+	return &trickleWriter{ResponseWriter: w, ctx: ctx, trickle: t}
+}
+
+// Write splits data into chunks and flushes each chunk to client
+func (w *trickleWriter) Write(data []byte) (int, error) {
+	chunks := min(w.trickle.chunks(), len(data))
+	if chunks == 0 {
+		return 0, nil
+	}
+
+	chunkSize := (len(data) + chunks - 1) / chunks
+	interval := w.trickle.Duration / time.Duration(chunks)
+	flusher, _ := w.ResponseWriter.(http.Flusher)
+
+	written := 0
+	for written < len(data) {
+		end := min(written+chunkSize, len(data))
+		n, err := w.ResponseWriter.Write(data[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if written < len(data) {
+			if err := Sleep(w.ctx, interval); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// Flush implements http.Flusher, so that the streaming response can flush through trickle writer
+func (w *trickleWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Sleep waits for a duration, returns the error of context if it is cancelled before the duration
+// This is shared by http and grpc handlers to delay responses and messages
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		log.Info(ctx, "context is cancelled while waiting")
+		return ctx.Err()
+	}
+}
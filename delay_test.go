@@ -0,0 +1,182 @@
+package rio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelay_Sample(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fixed", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, 100*time.Millisecond, FixedDelay(100*time.Millisecond, 0).Sample())
+
+		d := FixedDelay(100*time.Millisecond, 20*time.Millisecond)
+		for i := 0; i < 100; i++ {
+			v := d.Sample()
+			require.GreaterOrEqual(t, v, 80*time.Millisecond)
+			require.LessOrEqual(t, v, 120*time.Millisecond)
+		}
+
+		// Jitter never makes the delay negative
+		d = FixedDelay(time.Millisecond, time.Second)
+		for i := 0; i < 100; i++ {
+			require.GreaterOrEqual(t, d.Sample(), time.Duration(0))
+		}
+	})
+
+	t.Run("uniform", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, time.Second, UniformDelay(time.Second, time.Second).Sample())
+
+		d := UniformDelay(10*time.Millisecond, 50*time.Millisecond)
+		for i := 0; i < 100; i++ {
+			v := d.Sample()
+			require.GreaterOrEqual(t, v, 10*time.Millisecond)
+			require.LessOrEqual(t, v, 50*time.Millisecond)
+		}
+	})
+
+	t.Run("lognormal", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, 100*time.Millisecond, LogNormalDelay(100*time.Millisecond, 0).Sample())
+
+		d := LogNormalDelay(100*time.Millisecond, 0.5)
+		samples := make([]time.Duration, 1001)
+		for i := range samples {
+			samples[i] = d.Sample()
+			require.Greater(t, samples[i], time.Duration(0))
+		}
+
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		median := samples[len(samples)/2]
+		require.Greater(t, median, 80*time.Millisecond)
+		require.Less(t, median, 125*time.Millisecond)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+
+		var d *Delay
+		require.Zero(t, d.Sample())
+	})
+}
+
+func TestDelay_Validate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validDelays := []*Delay{
+		nil,
+		FixedDelay(time.Second, 0),
+		UniformDelay(0, time.Second),
+		LogNormalDelay(time.Second, 0.5),
+	}
+
+	for _, d := range validDelays {
+		require.NoError(t, d.Validate(ctx))
+	}
+
+	invalidDelays := []*Delay{
+		{Type: "unknown"},
+		FixedDelay(-time.Second, 0),
+		UniformDelay(time.Second, time.Millisecond),
+		LogNormalDelay(0, 0.5),
+		LogNormalDelay(time.Second, -1),
+	}
+
+	for _, d := range invalidDelays {
+		require.Error(t, d.Validate(ctx))
+	}
+
+	require.Error(t, (&Trickle{}).Validate(ctx))
+	require.Error(t, (&Trickle{Duration: time.Second, Chunks: -1}).Validate(ctx))
+	require.NoError(t, (&Trickle{Duration: time.Second}).Validate(ctx))
+}
+
+func TestStubSettings_SampleDelay(t *testing.T) {
+	t.Parallel()
+
+	s := NewStub().ShouldDelay(time.Second)
+	require.Equal(t, time.Second, s.Settings.SampleDelay())
+
+	s.ShouldDelayWith(UniformDelay(time.Millisecond, time.Millisecond))
+	require.Equal(t, time.Millisecond, s.Settings.SampleDelay())
+
+	clone := s.Settings.Clone()
+	clone.Delay.Min = time.Second
+	require.Equal(t, time.Millisecond, s.Settings.Delay.Min)
+
+	ctx := context.Background()
+	require.Error(t, NewStub().ForGRPC(EqualTo("/offers.v1.OfferService/ValidateOffer")).ShouldTrickleBody(time.Second, 0).Validate(ctx))
+	require.Error(t, NewStub().For(http.MethodGet, EqualTo("/delay")).ShouldDelayWith(&Delay{Type: "unknown"}).Validate(ctx))
+}
+
+func TestTrickleWriter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	body := []byte("0123456789abcdefghij")
+
+	t.Run("paced", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		w := newTrickleWriter(ctx, recorder, &Trickle{Duration: 100 * time.Millisecond, Chunks: 4})
+
+		start := time.Now()
+		n, err := w.Write(body)
+		require.NoError(t, err)
+		require.Equal(t, len(body), n)
+		require.Equal(t, body, recorder.Body.Bytes())
+		require.True(t, recorder.Flushed)
+
+		// There are 3 intervals between 4 chunks
+		require.GreaterOrEqual(t, time.Since(start), 75*time.Millisecond)
+	})
+
+	t.Run("more_chunks_than_bytes", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		n, err := newTrickleWriter(ctx, recorder, &Trickle{Duration: time.Millisecond, Chunks: 100}).Write(body[:3])
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+		require.Equal(t, body[:3], recorder.Body.Bytes())
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		recorder := httptest.NewRecorder()
+		n, err := newTrickleWriter(ctx, recorder, &Trickle{Duration: time.Hour}).Write(body)
+		require.True(t, errors.Is(err, context.Canceled))
+		require.Equal(t, 2, n)
+	})
+}
+
+func TestSleep(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	require.ErrorIs(t, Sleep(ctx, time.Hour), context.Canceled)
+	require.Less(t, time.Since(start), time.Second)
+	require.NoError(t, Sleep(context.Background(), time.Millisecond))
+}
@@ -0,0 +1,129 @@
+package rio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The maximum number of candidate stubs are reported when there is no matched stub
+const maxDiagnostics = 3
+
+// StubDiagnostic describes how close a stub is to a request which is not matched with any stub
+type StubDiagnostic struct {
+	StubID       int64       `json:"stub_id" yaml:"stub_id"`
+	Description  string      `json:"description,omitempty" yaml:"description"`
+	MatchedRules int         `json:"matched_rules" yaml:"matched_rules"`
+	TotalRules   int         `json:"total_rules" yaml:"total_rules"`
+	Mismatches   []*Mismatch `json:"mismatches" yaml:"mismatches"`
+}
+
+func (d *StubDiagnostic) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("stub %d matched %d/%d rules", d.StubID, d.MatchedRules, d.TotalRules))
+	if len(d.Description) > 0 {
+		sb.WriteString(" (" + d.Description + ")")
+	}
+
+	for _, m := range d.Mismatches {
+		sb.WriteString("\n  - " + m.String())
+	}
+
+	return sb.String()
+}
+
+// Diagnostics is a list of candidate stubs which are ordered from the closest
+type Diagnostics []*StubDiagnostic
+
+// String returns the human readable diagnostics which can be used in error message
+func (d Diagnostics) String() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// DiagnoseGrpcRequest ranks the grpc stubs by the number of satisfied rules against the grpc request and its metadata
+func DiagnoseGrpcRequest(ctx context.Context, stubs []*Stub, r *GrpcRequest, md map[string][]string) Diagnostics {
+	candidates := make([]*Stub, 0, len(stubs))
+	for _, stub := range stubs {
+		if stub.Request != nil && stub.Request.Method == MethodGrpc {
+			candidates = append(candidates, stub)
+		}
+	}
+
+	return diagnose(candidates, func(rm *RequestMatching) []*Mismatch {
+		return evaluateGrpcRequest(ctx, rm, r, md)
+	})
+}
+
+// diagnoseHTTPRequest ranks the http stubs by the number of satisfied rules against the http request
+func diagnoseHTTPRequest(ctx context.Context, stubs []*Stub, r *http.Request) Diagnostics {
+	candidates := make([]*Stub, 0, len(stubs))
+	for _, stub := range stubs {
+		if stub.Request != nil && stub.Request.Method != MethodGrpc {
+			candidates = append(candidates, stub)
+		}
+	}
+
+	return diagnose(candidates, func(rm *RequestMatching) []*Mismatch {
+		return evaluateHTTPRequest(ctx, rm, r)
+	})
+}
+
+func diagnose(stubs []*Stub, evaluate func(rm *RequestMatching) []*Mismatch) Diagnostics {
+	diagnostics := make(Diagnostics, 0, len(stubs))
+	for _, stub := range stubs {
+		mismatches := evaluate(stub.Request)
+		totalRules := countRules(stub.Request)
+		diagnostics = append(diagnostics, &StubDiagnostic{
+			StubID:       stub.ID,
+			Description:  stub.Description,
+			MatchedRules: totalRules - len(mismatches),
+			TotalRules:   totalRules,
+			Mismatches:   mismatches,
+		})
+	}
+
+	// The more matched rules, the closer. Fewer mismatches are preferred for the same number of matched rules
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].MatchedRules != diagnostics[j].MatchedRules {
+			return diagnostics[i].MatchedRules > diagnostics[j].MatchedRules
+		}
+
+		return len(diagnostics[i].Mismatches) < len(diagnostics[j].Mismatches)
+	})
+
+	if len(diagnostics) > maxDiagnostics {
+		diagnostics = diagnostics[:maxDiagnostics]
+	}
+
+	return diagnostics
+}
+
+// countRules returns the number of rules which are evaluated by evaluator
+func countRules(rm *RequestMatching) int {
+	n := len(rm.URL) + len(rm.Header) + len(rm.Query) + len(rm.Cookie) + len(rm.Body) + len(rm.Stream)
+	if len(rm.Method) > 0 {
+		n++
+	}
+
+	if rm.Method == MethodGrpc {
+		return n
+	}
+
+	n += len(rm.Host) + len(rm.Port) + len(rm.GraphQL)
+	if len(rm.Scheme) > 0 {
+		n++
+	}
+
+	if len(rm.Path) > 0 {
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,105 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnoseHTTPRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	name := uuid.NewString()
+
+	closest := NewStub().WithID(1).
+		For("POST", Contains("animal/create")).
+		WithHeader("X-REQUEST-ID", EqualTo("request_id")).
+		WithRequestBody(BodyJSONPath("$.name", EqualTo(name)))
+	farther := NewStub().WithID(2).For("GET", Contains("animal/get"))
+	grpcStub := NewStub().WithID(3).ForGRPC(Contains("animal/create"))
+
+	body := bytes.NewReader([]byte(`{"name": "cat"}`))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/animal/create", body)
+	require.NoError(t, err)
+	req.Header.Set(HeaderContentType, ContentTypeJSON)
+	req.Header.Set("X-REQUEST-ID", "request_id")
+
+	diagnostics := diagnoseHTTPRequest(ctx, []*Stub{farther, grpcStub, closest}, req)
+	require.Len(t, diagnostics, 2)
+
+	require.Equal(t, closest.ID, diagnostics[0].StubID)
+	require.Equal(t, 3, diagnostics[0].MatchedRules)
+	require.Equal(t, 4, diagnostics[0].TotalRules)
+	require.Len(t, diagnostics[0].Mismatches, 1)
+	require.Equal(t, "body", diagnostics[0].Mismatches[0].Field)
+	require.Equal(t, name, diagnostics[0].Mismatches[0].Expected)
+	require.Equal(t, "cat", diagnostics[0].Mismatches[0].Actual)
+
+	require.Equal(t, farther.ID, diagnostics[1].StubID)
+	require.Equal(t, 0, diagnostics[1].MatchedRules)
+	require.Len(t, diagnostics[1].Mismatches, 2)
+	require.Contains(t, diagnostics.String(), "stub 1 matched 3/4 rules\n  - body[$.name]: expected equal_to "+name+", actual cat")
+}
+
+func TestDiagnoseGrpcRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fullMethod := "/offers.v1.OfferService/ValidateOffer"
+
+	stub := NewStub().WithID(1).
+		ForGRPC(EqualTo(fullMethod)).
+		WithRequestBody(BodyJSONPath("$.id", EqualTo("offer_id")))
+	httpStub := NewStub().WithID(2).For("POST", Contains("offers"))
+
+	input := types.Map{"id": "another_id"}
+	r := &GrpcRequest{FullMethod: fullMethod, InputData: input, Messages: []types.Map{input}}
+
+	diagnostics := DiagnoseGrpcRequest(ctx, []*Stub{stub, httpStub}, r, nil)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, stub.ID, diagnostics[0].StubID)
+	require.Equal(t, 2, diagnostics[0].MatchedRules)
+	require.Equal(t, 3, diagnostics[0].TotalRules)
+	require.Equal(t, "another_id", diagnostics[0].Mismatches[0].Actual)
+}
+
+func TestHandler_NotFoundDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t)
+
+	stub := NewStub().For("GET", Contains("animal/get")).WithQuery("id", EqualTo("animal_id"))
+	require.NoError(t, stub.Send(ctx, server))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.GetURL(ctx)+"/animal/get?id=another_id", nil)
+	require.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	body := struct {
+		Message     string      `json:"message"`
+		Diagnostics Diagnostics `json:"diagnostics"`
+	}{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	require.Len(t, body.Diagnostics, 1)
+	require.Equal(t, stub.ID, body.Diagnostics[0].StubID)
+	require.Equal(t, "query", body.Diagnostics[0].Mismatches[0].Field)
+	require.Equal(t, "another_id", body.Diagnostics[0].Mismatches[0].Actual)
+
+	requests, err := server.GetIncomingRequests(ctx, &IncomingQueryOption{Unmatched: true})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0].Diagnostics, 1)
+	require.Equal(t, stub.ID, requests[0].Diagnostics[0].StubID)
+}
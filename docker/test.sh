@@ -1,7 +1,13 @@
 #!/bin/bash
 
-until mysqladmin ping -s -h db -P 3306 -uadmin -ppassword; do
-  sleep 1
-done
+if [ "$DB_TYPE" = "postgres" ]; then
+  until pg_isready -q -h db -p 5432 -U admin; do
+    sleep 1
+  done
+else
+  until mysqladmin ping -s -h db -P 3306 -uadmin -ppassword; do
+    sleep 1
+  done
+fi
 
 make test
@@ -0,0 +1,164 @@
+package rio
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// Defines fault modes to simulate network-level failures
+const (
+	// FaultConnectionReset closes the connection with TCP RST without writing response
+	FaultConnectionReset FaultType = "connection_reset"
+
+	// FaultEmptyResponse closes the connection without writing response
+	FaultEmptyResponse FaultType = "empty_response"
+
+	// FaultMalformedChunk writes status and headers, then an invalid chunk of chunked encoding and closes the connection
+	FaultMalformedChunk FaultType = "malformed_chunk"
+
+	// FaultRandomData writes random bytes instead of a http response and closes the connection
+	FaultRandomData FaultType = "random_data"
+
+	// FaultCloseMidBody writes status, headers with full content length and a half of body, then closes the connection
+	FaultCloseMidBody FaultType = "close_mid_body"
+
+	// FaultCloseMidStream sends the messages of response, then aborts the grpc stream with UNKNOWN status
+	// Only the stream is aborted, other calls on the same connection are not affected
+	FaultCloseMidStream FaultType = "close_mid_stream"
+
+	// FaultUnavailable returns UNAVAILABLE status without sending any message
+	FaultUnavailable FaultType = "unavailable"
+)
+
+// The number of bytes which are written for random data fault
+const faultRandomDataSize = 1024
+
+// FaultType is alias for the type of fault
+type FaultType string
+
+// IsHTTP returns true if fault is applied for http
+func (f FaultType) IsHTTP() bool {
+	switch f {
+	case FaultConnectionReset, FaultEmptyResponse, FaultMalformedChunk, FaultRandomData, FaultCloseMidBody:
+		return true
+	}
+
+	return false
+}
+
+// IsGrpc returns true if fault is applied for grpc
+func (f FaultType) IsGrpc() bool {
+	return f == FaultCloseMidStream || f == FaultUnavailable
+}
+
+// writeFault writes the broken response by hijacking the connection
+// This is only supported for HTTP/1.x since HTTP/2 connection cannot be hijacked
+func (r *Response) writeFault(ctx context.Context, w http.ResponseWriter) error {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		err := fmt.Errorf("fault %s requires a connection which can be hijacked", r.Fault)
+		log.Error(ctx, err)
+		return err
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		log.Error(ctx, "cannot hijack connection", err)
+		return err
+	}
+
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Error(ctx, "cannot close connection", err)
+		}
+	}()
+
+	log.Info(ctx, "inject fault", r.Fault)
+
+	switch r.Fault {
+	case FaultConnectionReset:
+		// Zero linger discards unsent data and sends RST instead of FIN when closing
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			return tcpConn.SetLinger(0)
+		}
+
+		return nil
+
+	case FaultEmptyResponse:
+		return nil
+
+	case FaultMalformedChunk:
+		r.writeStatusAndHeader(buf, map[string]string{"Transfer-Encoding": "chunked"})
+		_, _ = buf.WriteString("zz\r\n")
+		_, _ = buf.Write(r.Body)
+		_, _ = buf.WriteString("\r\n")
+
+	case FaultRandomData:
+		data := make([]byte, faultRandomDataSize)
+		if _, err := rand.Read(data); err != nil {
+			log.Error(ctx, err)
+			return err
+		}
+
+		_, _ = buf.Write(data)
+
+	case FaultCloseMidBody:
+		// Declare at least one byte, so that the body is always incomplete
+		contentLength := len(r.Body)
+		if contentLength == 0 {
+			contentLength = 1
+		}
+
+		r.writeStatusAndHeader(buf, map[string]string{HeaderContentLength: strconv.Itoa(contentLength)})
+		_, _ = buf.Write(r.Body[:len(r.Body)/2])
+
+	default:
+		err := fmt.Errorf("unsupported http fault %s", r.Fault)
+		log.Error(ctx, err)
+		return err
+	}
+
+	if err := buf.Flush(); err != nil {
+		log.Error(ctx, "cannot write fault", err)
+		return err
+	}
+
+	return nil
+}
+
+// writeStatusAndHeader writes raw status line and headers of HTTP/1.1 response
+func (r *Response) writeStatusAndHeader(buf *bufio.ReadWriter, extraHeader map[string]string) {
+	statusCode := r.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	header := http.Header{}
+	for k, v := range r.Header {
+		header.Set(k, v)
+	}
+
+	for k, v := range extraHeader {
+		header.Set(k, v)
+	}
+
+	_, _ = fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", statusCode, http.StatusText(statusCode))
+	_ = header.Write(buf)
+	_, _ = buf.WriteString("\r\n")
+}
+
+func validateFault(f FaultType) error {
+	if len(f) == 0 || f.IsHTTP() || f.IsGrpc() {
+		return nil
+	}
+
+	return errors.New("unsupported fault " + string(f))
+}
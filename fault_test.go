@@ -0,0 +1,101 @@
+package rio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_WriteFault(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newServer := func(t *testing.T, res *Response) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = res.WriteTo(ctx, w)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(t *testing.T, url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		require.NoError(t, err)
+		return client.Do(req)
+	}
+
+	t.Run("connection_reset", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := get(t, newServer(t, NewResponse().WithFault(FaultConnectionReset)))
+		require.ErrorIs(t, err, syscall.ECONNRESET)
+	})
+
+	t.Run("empty_response", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := get(t, newServer(t, NewResponse().WithFault(FaultEmptyResponse)))
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("random_data", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := get(t, newServer(t, NewResponse().WithFault(FaultRandomData)))
+		require.Error(t, err)
+	})
+
+	t.Run("malformed_chunk", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := get(t, newServer(t, JSONResponse(map[string]string{"id": "1"}).WithFault(FaultMalformedChunk)))
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, ContentTypeJSON, res.Header.Get(HeaderContentType))
+
+		_, err = io.ReadAll(res.Body)
+		require.Error(t, err)
+	})
+
+	t.Run("close_mid_body", func(t *testing.T) {
+		t.Parallel()
+
+		body := []byte(`{"id": "1", "name": "rio"}`)
+		res, err := get(t, newServer(t, NewResponse().WithStatusCode(http.StatusAccepted).WithBody(ContentTypeJSON, body).WithFault(FaultCloseMidBody)))
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusAccepted, res.StatusCode)
+		require.Equal(t, int64(len(body)), res.ContentLength)
+
+		data, err := io.ReadAll(res.Body)
+		require.True(t, errors.Is(err, io.ErrUnexpectedEOF), err)
+		require.Equal(t, body[:len(body)/2], data)
+	})
+
+	t.Run("not_hijackable", func(t *testing.T) {
+		t.Parallel()
+
+		require.Error(t, NewResponse().WithFault(FaultEmptyResponse).WriteTo(ctx, httptest.NewRecorder()))
+	})
+}
+
+func TestStub_ValidateFault(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.NoError(t, NewStub().For(http.MethodGet, EqualTo("/fault")).WillReturn(NewResponse().WithFault(FaultConnectionReset)).Validate(ctx))
+	require.NoError(t, NewStub().ForGRPC(EqualTo("/offers.v1.OfferService/ValidateOffer")).WillReturn(NewResponse().WithFault(FaultUnavailable)).Validate(ctx))
+	require.Error(t, NewStub().For(http.MethodGet, EqualTo("/fault")).WillReturn(NewResponse().WithFault(FaultCloseMidStream)).Validate(ctx))
+	require.Error(t, NewStub().ForGRPC(EqualTo("/offers.v1.OfferService/ValidateOffer")).WillReturn(NewResponse().WithFault(FaultRandomData)).Validate(ctx))
+	require.Error(t, NewStub().For(http.MethodGet, EqualTo("/fault")).WillReturn(NewResponse().WithFault("unknown")).Validate(ctx))
+}
@@ -0,0 +1,300 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hungdv136/rio/internal/log"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Defines scopes to select the part of GraphQL request to be matched
+const (
+	GraphQLScopeOperationName GraphQLScope = "operation_name"
+	GraphQLScopeOperationType GraphQLScope = "operation_type"
+	GraphQLScopeRootFields    GraphQLScope = "root_fields"
+	GraphQLScopeVariables     GraphQLScope = "variables"
+)
+
+// GraphQLScope is alias for the scope of GraphQL operator
+type GraphQLScope string
+
+// GraphQLOperator defines operator for matching the parsed GraphQL request
+type GraphQLOperator struct {
+	// Scope defines which part of GraphQL request is matched, it is one of the following values
+	//  - "operation_name": the name of executed operation
+	//  - "operation_type": the type of executed operation query, mutation or subscription
+	//  - "root_fields": the names (not aliases) of the root fields which are selected by executed operation
+	//  - "variables": the variables of request by the key path
+	Scope GraphQLScope `json:"scope" yaml:"scope"`
+
+	Operator Operator `json:"operator" yaml:"operator"`
+
+	// KeyPath is json path which is applied for variables. For example: $.id, $.input.name
+	KeyPath string `json:"key_path,omitempty" yaml:"key_path"`
+}
+
+// CreateGraphQLOperator is alias function for creating a GraphQL operator
+type CreateGraphQLOperator func() GraphQLOperator
+
+// GraphQLOperationName matches the name of executed operation
+func GraphQLOperationName(createOperator CreateOperator) CreateGraphQLOperator {
+	return newGraphQLOperator(GraphQLScopeOperationName, "", createOperator)
+}
+
+// GraphQLOperationType matches the type of executed operation: query, mutation or subscription
+func GraphQLOperationType(createOperator CreateOperator) CreateGraphQLOperator {
+	return newGraphQLOperator(GraphQLScopeOperationType, "", createOperator)
+}
+
+// GraphQLRootFields matches the names of selected root fields. For example: Contains("user"), ContainsAll("user", "orders")
+func GraphQLRootFields(createOperator CreateOperator) CreateGraphQLOperator {
+	return newGraphQLOperator(GraphQLScopeRootFields, "", createOperator)
+}
+
+// GraphQLVariable matches the variables of request by the json path
+func GraphQLVariable(jsonPath string, createOperator CreateOperator) CreateGraphQLOperator {
+	return newGraphQLOperator(GraphQLScopeVariables, jsonPath, createOperator)
+}
+
+func newGraphQLOperator(scope GraphQLScope, jsonPath string, createOperator CreateOperator) CreateGraphQLOperator {
+	return func() GraphQLOperator {
+		return GraphQLOperator{
+			Scope:    scope,
+			Operator: createOperator(),
+			KeyPath:  jsonPath,
+		}
+	}
+}
+
+func validateGraphQLOps(ctx context.Context, ops ...GraphQLOperator) error {
+	for _, o := range ops {
+		if err := validateOp(ctx, o.Operator); err != nil {
+			return err
+		}
+
+		switch o.Scope {
+		case GraphQLScopeOperationName, GraphQLScopeOperationType, GraphQLScopeRootFields:
+			continue
+		case GraphQLScopeVariables:
+			if len(o.KeyPath) == 0 {
+				err := fmt.Errorf("missing key path for scope %s", o.Scope)
+				log.Error(ctx, err)
+				return err
+			}
+		default:
+			err := fmt.Errorf("unsupported graphql scope %s", o.Scope)
+			log.Error(ctx, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// graphQLRequest is the parsed GraphQL request
+type graphQLRequest struct {
+	OperationName string
+	OperationType string
+	RootFields    []string
+	Variables     map[string]interface{}
+}
+
+// graphQLParams is the standard parameters of GraphQL request over HTTP
+type graphQLParams struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// value returns the value of the request by the scope of operator
+func (g *graphQLRequest) value(ctx context.Context, op GraphQLOperator) (interface{}, error) {
+	switch op.Scope {
+	case GraphQLScopeOperationName:
+		return g.OperationName, nil
+	case GraphQLScopeOperationType:
+		return g.OperationType, nil
+	case GraphQLScopeRootFields:
+		return g.RootFields, nil
+	case GraphQLScopeVariables:
+		return getJSONPath(ctx, op.KeyPath, g.Variables)
+	}
+
+	return nil, fmt.Errorf("unsupported graphql scope %s", op.Scope)
+}
+
+// parseGraphQLRequest reads the GraphQL parameters from http request and parses the document
+// The parameters are read from json body, application/graphql body or query string for GET request
+func parseGraphQLRequest(r *http.Request) (*graphQLRequest, error) {
+	params, err := readGraphQLParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params.Query) == 0 {
+		return nil, errors.New("missing graphql query")
+	}
+
+	doc, err := parser.ParseQuery(&ast.Source{Input: params.Query})
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse graphql query: %w", err)
+	}
+
+	operation := doc.Operations.ForName(params.OperationName)
+	if operation == nil {
+		return nil, fmt.Errorf("graphql operation %q is not found", params.OperationName)
+	}
+
+	g := &graphQLRequest{
+		OperationName: operation.Name,
+		OperationType: string(operation.Operation),
+		Variables:     params.Variables,
+	}
+
+	if g.Variables == nil {
+		g.Variables = map[string]interface{}{}
+	}
+
+	visited := map[string]bool{}
+	collectRootFields(operation.SelectionSet, doc.Fragments, visited, &g.RootFields)
+	return g, nil
+}
+
+func readGraphQLParams(r *http.Request) (*graphQLParams, error) {
+	query := r.URL.Query()
+	params := &graphQLParams{
+		Query:         query.Get("query"),
+		OperationName: query.Get("operationName"),
+	}
+
+	if v := query.Get("variables"); len(v) > 0 {
+		if err := decodeJSONWithNumber([]byte(v), &params.Variables); err != nil {
+			return nil, fmt.Errorf("cannot decode graphql variables: %w", err)
+		}
+	}
+
+	if r.Body == nil || r.Method == http.MethodGet {
+		return params, nil
+	}
+
+	body, err := io.ReadAll(readRequestBody(r))
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := r.Header.Get(HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, ContentTypeGraphQL):
+		params.Query = string(body)
+	case strings.HasPrefix(contentType, ContentTypeJSON):
+		if err := decodeJSONWithNumber(body, params); err != nil {
+			return nil, fmt.Errorf("cannot decode graphql request: %w", err)
+		}
+	}
+
+	return params, nil
+}
+
+func decodeJSONWithNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// collectRootFields collects the names of root fields including the fields in fragments
+func collectRootFields(selections ast.SelectionSet, fragments ast.FragmentDefinitionList, visited map[string]bool, fields *[]string) {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if !containsString(*fields, s.Name) {
+				*fields = append(*fields, s.Name)
+			}
+
+		case *ast.InlineFragment:
+			collectRootFields(s.SelectionSet, fragments, visited, fields)
+
+		case *ast.FragmentSpread:
+			// A fragment can be spread many times or recursively in an invalid document
+			if visited[s.Name] {
+				continue
+			}
+
+			visited[s.Name] = true
+			if fragment := fragments.ForName(s.Name); fragment != nil {
+				collectRootFields(fragment.SelectionSet, fragments, visited, fields)
+			}
+		}
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchGraphQL(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
+	if len(s.Request.GraphQL) == 0 {
+		return true, nil
+	}
+
+	g, err := parseGraphQLRequest(r)
+	if err != nil {
+		log.Info(ctx, "invalid graphql request", err)
+		return false, nil
+	}
+
+	for _, op := range s.Request.GraphQL {
+		val, err := g.value(ctx, op)
+		if err != nil {
+			return false, err
+		}
+
+		if matched, err := Match(ctx, op.Operator, val); err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// GraphQLError is an error in the response of GraphQL
+type GraphQLError struct {
+	Message    string                 `json:"message" yaml:"message"`
+	Path       []interface{}          `json:"path,omitempty" yaml:"path"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty" yaml:"locations"`
+	Extensions map[string]interface{} `json:"extensions,omitempty" yaml:"extensions"`
+}
+
+// GraphQLLocation is the location of an error in GraphQL document
+type GraphQLLocation struct {
+	Line   int `json:"line" yaml:"line"`
+	Column int `json:"column" yaml:"column"`
+}
+
+type graphQLResponse struct {
+	Data   interface{}     `json:"data"`
+	Errors []*GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLResponse is convenient constructor to initialize response with the GraphQL data/errors envelope
+// Data is null if it is nil, errors are omitted if there is no error
+func GraphQLResponse(data interface{}, errs ...*GraphQLError) *Response {
+	return JSONResponse(graphQLResponse{Data: data, Errors: errs})
+}
+
+// GraphQLErrorResponse is convenient constructor to initialize GraphQL response with errors only
+func GraphQLErrorResponse(errs ...*GraphQLError) *Response {
+	return GraphQLResponse(nil, errs...)
+}
@@ -0,0 +1,176 @@
+package rio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGraphQLRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	document := `
+		query GetUser($id: ID!) {
+			me: user(id: $id) { id name }
+			...Extra
+		}
+
+		mutation DeleteUser($id: ID!) { deleteUser(id: $id) }
+
+		fragment Extra on Query {
+			orders { id }
+			... on Query { user(id: 1) { id } settings }
+		}`
+
+	newJSONRequest := func(body types.Map) *http.Request {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/graphql", strings.NewReader(body.ForceJSON()))
+		require.NoError(t, err)
+		req.Header.Set(HeaderContentType, ContentTypeJSON)
+		return req
+	}
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		req := newJSONRequest(types.Map{"query": document, "operationName": "GetUser", "variables": types.Map{"id": 10}})
+		g, err := parseGraphQLRequest(req)
+		require.NoError(t, err)
+		require.Equal(t, "GetUser", g.OperationName)
+		require.Equal(t, "query", g.OperationType)
+		require.Equal(t, []string{"user", "orders", "settings"}, g.RootFields)
+		require.Equal(t, map[string]interface{}{"id": json.Number("10")}, g.Variables)
+
+		req = newJSONRequest(types.Map{"query": document, "operationName": "DeleteUser"})
+		g, err = parseGraphQLRequest(req)
+		require.NoError(t, err)
+		require.Equal(t, "mutation", g.OperationType)
+		require.Equal(t, []string{"deleteUser"}, g.RootFields)
+		require.Empty(t, g.Variables)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		t.Parallel()
+
+		query := url.Values{"query": {"{ user(id: 1) { id } }"}, "variables": {`{"id": "1"}`}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/graphql?"+query.Encode(), nil)
+		require.NoError(t, err)
+
+		g, err := parseGraphQLRequest(req)
+		require.NoError(t, err)
+		require.Empty(t, g.OperationName)
+		require.Equal(t, "query", g.OperationType)
+		require.Equal(t, []string{"user"}, g.RootFields)
+		require.Equal(t, map[string]interface{}{"id": "1"}, g.Variables)
+	})
+
+	t.Run("graphql_content_type", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/graphql?operationName=DeleteUser", strings.NewReader(document))
+		require.NoError(t, err)
+		req.Header.Set(HeaderContentType, ContentTypeGraphQL)
+
+		g, err := parseGraphQLRequest(req)
+		require.NoError(t, err)
+		require.Equal(t, "DeleteUser", g.OperationName)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		invalidRequests := []*http.Request{
+			newJSONRequest(types.Map{"name": "not graphql"}),
+			newJSONRequest(types.Map{"query": "query { user { id }"}),
+			newJSONRequest(types.Map{"query": document}),
+			newJSONRequest(types.Map{"query": document, "operationName": "Unknown"}),
+		}
+
+		for _, req := range invalidRequests {
+			_, err := parseGraphQLRequest(req)
+			require.Error(t, err)
+		}
+	})
+}
+
+func TestMatchGraphQL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newRequest := func(query string, variables types.Map) *http.Request {
+		body := types.Map{"query": query, "variables": variables}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/echo/graphql", strings.NewReader(body.ForceJSON()))
+		require.NoError(t, err)
+		req.Header.Set(HeaderContentType, ContentTypeJSON)
+		return req
+	}
+
+	stub := NewStub().For(http.MethodPost, EndWith("/graphql")).
+		WithGraphQL(GraphQLOperationName(EqualTo("GetUser"))).
+		WithGraphQL(GraphQLOperationType(EqualTo("query"))).
+		WithGraphQL(GraphQLRootFields(ContainsAll("user", "orders"))).
+		WithGraphQL(GraphQLVariable("$.id", GreaterThan(5)))
+	require.NoError(t, stub.Request.Validate(ctx))
+
+	// The whitespace and the order of fields are not relevant
+	for _, query := range []string{
+		"query GetUser($id: ID!) { user(id: $id) { id name } orders { id } }",
+		"query   GetUser ( $id : ID! )\n{\n  orders { id }\n  user(id: $id) { name id }\n}",
+	} {
+		matched, err := matchHTTPRequest(ctx, stub, newRequest(query, types.Map{"id": 10}))
+		require.NoError(t, err)
+		require.True(t, matched, query)
+	}
+
+	for _, req := range []*http.Request{
+		newRequest("query GetUser($id: ID!) { user(id: $id) { id } }", types.Map{"id": 10}),
+		newRequest("query GetUser($id: ID!) { user(id: $id) { id } orders { id } }", types.Map{"id": 1}),
+		newRequest("mutation GetUser { user { id } orders { id } }", types.Map{"id": 10}),
+		newRequest("query GetUser { user { id }", types.Map{"id": 10}),
+	} {
+		matched, err := matchHTTPRequest(ctx, stub, req)
+		require.NoError(t, err)
+		require.False(t, matched)
+	}
+
+	mismatches := evaluateHTTPRequest(ctx, stub.Request, newRequest("query GetUser($id: ID!) { user(id: $id) { id } }", types.Map{"id": 1}))
+	require.Len(t, mismatches, 2)
+	require.Equal(t, "root_fields", mismatches[0].Key)
+	require.Equal(t, "variables[$.id]", mismatches[1].Key)
+	require.Equal(t, 6, countRules(stub.Request))
+}
+
+func TestValidateGraphQLOps(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.NoError(t, validateGraphQLOps(ctx, GraphQLOperationName(EqualTo("GetUser"))(), GraphQLVariable("$.id", NotEmpty())()))
+	require.Error(t, validateGraphQLOps(ctx, GraphQLVariable("", NotEmpty())()))
+	require.Error(t, validateGraphQLOps(ctx, GraphQLOperator{Scope: "unknown", Operator: EqualTo("A")()}))
+	require.Error(t, validateGraphQLOps(ctx, GraphQLOperationType(GreaterThan("abc"))()))
+}
+
+func TestGraphQLResponse(t *testing.T) {
+	t.Parallel()
+
+	res := GraphQLResponse(types.Map{"user": types.Map{"id": "1"}})
+	require.Equal(t, ContentTypeJSON, res.Header[HeaderContentType])
+	require.JSONEq(t, `{"data": {"user": {"id": "1"}}}`, string(res.Body))
+
+	res = GraphQLErrorResponse(&GraphQLError{
+		Message:    "user not found",
+		Path:       []interface{}{"user"},
+		Locations:  []GraphQLLocation{{Line: 1, Column: 3}},
+		Extensions: map[string]interface{}{"code": "NOT_FOUND"},
+	})
+	require.JSONEq(t, `{
+		"data": null,
+		"errors": [{"message": "user not found", "path": ["user"], "locations": [{"line": 1, "column": 3}], "extensions": {"code": "NOT_FOUND"}}]
+	}`, string(res.Body))
+}
@@ -11,15 +11,17 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"path"
 	"strings"
-	"time"
 
-	"github.com/PaesslerAG/jsonpath"
+	"github.com/antchfx/xmlquery"
 	"github.com/hungdv136/rio/internal/log"
 	fs "github.com/hungdv136/rio/internal/storage"
+	"github.com/hungdv136/rio/internal/types"
 )
 
+// The base path of mock server, which is removed before matching with path template or forwarding
+const defaultBasePath = "/echo/"
+
 // Handler handles mocking for http request
 type Handler struct {
 	fileStorage fs.FileStorage
@@ -31,6 +33,11 @@ type Handler struct {
 	// then body won't be saved to DB to avoid hurting DB performance when uploading with a file
 	// If set to zero, then body is always saved to database
 	bodyStoreThreshold int
+
+	// The forwarded headers are used to resolve the origin of request only if they are trusted
+	trustForwardedHeaders bool
+
+	callbackSender *CallbackSender
 }
 
 // NewHandler handles request
@@ -38,7 +45,7 @@ func NewHandler(stubStore StubStore, fileStorage fs.FileStorage) *Handler {
 	return &Handler{
 		stubStore:          stubStore,
 		fileStorage:        fileStorage,
-		basePath:           "/echo/",
+		basePath:           defaultBasePath,
 		bodyStoreThreshold: 1 << 20, // Default 1MB is a lot of text
 	}
 }
@@ -49,17 +56,49 @@ func (h *Handler) WithNamespace(namespace string) *Handler {
 	return h
 }
 
+// WithBasePath sets the base path which is removed before matching with path template or forwarding
+// Empty base path is used for virtual hosts, the whole url path is matched and forwarded
+func (h *Handler) WithBasePath(basePath string) *Handler {
+	h.basePath = basePath
+	return h
+}
+
 func (h *Handler) WithBodyStoreThreshold(v int) *Handler {
 	h.bodyStoreThreshold = v
 	return h
 }
 
+// WithTrustForwardedHeaders sets whether X-Forwarded-Host and X-Forwarded-Proto are trusted
+// This should be enabled only if the server is behind a proxy which overrides these headers
+func (h *Handler) WithTrustForwardedHeaders(v bool) *Handler {
+	h.trustForwardedHeaders = v
+	return h
+}
+
+// WithCallbackSender sets the sender of callbacks. Callbacks are skipped if the sender is not set
+// The caller owns the sender, which must be closed after the server is shut down
+func (h *Handler) WithCallbackSender(sender *CallbackSender) *Handler {
+	h.callbackSender = sender
+	return h
+}
+
 // Handle handles http request
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := ContextWithFileStorage(r.Context(), h.fileStorage)
+	ctx = contextWithRequestPath(ctx, h.rewritePath(r.URL.EscapedPath()))
+	ctx = contextWithRequestOrigin(ctx, parseRequestOrigin(r, h.trustForwardedHeaders))
+	r = r.WithContext(ctx)
 	incomeRequest := Capture(r, h.bodyStoreThreshold).WithNamespace(h.namespace)
 
-	defer h.stubStore.CreateIncomingRequest(ctx, incomeRequest)
+	// The request is still recorded if the client cancels it while waiting for delay
+	// Callbacks are dispatched after the request is recorded, so that their results are linked to the request
+	// They are only set once the response is written, so that callbacks are not sent for failed or cancelled requests
+	var callbacks *CallbackDispatcher
+	defer func() {
+		ctx := context.WithoutCancel(ctx)
+		_ = h.stubStore.CreateIncomingRequest(ctx, incomeRequest)
+		callbacks.Dispatch(ctx, incomeRequest)
+	}()
 
 	stubs, err := h.stubStore.GetAll(ctx, h.namespace)
 	if err != nil {
@@ -88,16 +127,53 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	if len(matchedStubs) == 0 {
 		log.Info(ctx, "no matched stub found")
-		w.WriteHeader(http.StatusNotFound)
+		incomeRequest.Diagnostics = diagnoseHTTPRequest(ctx, stubs, r)
+		writeDiagnostics(ctx, w, "no matched stub found", incomeRequest.Diagnostics)
+		return
+	}
+
+	stub, err := SelectStubWithScenario(ctx, h.stubStore, h.namespace, matchedStubs)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if stub == nil {
+		log.Info(ctx, "no matched stub found for current scenario state")
+		incomeRequest.Diagnostics, err = DiagnoseScenario(ctx, h.stubStore, h.namespace, matchedStubs)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		writeDiagnostics(ctx, w, "no matched stub found for current scenario state", incomeRequest.Diagnostics)
+		return
+	}
+
+	stub, err = SelectSequenceResponse(ctx, h.stubStore, h.namespace, stub)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	pathParams, _, err := stub.Request.matchPath(ctx, getRequestPath(ctx, r.URL))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	stub := SelectStubs(matchedStubs)
 	incomeRequest.StubID = stub.ID
 	incomeRequest.Tag = stub.Tag
+	incomeRequest.PathParams = pathParams
 
 	log.Info(ctx, "matched stub", stub.ID, stub.Description, "nb stubs", len(stubs), "in", h.namespace)
 
+	dispatcher, err := NewCallbackDispatcher(ctx, h.callbackSender, stub, &TemplateData{Request: r, PathParams: pathParams})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	if stub.Settings.DeactivateWhenMatched {
 		log.Info(ctx, "remove used stub", stub.ID)
 		if err := h.stubStore.Delete(ctx, stub.ID); err != nil {
@@ -105,9 +181,11 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if stub.Settings.DelayDuration > 0 {
-		log.Info(ctx, "delay response", stub.Settings.DelayDuration)
-		time.Sleep(stub.Settings.DelayDuration)
+	if delay := stub.Settings.SampleDelay(); delay > 0 {
+		log.Info(ctx, "delay response", delay)
+		if err := Sleep(ctx, delay); err != nil {
+			return
+		}
 	}
 
 	if stub.IsReversed() {
@@ -116,21 +194,39 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		callbacks = dispatcher
 		return
 	}
 
-	if err := h.processResponse(ctx, r, stub); err != nil {
+	if err := h.processResponse(ctx, r, stub, pathParams); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	if stub.Settings.Trickle != nil && len(stub.Response.Fault) == 0 {
+		w = newTrickleWriter(ctx, w, stub.Settings.Trickle)
+	}
+
 	if err := stub.Response.WriteTo(ctx, w); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	callbacks = dispatcher
+}
+
+// writeDiagnostics writes not found status with the closest stubs to help debugging
+func writeDiagnostics(ctx context.Context, w http.ResponseWriter, message string, diagnostics Diagnostics) {
+	w.Header().Set(HeaderContentType, ContentTypeJSON)
+	w.WriteHeader(http.StatusNotFound)
+
+	body := types.Map{"message": message, "diagnostics": diagnostics}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error(ctx, "cannot write diagnostics", err)
+	}
 }
 
-func (h *Handler) processResponse(ctx context.Context, r *http.Request, stub *Stub) error {
+func (h *Handler) processResponse(ctx context.Context, r *http.Request, stub *Stub, pathParams map[string]string) error {
 	if len(stub.Response.BodyFile) > 0 {
 		if err := stub.Response.LoadBodyFromFile(ctx, h.fileStorage); err != nil {
 			return err
@@ -138,7 +234,7 @@ func (h *Handler) processResponse(ctx context.Context, r *http.Request, stub *St
 	}
 
 	if stub.HasTemplate() {
-		if err := stub.Response.LoadBodyFromTemplate(ctx, &TemplateData{Request: r}); err != nil {
+		if err := stub.Response.LoadBodyFromTemplate(ctx, &TemplateData{Request: r, PathParams: pathParams}); err != nil {
 			return err
 		}
 	}
@@ -189,11 +285,7 @@ func (h *Handler) reverse(w http.ResponseWriter, r *http.Request, stub *Stub) er
 // The url path contains /echo as prefix of mock service
 // This should be removed before forwarding to real service
 func (h *Handler) rewritePath(urlPath string) string {
-	if len(h.namespace) > 0 {
-		return path.Join("/", strings.TrimPrefix(urlPath, "/"+h.namespace+h.basePath))
-	}
-
-	return path.Join("/", strings.TrimPrefix(urlPath, h.basePath))
+	return trimBasePath(urlPath, h.namespace, h.basePath)
 }
 
 func (h *Handler) proxyRecorder(stub *Stub) func(*http.Response) error {
@@ -245,10 +337,18 @@ func matchHTTPRequest(ctx context.Context, s *Stub, r *http.Request) (bool, erro
 		return false, nil
 	}
 
+	if matched, err := matchOrigin(ctx, s, r); err != nil || !matched {
+		return false, err
+	}
+
 	if matched, err := matchURL(ctx, s, r); err != nil || !matched {
 		return false, err
 	}
 
+	if _, matched, err := s.Request.matchPath(ctx, getRequestPath(ctx, r.URL)); err != nil || !matched {
+		return false, err
+	}
+
 	if matched, err := matchHeader(ctx, s, r); err != nil || !matched {
 		return false, err
 	}
@@ -265,6 +365,35 @@ func matchHTTPRequest(ctx context.Context, s *Stub, r *http.Request) (bool, erro
 		return false, err
 	}
 
+	if matched, err := matchGraphQL(ctx, s, r); err != nil || !matched {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func matchOrigin(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
+	if len(s.Request.Host) == 0 && len(s.Request.Scheme) == 0 && len(s.Request.Port) == 0 {
+		return true, nil
+	}
+
+	origin := getRequestOrigin(ctx, r)
+	if len(s.Request.Scheme) > 0 && !strings.EqualFold(s.Request.Scheme, origin.Scheme) {
+		return false, nil
+	}
+
+	for _, op := range s.Request.Host {
+		if matched, err := Match(ctx, op, origin.Host); err != nil || !matched {
+			return false, err
+		}
+	}
+
+	for _, op := range s.Request.Port {
+		if matched, err := Match(ctx, op, origin.Port); err != nil || !matched {
+			return false, err
+		}
+	}
+
 	return true, nil
 }
 
@@ -280,7 +409,7 @@ func matchURL(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
 
 func matchHeader(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
 	for _, op := range s.Request.Header {
-		if matched, err := Match(ctx, op.Operator, r.Header.Get(op.FieldName)); err != nil || !matched {
+		if matched, err := MatchValues(ctx, op.GetQuantifier(QuantifierFirst), op.Operator, r.Header.Values(op.FieldName)); err != nil || !matched {
 			return false, err
 		}
 	}
@@ -291,7 +420,7 @@ func matchHeader(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
 func matchQuery(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
 	query := r.URL.Query()
 	for _, op := range s.Request.Query {
-		if matched, err := Match(ctx, op.Operator, query.Get(op.FieldName)); err != nil || !matched {
+		if matched, err := MatchValues(ctx, op.GetQuantifier(QuantifierFirst), op.Operator, query[op.FieldName]); err != nil || !matched {
 			return false, err
 		}
 	}
@@ -335,28 +464,75 @@ func matchBody(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
 	}
 
 	contentType := r.Header.Get(HeaderContentType)
-	if err := validateBodyOperator(ctx, s, contentType); err != nil {
+	if !matchBodyContentType(ctx, s, contentType) {
+		return false, nil
+	}
+
+	rawOps, ops := splitRawBodyOperators(s.Request.Body)
+	if matched, err := matchRawBody(ctx, rawOps, r); err != nil || !matched {
 		return false, err
 	}
 
+	if len(ops) == 0 {
+		return true, nil
+	}
+
 	if strings.HasPrefix(contentType, ContentTypeJSON) {
-		return matchJSONBody(ctx, s, r)
+		return matchJSONBody(ctx, ops, r)
+	}
+
+	if isXMLContentType(contentType) {
+		return matchXMLBody(ctx, ops, r)
 	}
 
 	if strings.HasPrefix(contentType, ContentTypeMultipart) {
-		return matchMultiplePart(ctx, s, r)
+		return matchMultiplePart(ctx, ops, r)
 	}
 
 	if strings.HasPrefix(contentType, ContentTypeForm) {
-		return matchURLEncodedBody(ctx, s, r)
+		return matchURLEncodedBody(ctx, ops, r)
+	}
+
+	log.Info(ctx, "unsupported content type for key path", contentType)
+	return false, nil
+}
+
+// splitRawBodyOperators separates raw body operators from the operators which are applied for a key path
+func splitRawBodyOperators(ops []BodyOperator) ([]BodyOperator, []BodyOperator) {
+	rawOps := make([]BodyOperator, 0, len(ops))
+	pathOps := make([]BodyOperator, 0, len(ops))
+	for _, op := range ops {
+		if op.Raw {
+			rawOps = append(rawOps, op)
+		} else {
+			pathOps = append(pathOps, op)
+		}
+	}
+
+	return rawOps, pathOps
+}
+
+func matchRawBody(ctx context.Context, ops []BodyOperator, r *http.Request) (bool, error) {
+	if len(ops) == 0 {
+		return true, nil
+	}
+
+	body, err := io.ReadAll(readRequestBody(r))
+	if err != nil {
+		log.Error(ctx, "cannot read body", err)
+		return false, err
 	}
 
-	err := fmt.Errorf("unsupported content type %s", contentType)
-	log.Error(ctx, err)
-	return false, err
+	for _, op := range ops {
+		if matched, err := Match(ctx, op.Operator, string(body)); err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
 }
 
-func matchJSONBody(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
+func matchJSONBody(ctx context.Context, ops []BodyOperator, r *http.Request) (bool, error) {
 	dataMap := map[string]interface{}{}
 	decoder := json.NewDecoder(readRequestBody(r))
 	decoder.UseNumber()
@@ -365,13 +541,10 @@ func matchJSONBody(ctx context.Context, s *Stub, r *http.Request) (bool, error)
 		return false, err
 	}
 
-	for _, op := range s.Request.Body {
-		val, err := jsonpath.Get(op.KeyPath, dataMap)
+	for _, op := range ops {
+		val, err := getJSONPath(ctx, op.KeyPath, dataMap)
 		if err != nil {
-			if !strings.Contains(err.Error(), "unknown key") {
-				log.Error(ctx, "error when executing json path", err)
-				return false, err
-			}
+			return false, err
 		}
 
 		if matched, err := Match(ctx, op.Operator, val); err != nil || !matched {
@@ -382,13 +555,34 @@ func matchJSONBody(ctx context.Context, s *Stub, r *http.Request) (bool, error)
 	return true, nil
 }
 
-func matchMultiplePart(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
+func matchXMLBody(ctx context.Context, ops []BodyOperator, r *http.Request) (bool, error) {
+	doc, err := xmlquery.Parse(readRequestBody(r))
+	if err != nil {
+		log.Error(ctx, "cannot decode xml", err)
+		return false, err
+	}
+
+	for _, op := range ops {
+		val, err := evaluateXMLPath(ctx, doc, op)
+		if err != nil {
+			return false, err
+		}
+
+		if matched, err := Match(ctx, op.Operator, val); err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchMultiplePart(ctx context.Context, ops []BodyOperator, r *http.Request) (bool, error) {
 	if err := r.ParseMultipartForm(1024 * 1024 * 20 << 20); err != nil {
 		log.Error(ctx, err)
 		return false, err
 	}
 
-	for _, op := range s.Request.Body {
+	for _, op := range ops {
 		val := r.FormValue(op.KeyPath)
 		if matched, err := Match(ctx, op.Operator, val); err != nil || !matched {
 			return false, err
@@ -398,8 +592,8 @@ func matchMultiplePart(ctx context.Context, s *Stub, r *http.Request) (bool, err
 	return true, nil
 }
 
-func matchURLEncodedBody(ctx context.Context, s *Stub, r *http.Request) (bool, error) {
-	for _, op := range s.Request.Body {
+func matchURLEncodedBody(ctx context.Context, ops []BodyOperator, r *http.Request) (bool, error) {
+	for _, op := range ops {
 		val := r.FormValue(op.KeyPath)
 		if matched, err := Match(ctx, op.Operator, val); err != nil || !matched {
 			return false, err
@@ -410,15 +604,23 @@ func matchURLEncodedBody(ctx context.Context, s *Stub, r *http.Request) (bool, e
 }
 
 // Each body operator is applied for a specific content type
-// This is to validate whether request content type is matched with content type of all operators
-func validateBodyOperator(ctx context.Context, s *Stub, contentType string) error {
+// This is to check whether request content type is matched with content type of all operators
+// Raw body operator without content type is applied for any content type
+func matchBodyContentType(ctx context.Context, s *Stub, contentType string) bool {
 	for _, op := range s.Request.Body {
-		if !strings.HasPrefix(contentType, op.ContentType) {
-			err := fmt.Errorf("mismatch request and operator content type %s - %s", contentType, op.ContentType)
-			log.Error(ctx, err)
-			return err
+		if !isBodyContentTypeMatched(contentType, op) {
+			log.Info(ctx, "mismatch request and operator content type", contentType, op.ContentType)
+			return false
 		}
 	}
 
-	return nil
+	return true
+}
+
+func isBodyContentTypeMatched(contentType string, op BodyOperator) bool {
+	if isXMLContentType(contentType) && isXMLContentType(op.ContentType) {
+		return true
+	}
+
+	return strings.HasPrefix(contentType, op.ContentType)
 }
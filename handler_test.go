@@ -2,10 +2,12 @@ package rio
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hungdv136/rio/internal/netkit"
@@ -78,6 +80,44 @@ func TestMatchHTTPRequest(t *testing.T) {
 		require.False(t, matched)
 	})
 
+	t.Run("origin", func(t *testing.T) {
+		t.Parallel()
+
+		newRequest := func(host string, proto string) *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/echo/animal", nil)
+			require.NoError(t, err)
+			req.Host = host
+			if len(proto) > 0 {
+				req.Header.Set(HeaderXForwardedProto, proto)
+			}
+			return req
+		}
+
+		stub := NewStub().For("GET", Contains("animal")).
+			WithHost(EndWith(".partner.com")).
+			WithScheme(SchemeHTTPS).
+			WithPort(EqualTo(443))
+
+		// The origin is resolved by handler with trusted forwarded headers
+		match := func(req *http.Request, trustForwarded bool) bool {
+			ctx := contextWithRequestOrigin(ctx, parseRequestOrigin(req, trustForwarded))
+			matched, err := matchHTTPRequest(ctx, stub, req)
+			require.NoError(t, err)
+			return matched
+		}
+
+		require.True(t, match(newRequest("api.partner.com", "https"), true))
+		require.False(t, match(newRequest("api.partner.com", "https"), false))
+
+		for _, req := range []*http.Request{
+			newRequest("api.partner.com", ""),
+			newRequest("api.partner.com:8443", "https"),
+			newRequest("api.other.com", "https"),
+		} {
+			require.False(t, match(req, true), req.Host)
+		}
+	})
+
 	t.Run("regex", func(t *testing.T) {
 		t.Parallel()
 
@@ -122,27 +162,269 @@ func TestMatchHTTPRequest(t *testing.T) {
 		require.False(t, matched)
 	})
 
+	t.Run("composite", func(t *testing.T) {
+		t.Parallel()
+
+		newRequest := func(path string, platform string) *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.com"+path, nil)
+			require.NoError(t, err)
+			req.Header.Add("X-PLATFORM", platform)
+			return req
+		}
+
+		stub := NewStub().
+			For("GET", Not(Contains("/internal"))).
+			WithHeader("X-PLATFORM", AnyOf(EqualTo("ios"), EqualTo("android")))
+
+		matched, err := matchHTTPRequest(ctx, stub, newRequest("/animal", "ios"))
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		matched, err = matchHTTPRequest(ctx, stub, newRequest("/animal", "web"))
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		matched, err = matchHTTPRequest(ctx, stub, newRequest("/internal/animal", "android"))
+		require.NoError(t, err)
+		require.False(t, matched)
+	})
+
+	t.Run("multi_values", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.com/animal?tag=cat&tag=dog", nil)
+		require.NoError(t, err)
+		req.Header.Add("Accept", "text/html")
+		req.Header.Add("Accept", "application/json")
+
+		stub := NewStub().For("GET", Contains("animal")).
+			WithQueryValues("tag", QuantifierValues, ContainsAll("dog", "cat")).
+			WithQueryValues("tag", QuantifierCount, EqualTo(2)).
+			WithHeaderValues("Accept", QuantifierAny, EqualTo("application/json"))
+		matched, err := matchHTTPRequest(ctx, stub, req)
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		// First value is matched by default
+		stub = NewStub().For("GET", Contains("animal")).WithHeader("Accept", EqualTo("application/json"))
+		matched, err = matchHTTPRequest(ctx, stub, req)
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		stub = NewStub().For("GET", Contains("animal")).WithQueryValues("tag", QuantifierAll, EqualTo("cat"))
+		matched, err = matchHTTPRequest(ctx, stub, req)
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		mismatches := evaluateHTTPRequest(ctx, stub.Request, req)
+		require.Len(t, mismatches, 1)
+		require.Equal(t, []string{"cat", "dog"}, mismatches[0].Actual)
+	})
+
+	t.Run("body_json_comparison", func(t *testing.T) {
+		t.Parallel()
+
+		data := types.Map{"amount": 1500, "expires_at": time.Now().Add(-time.Hour).Format(time.RFC3339)}
+		newRequest := func() *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.com/payment", strings.NewReader(data.ForceJSON()))
+			require.NoError(t, err)
+			req.Header.Add(HeaderContentType, ContentTypeJSON)
+			return req
+		}
+
+		stub := NewStub().For("POST", Contains("payment")).
+			WithRequestBody(BodyJSONPath("$.amount", GreaterThan(1000))).
+			WithRequestBody(BodyJSONPath("$.expires_at", Before("now")))
+		matched, err := matchHTTPRequest(ctx, stub, newRequest())
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		stub = NewStub().For("POST", Contains("payment")).WithRequestBody(BodyJSONPath("$.amount", Between(0, 1000)))
+		matched, err = matchHTTPRequest(ctx, stub, newRequest())
+		require.NoError(t, err)
+		require.False(t, matched)
+	})
+
+	t.Run("body_json_equal", func(t *testing.T) {
+		t.Parallel()
+
+		data := types.Map{"id": uuid.NewString(), "name": "cat", "tags": []string{"pet", "mammal"}, "owner": types.Map{"name": "A", "age": 30}}
+		newRequest := func() *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.com/animal", strings.NewReader(data.ForceJSON()))
+			require.NoError(t, err)
+			req.Header.Add(HeaderContentType, ContentTypeJSON)
+			return req
+		}
+
+		stub := NewStub().For("POST", Contains("animal")).
+			WithRequestBody(BodyJSONEqual(types.Map{"id": PlaceholderUUID, "name": "cat", "tags": []string{"mammal", "pet"}}, IgnoreArrayOrder(), IgnorePaths("$.owner"))).
+			WithRequestBody(BodyJSONPath("$.owner", JSONEqual(types.Map{"name": "A"}, IgnoreExtraFields())))
+		matched, err := matchHTTPRequest(ctx, stub, newRequest())
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		stub = NewStub().For("POST", Contains("animal")).WithRequestBody(BodyJSONEqual(types.Map{"name": "cat"}))
+		matched, err = matchHTTPRequest(ctx, stub, newRequest())
+		require.NoError(t, err)
+		require.False(t, matched)
+	})
+
+	t.Run("body_json_schema", func(t *testing.T) {
+		t.Parallel()
+
+		schema := types.Map{"type": "object", "required": []string{"name"}, "properties": types.Map{"name": types.Map{"type": "string"}}}
+		newRequest := func(data types.Map) *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.com/animal", strings.NewReader(data.ForceJSON()))
+			require.NoError(t, err)
+			req.Header.Add(HeaderContentType, ContentTypeJSON)
+			return req
+		}
+
+		validStub := NewStub().For("POST", Contains("animal")).WithRequestBody(BodyJSONPath("$", JSONSchema(schema)))
+		invalidStub := NewStub().For("POST", Contains("animal")).WithRequestBody(BodyJSONPath("$", Not(JSONSchema(schema))))
+
+		matched, err := matchHTTPRequest(ctx, validStub, newRequest(types.Map{"name": "cat"}))
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		matched, err = matchHTTPRequest(ctx, invalidStub, newRequest(types.Map{"name": "cat"}))
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		matched, err = matchHTTPRequest(ctx, invalidStub, newRequest(types.Map{"name": 10}))
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("body_raw", func(t *testing.T) {
+		t.Parallel()
+
+		data := "id,name\n1,cat\n2,dog\n"
+		newRequest := func(contentType string) *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.com/animal/import", strings.NewReader(data))
+			require.NoError(t, err)
+			if len(contentType) > 0 {
+				req.Header.Add(HeaderContentType, contentType)
+			}
+			return req
+		}
+
+		stub := NewStub().For("POST", Contains("animal/import")).
+			WithRequestBody(BodyRaw(Contains("1,cat"))).
+			WithRequestBody(BodyRaw(Regex(`^id,name\n`))).
+			WithRequestBody(BodyRaw(Length(len(data)))).
+			WithRequestBody(BodyRaw(Hash("sha256", sha256Hex(data))))
+		for _, contentType := range []string{"text/csv", "", ContentTypeJSON} {
+			matched, err := matchHTTPRequest(ctx, stub, newRequest(contentType))
+			require.NoError(t, err)
+			require.True(t, matched, contentType)
+		}
+
+		stub = NewStub().For("POST", Contains("animal/import")).WithRequestBody(BodyRawWithContentType("text/csv", Contains("cat")))
+		matched, err := matchHTTPRequest(ctx, stub, newRequest("text/csv; charset=utf-8"))
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		matched, err = matchHTTPRequest(ctx, stub, newRequest(ContentTypeText))
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		stub = NewStub().For("POST", Contains("animal/import")).WithRequestBody(BodyRaw(EqualTo("id,name")))
+		matched, err = matchHTTPRequest(ctx, stub, newRequest("text/csv"))
+		require.NoError(t, err)
+		require.False(t, matched)
+	})
+
+	t.Run("body_content_type_mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		stub := NewStub().For("POST", Contains("animal")).WithRequestBody(BodyJSONPath("$.name", EqualTo("cat")))
+		for _, contentType := range []string{"", ContentTypeText, "application/octet-stream"} {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.com/animal", strings.NewReader(`{"name":"cat"}`))
+			require.NoError(t, err)
+			if len(contentType) > 0 {
+				req.Header.Add(HeaderContentType, contentType)
+			}
+
+			matched, err := matchHTTPRequest(ctx, stub, req)
+			require.NoError(t, err)
+			require.False(t, matched, contentType)
+		}
+	})
+
 	t.Run("body_xml", func(t *testing.T) {
 		t.Parallel()
 
 		randomValue := uuid.NewString()
-		data := types.Map{
-			"key_1": types.Map{
-				"key_2": randomValue,
-			},
-		}
+		data := fmt.Sprintf(`<animal><name>%s</name><tags><tag>bird</tag><tag>pet</tag></tags></animal>`, randomValue)
 
 		requestURL := "https://api.com/animal/create"
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(data.ForceJSON()))
+		newRequest := func(contentType string) *http.Request {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(data))
+			require.NoError(t, err)
+			req.Header.Add(HeaderContentType, contentType)
+			return req
+		}
+
+		stub := NewStub().For("POST", Contains("animal/create")).
+			WithRequestBody(BodyXMLPath("//animal/name", EqualTo(randomValue))).
+			WithRequestBody(BodyXMLPath("count(//tag)", EqualTo(2))).
+			WithRequestBody(BodyXMLPath("//tag", Contains("pet")))
+		for _, contentType := range []string{ContentTypeXML, ContentTypeAppXML, "text/xml; charset=utf-8"} {
+			matched, err := matchHTTPRequest(ctx, stub, newRequest(contentType))
+			require.NoError(t, err)
+			require.True(t, matched, contentType)
+		}
+
+		stub = NewStub().For("POST", Contains("animal/create")).WithRequestBody(BodyXMLPath("//animal/color", EqualTo(randomValue)))
+		matched, err := matchHTTPRequest(ctx, stub, newRequest(ContentTypeXML))
 		require.NoError(t, err)
-		req.Header.Add(HeaderContentType, ContentTypeJSON)
+		require.False(t, matched)
 
-		stub := NewStub().For("POST", Contains("animal/create")).WithRequestBody(BodyJSONPath("$.key_1.key_2", EqualTo(randomValue)))
+		stub = NewStub().For("POST", Contains("animal/create")).WithRequestBody(BodyXMLPath("//animal/color", Empty()))
+		matched, err = matchHTTPRequest(ctx, stub, newRequest(ContentTypeXML))
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("body_xml_namespace", func(t *testing.T) {
+		t.Parallel()
+
+		randomValue := uuid.NewString()
+		data := fmt.Sprintf(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="http://www.example.org/stock">
+  <soap:Body>
+    <m:GetStockPrice>
+      <m:StockName>%s</m:StockName>
+    </m:GetStockPrice>
+  </soap:Body>
+</soap:Envelope>`, randomValue)
+
+		requestURL := "https://api.com/stock"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(data))
+		require.NoError(t, err)
+		req.Header.Add(HeaderContentType, ContentTypeXML)
+
+		// Prefixes are declared in the request body
+		stub := NewStub().For("POST", Contains("stock")).
+			WithRequestBody(BodyXMLPath("/soap:Envelope/soap:Body/m:GetStockPrice/m:StockName", EqualTo(randomValue)))
 		matched, err := matchHTTPRequest(ctx, stub, req)
 		require.NoError(t, err)
 		require.True(t, matched)
 
-		stub = NewStub().For("POST", Contains("animal/create")).WithRequestBody(BodyJSONPath("$.key_1.key_n", EqualTo(randomValue)))
+		// Prefixes are mapped to namespace uris which are different from prefixes in the request body
+		namespaces := map[string]string{
+			"env":   "http://schemas.xmlsoap.org/soap/envelope/",
+			"stock": "http://www.example.org/stock",
+		}
+		stub = NewStub().For("POST", Contains("stock")).
+			WithRequestBody(BodyXMLPathWithNamespaces("/env:Envelope/env:Body/stock:GetStockPrice/stock:StockName", namespaces, EqualTo(randomValue)))
+		matched, err = matchHTTPRequest(ctx, stub, req)
+		require.NoError(t, err)
+		require.True(t, matched)
+
+		namespaces["stock"] = "http://www.example.org/other"
+		stub = NewStub().For("POST", Contains("stock")).
+			WithRequestBody(BodyXMLPathWithNamespaces("//stock:StockName", namespaces, EqualTo(randomValue)))
 		matched, err = matchHTTPRequest(ctx, stub, req)
 		require.NoError(t, err)
 		require.False(t, matched)
@@ -0,0 +1,77 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"  // nolint:gosec
+	"crypto/sha1" // nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// Defines supported algorithms of hash operator
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// parseHashValue parses value of hash operator which is formatted as <algorithm>:<hex digest>
+func parseHashValue(value interface{}) (func() hash.Hash, []byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("hash value must be a string, got %T", value)
+	}
+
+	algorithm, hexDigest, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, nil, fmt.Errorf("hash value must be formatted as <algorithm>:<hex digest>, got %s", s)
+	}
+
+	newHash, ok := hashAlgorithms[strings.ToLower(algorithm)]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported hash algorithm %s", algorithm)
+	}
+
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid hex digest %s: %w", hexDigest, err)
+	}
+
+	if len(digest) != newHash().Size() {
+		return nil, nil, fmt.Errorf("invalid digest length for %s", algorithm)
+	}
+
+	return newHash, digest, nil
+}
+
+func executeHashOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	newHash, expected, err := parseHashValue(op.Value)
+	if err != nil {
+		log.Error(ctx, err)
+		return false, err
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		err := fmt.Errorf("unsupported data type %T", value)
+		log.Error(ctx, err)
+		return false, err
+	}
+
+	h := newHash()
+	_, _ = h.Write(data)
+	return bytes.Equal(h.Sum(nil), expected), nil
+}
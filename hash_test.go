@@ -0,0 +1,42 @@
+package rio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExecuteHashOperator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testCases := []struct {
+		Name             string
+		Operator         Operator
+		ActualValue      interface{}
+		ExpectedMatched  bool
+		ExpectedHasError bool
+	}{
+		{"md5", Hash("md5", "5d41402abc4b2a76b9719d911017c592")(), "hello", true, false},
+		{"sha1_upper_case", Hash("SHA1", "AAF4C61DDCC5E8A2DABEDE0F3B482CD9AEA9434D")(), "hello", true, false},
+		{"sha256_bytes", Hash("sha256", sha256Hex("hello"))(), []byte("hello"), true, false},
+		{"sha512", Hash("sha512", "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043")(), "hello", true, false},
+		{"not_matched", Hash("sha256", sha256Hex("hello"))(), "world", false, false},
+		{"unsupported_value", Hash("sha256", sha256Hex("hello"))(), 10, false, true},
+		{"invalid_operator_value", Operator{Name: OpHash, Value: "sha256"}, "hello", false, true},
+	}
+
+	for _, tc := range testCases {
+		matched, err := executeHashOperator(ctx, tc.Operator, tc.ActualValue)
+		require.Equal(t, tc.ExpectedHasError, err != nil, tc.Name)
+		require.Equal(t, tc.ExpectedMatched, matched, tc.Name)
+	}
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,45 +16,96 @@ import (
 	fs "github.com/hungdv136/rio/internal/storage"
 )
 
+// The routes of mock server, other routes are admin routes
+const (
+	echoRoute          = "/echo/*path"
+	namespaceEchoRoute = "/:namespace/echo/*path"
+)
+
 type AppOption func(*App)
 
 // App defines app interface
 type App struct {
-	config      *config.Config
-	fileStorage fs.FileStorage
-	stubStore   rio.StubStore
-	kit         *gin.Engine
+	config         *config.Config
+	fileStorage    fs.FileStorage
+	stubStore      rio.StubStore
+	callbackSender *rio.CallbackSender
+	hostBindings   rio.HostBindings
+	kit            *gin.Engine
 }
 
 // NewApp returns new app
 func NewApp(ctx context.Context, config *config.Config, options ...AppOption) (*App, error) {
-	stubStore, err := setup.ProvideStubStore(ctx, config)
-	if err != nil {
-		return nil, err
+	app := &App{
+		config: config,
+		kit:    gin.New(),
 	}
 
-	fileStorage, err := setup.ProvideFileStorage(ctx, config)
-	if err != nil {
-		return nil, err
+	for _, optionFunc := range options {
+		optionFunc(app)
 	}
 
-	app := &App{
-		config:      config,
-		stubStore:   stubStore,
-		fileStorage: fileStorage,
-		kit:         gin.New(),
+	if app.stubStore == nil {
+		stubStore, err := setup.ProvideStubStore(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+
+		app.stubStore = stubStore
 	}
 
-	for _, optionFunc := range options {
-		optionFunc(app)
+	if app.fileStorage == nil {
+		fileStorage, err := setup.ProvideFileStorage(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+
+		app.fileStorage = fileStorage
 	}
 
+	if app.callbackSender == nil {
+		app.callbackSender = setup.ProvideCallbackSender(config, app.stubStore)
+	}
+
+	hostBindings, err := rio.ParseHostBindings(config.HostBindings)
+	if err != nil {
+		log.Error(ctx, "cannot parse host bindings", err)
+		return nil, err
+	}
+
+	app.hostBindings = hostBindings
 	app.setup()
 	return app, nil
 }
 
-// Start starts app
+// WithStubStore uses the given stub store instead of creating a new one from config
+// This is to share the stub store with the grpc server in the same process
+func WithStubStore(stubStore rio.StubStore) AppOption {
+	return func(app *App) {
+		app.stubStore = stubStore
+	}
+}
+
+// WithFileStorage uses the given file storage instead of creating a new one from config
+func WithFileStorage(fileStorage fs.FileStorage) AppOption {
+	return func(app *App) {
+		app.fileStorage = fileStorage
+	}
+}
+
+// WithCallbackSender uses the given callback sender instead of creating a new one from config
+// This is to share the callback sender with the grpc server in the same process
+func WithCallbackSender(sender *rio.CallbackSender) AppOption {
+	return func(app *App) {
+		app.callbackSender = sender
+	}
+}
+
+// Start starts app, the pending callbacks are drained when the server is shut down
 func (app *App) Start(ctx context.Context) error {
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
 	address := app.config.ServerAddress
 	srv := &http.Server{
 		Addr:              address,
@@ -59,37 +113,98 @@ func (app *App) Start(ctx context.Context) error {
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+
+		log.Info(ctx, "shutting down server")
+		if err := srv.Shutdown(context.WithoutCancel(ctx)); err != nil {
+			log.Error(ctx, "cannot shutdown server", err)
+		}
+
+		app.callbackSender.Close()
+	}()
+
 	log.Info(ctx, "starting server", address)
-	return srv.ListenAndServe()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-stopped
+	return nil
 }
 
 func (app *App) setup() {
 	app.kit.Use(RequestIDMiddleware())
 	app.kit.Use(RequestTimeMiddleware())
 	app.kit.Use(Recovery())
+
+	if len(app.hostBindings) > 0 {
+		app.kit.Use(app.handleVirtualHost)
+	}
+
 	app.initRoutes()
 }
 
+// handleVirtualHost handles the requests to a bound host in its namespace without base path
+// Other requests and the requests to admin routes are passed to the next handlers, so a bound host cannot shadow admin routes
+func (app *App) handleVirtualHost(ctx *gin.Context) {
+	if isAdminRoute(ctx.FullPath()) {
+		ctx.Next()
+		return
+	}
+
+	namespace, ok := app.hostBindings.Namespace(ctx.Request, app.config.TrustForwardedHeaders)
+	if !ok {
+		ctx.Next()
+		return
+	}
+
+	handler := app.newMockHandler(namespace).WithBasePath("")
+	handler.Handle(ctx.Writer, ctx.Request)
+	ctx.Abort()
+}
+
+// isAdminRoute returns true if the matched route is not an echo route. The full path is empty if no route is matched
+func isAdminRoute(fullPath string) bool {
+	return len(fullPath) > 0 && fullPath != echoRoute && fullPath != namespaceEchoRoute
+}
+
+func (app *App) newMockHandler(namespace string) *rio.Handler {
+	return rio.NewHandler(app.stubStore, app.fileStorage).
+		WithBodyStoreThreshold(app.config.BodyStoreThreshold).
+		WithTrustForwardedHeaders(app.config.TrustForwardedHeaders).
+		WithCallbackSender(app.callbackSender).
+		WithNamespace(namespace)
+}
+
 func (app *App) initRoutes() {
 	app.kit.GET("/ping", app.handlePing)
 	app.kit.DELETE("/reset", app.handleReset)
 	app.kit.POST("/stub/create_many", app.handleCreate)
 	app.kit.POST("/stub/upload", app.handleUpload)
 	app.kit.GET("/stub/list", app.handleGetStubs)
+	app.kit.GET("/stub/:id", app.handleGetStub)
+	app.kit.PUT("/stub/:id", app.handleUpdateStub)
+	app.kit.PATCH("/stub/:id", app.handlePatchStub)
+	app.kit.DELETE("/stub/:id", app.handleDeleteStub)
+	app.kit.POST("/stub/:id/activate", app.handleActivateStub)
+	app.kit.POST("/stub/:id/deactivate", app.handleDeactivateStub)
 	app.kit.POST("/proto/upload", app.handleUploadProto)
 	app.kit.POST("/incoming_request/list", app.handleGetIncomingRequest)
-
-	app.kit.Any("/echo/*path", func(ctx *gin.Context) {
-		handler := rio.NewHandler(app.stubStore, app.fileStorage).WithBodyStoreThreshold(app.config.BodyStoreThreshold)
-		handler.Handle(ctx.Writer, ctx.Request)
+	app.kit.POST("/incoming_request/verify", app.handleVerifyIncomingRequest)
+	app.kit.POST("/callback/list", app.handleGetCallbacks)
+	app.kit.GET("/scenario/list", app.handleGetScenarios)
+	app.kit.POST("/scenario/set", app.handleSetScenario)
+	app.kit.DELETE("/scenario/reset", app.handleResetScenarios)
+
+	app.kit.Any(echoRoute, func(ctx *gin.Context) {
+		app.newMockHandler("").Handle(ctx.Writer, ctx.Request)
 	})
 
-	app.kit.Any("/:namespace/echo/*path", func(ctx *gin.Context) {
-		namespace := ctx.Param("namespace")
-		handler := rio.NewHandler(app.stubStore, app.fileStorage).
-			WithBodyStoreThreshold(app.config.BodyStoreThreshold).
-			WithNamespace(namespace)
-		handler.Handle(ctx.Writer, ctx.Request)
+	app.kit.Any(namespaceEchoRoute, func(ctx *gin.Context) {
+		app.newMockHandler(ctx.Param("namespace")).Handle(ctx.Writer, ctx.Request)
 	})
 }
 
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hungdv136/rio"
+	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/types"
+)
+
+// handleGetCallbacks handles get the results of callbacks
+// GetCallbacks godoc
+// @Summary     Get callbacks
+// @Description Get the delivery results of callbacks which are sent after stubs are matched
+// @ID          get-callbacks
+// @Tags        Callbacks
+// @Param       request body rio.CallbackQueryOption true "request body"
+// @Success     200 {object}types.Map{callbacks=[]rio.CallbackResult}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /callback/list [post]
+func (app *App) handleGetCallbacks(ctx *gin.Context) {
+	params := rio.CallbackQueryOption{}
+	if err := ctx.ShouldBind(&params); err != nil {
+		log.Error(ctx, err)
+		SendError(ctx, err)
+		return
+	}
+
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+
+	callbacks, err := app.stubStore.GetCallbackResults(ctx, &params)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, "get callbacks successfully", types.Map{"callbacks": callbacks})
+}
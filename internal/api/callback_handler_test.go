@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio"
+	"github.com/hungdv136/rio/internal/config"
+	"github.com/hungdv136/rio/internal/netkit"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackHandlers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, err := NewApp(ctx, config.NewConfig())
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	result := &rio.CallbackResult{Namespace: namespace, StubID: 1, IncomingRequestID: 2, URL: "http://localhost/webhook", Method: http.MethodPost, StatusCode: http.StatusOK, Attempts: 1, Delivered: true}
+	require.NoError(t, app.stubStore.CreateCallbackResult(ctx, result))
+
+	type callbacksData struct {
+		Callbacks []*rio.CallbackResult `json:"callbacks"`
+	}
+
+	params := types.Map{"namespace": namespace, "incoming_request_id": 2}
+	tc := netkit.NewTestCase("list", http.MethodPost, "/callback/list", params, http.StatusOK, VerdictSuccess)
+	res := netkit.ExecuteTestCase[callbacksData](t, tc, app.kit)
+	require.Len(t, res.Body.Data.Callbacks, 1)
+	require.Equal(t, result.ID, res.Body.Data.Callbacks[0].ID)
+	require.True(t, res.Body.Data.Callbacks[0].Delivered)
+
+	params = types.Map{"namespace": namespace, "incoming_request_id": 3}
+	tc = netkit.NewTestCase("not_found", http.MethodPost, "/callback/list", params, http.StatusOK, VerdictSuccess)
+	res = netkit.ExecuteTestCase[callbacksData](t, tc, app.kit)
+	require.Empty(t, res.Body.Data.Callbacks)
+}
@@ -14,6 +14,7 @@ const (
 	VerdictFailure           = "failure"
 	VerdictMissingParameters = "missing_parameters"
 	VerdictInvalidParameters = "invalid_parameters"
+	VerdictNotFound          = "not_found"
 )
 
 // SendJSON sends JSON
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hungdv136/rio"
+	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/types"
+)
+
+// handleGetScenarios handles get the current states of scenarios
+// GetScenarios godoc
+// @Summary     Get scenarios
+// @Description Get the current states of scenarios in a namespace. Scenarios which have not been started are not returned
+// @ID          get-scenarios
+// @Tags        Scenarios
+// @Param       namespace query string false "Namespace"
+// @Param       name query string false "Scenario name"
+// @Success     200 {object}types.Map{scenarios=[]rio.Scenario}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /scenario/list [get]
+func (app *App) handleGetScenarios(ctx *gin.Context) {
+	option := &rio.ScenarioQueryOption{
+		Namespace: ctx.Query("namespace"),
+		Name:      ctx.Query("name"),
+	}
+
+	scenarios, err := app.stubStore.GetScenarios(ctx, option)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, "list scenarios successfully", types.Map{"scenarios": scenarios})
+}
+
+// handleSetScenario handles set the current state of a scenario
+// SetScenario godoc
+// @Summary     Set scenario state
+// @Description Set the current state of a scenario
+// @ID          set-scenario
+// @Tags        Scenarios
+// @Param       request body rio.Scenario true "request body"
+// @Success     200 {object}types.Map{scenario=rio.Scenario}
+// @Failure     400 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /scenario/set [post]
+func (app *App) handleSetScenario(ctx *gin.Context) {
+	scenario := rio.Scenario{}
+	if err := ctx.ShouldBind(&scenario); err != nil {
+		log.Error(ctx, err)
+		SendError(ctx, err)
+		return
+	}
+
+	if err := scenario.Validate(ctx); err != nil {
+		SendJSON(ctx, http.StatusBadRequest, VerdictInvalidParameters, err.Error(), types.Map{})
+		return
+	}
+
+	if err := app.stubStore.SetScenarioState(ctx, &scenario); err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, "set scenario state successfully", types.Map{"scenario": scenario})
+}
+
+// handleResetScenarios handles reset scenarios to the initial state
+// ResetScenarios godoc
+// @Summary     Reset scenarios
+// @Description Reset scenarios in a namespace to the initial state. All scenarios in namespace are reset if name is empty
+// @ID          reset-scenarios
+// @Tags        Scenarios
+// @Param       namespace query string false "Namespace"
+// @Param       name query string false "Scenario name"
+// @Success     200 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /scenario/reset [delete]
+func (app *App) handleResetScenarios(ctx *gin.Context) {
+	option := &rio.ScenarioQueryOption{
+		Namespace: ctx.Query("namespace"),
+		Name:      ctx.Query("name"),
+	}
+
+	if err := app.stubStore.ResetScenarios(ctx, option); err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, fmt.Sprintf("reset scenario '%s' in '%s' successfully", option.Name, option.Namespace), types.Map{})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio"
+	"github.com/hungdv136/rio/internal/config"
+	"github.com/hungdv136/rio/internal/netkit"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioHandlers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, err := NewApp(ctx, config.NewConfig())
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	name := uuid.NewString()
+
+	type scenariosData struct {
+		Scenarios []*rio.Scenario `json:"scenarios"`
+	}
+
+	getScenarios := func(t *testing.T) []*rio.Scenario {
+		tc := netkit.NewTestCase("list", http.MethodGet, "/scenario/list", types.Map{"namespace": namespace, "name": name}, http.StatusOK, VerdictSuccess)
+		res := netkit.ExecuteTestCase[scenariosData](t, tc, app.kit)
+		return res.Body.Data.Scenarios
+	}
+
+	invalidParams := types.Map{"namespace": namespace, "name": name}
+	tc := netkit.NewTestCase("missing_state", http.MethodPost, "/scenario/set", invalidParams, http.StatusBadRequest, VerdictInvalidParameters)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+	require.Empty(t, getScenarios(t))
+
+	validParams := types.Map{"namespace": namespace, "name": name, "state": "pending"}
+	tc = netkit.NewTestCase("set", http.MethodPost, "/scenario/set", validParams, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+
+	scenarios := getScenarios(t)
+	require.Len(t, scenarios, 1)
+	require.Equal(t, name, scenarios[0].Name)
+	require.Equal(t, "pending", scenarios[0].State)
+
+	resetPath := fmt.Sprintf("/scenario/reset?namespace=%s&name=%s", namespace, name)
+	tc = netkit.NewTestCase("reset", http.MethodDelete, resetPath, types.Map{}, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+	require.Empty(t, getScenarios(t))
+}
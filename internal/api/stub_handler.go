@@ -3,8 +3,10 @@ package api
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -55,11 +57,7 @@ func (app *App) handleCreate(ctx *gin.Context) {
 			return
 		}
 
-		if stub.Request != nil && stub.Request.Method == rio.MethodGrpc {
-			stub.Protocol = rio.ProtocolGrpc
-		} else {
-			stub.Protocol = rio.ProtocolHTTP
-		}
+		stub.Protocol = resolveProtocol(stub)
 
 		// By default active should be true since it is likely a mistake instead of intention
 		if !stub.Active {
@@ -110,6 +108,263 @@ func (app *App) handleGetStubs(ctx *gin.Context) {
 	SendSuccess(ctx, "list stubs successfully", data)
 }
 
+// handleGetStub handles get a stub by id
+// GetStub godoc
+// @Summary     Get stub
+// @Description Get a stub by id including inactive stub
+// @ID          get-stub
+// @Tags        Stubs
+// @Param       id path int true "Stub ID"
+// @Param       return_encoded query bool false "Return the encoded response body"
+// @Success     200 {object}types.Map{stub=rio.Stub}
+// @Failure     404 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /stub/{id} [get]
+func (app *App) handleGetStub(ctx *gin.Context) {
+	stub, ok := app.findStub(ctx)
+	if !ok {
+		return
+	}
+
+	sendStub(ctx, "get stub successfully", stub)
+}
+
+// handleUpdateStub handles replace a stub by id
+// UpdateStub godoc
+// @Summary     Update stub
+// @Description Replace a stub by id. Namespace and active status are not changed, use activate or deactivate API to change active status
+// @ID          update-stub
+// @Tags        Stubs
+// @Param       id path int true "Stub ID"
+// @Param       request body rio.Stub true "request body"
+// @Success     200 {object}types.Map{stub=rio.Stub}
+// @Failure     400 {object}types.Map{message=string}
+// @Failure     404 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /stub/{id} [put]
+func (app *App) handleUpdateStub(ctx *gin.Context) {
+	stub, ok := app.findStub(ctx)
+	if !ok {
+		return
+	}
+
+	params := rio.Stub{}
+	if err := ctx.ShouldBind(&params); err != nil {
+		SendJSON(ctx, http.StatusBadRequest, VerdictInvalidParameters, err.Error(), types.Map{})
+		return
+	}
+
+	params.ID = stub.ID
+	params.Namespace = stub.Namespace
+	params.Active = stub.Active
+	params.CreatedAt = stub.CreatedAt
+	app.saveStub(ctx, "update stub successfully", &params)
+}
+
+// handlePatchStub handles partially update a stub by id
+// PatchStub godoc
+// @Summary     Patch stub
+// @Description Update the given fields of a stub by id. Only description, request, response, sequence, proxy, weight, settings, scenario and callbacks can be patched
+// @ID          patch-stub
+// @Tags        Stubs
+// @Param       id path int true "Stub ID"
+// @Param       request body rio.Stub true "request body"
+// @Success     200 {object}types.Map{stub=rio.Stub}
+// @Failure     400 {object}types.Map{message=string}
+// @Failure     404 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /stub/{id} [patch]
+func (app *App) handlePatchStub(ctx *gin.Context) {
+	stub, ok := app.findStub(ctx)
+	if !ok {
+		return
+	}
+
+	patch := newStubPatch(stub)
+	if err := ctx.ShouldBind(&patch); err != nil {
+		SendJSON(ctx, http.StatusBadRequest, VerdictInvalidParameters, err.Error(), types.Map{})
+		return
+	}
+
+	patch.apply(stub)
+	app.saveStub(ctx, "patch stub successfully", stub)
+}
+
+// stubPatch holds the fields of stub which can be patched
+// It is initialized from the current stub, so that the omitted fields are kept unchanged
+type stubPatch struct {
+	Description string                `json:"description" yaml:"description"`
+	Request     *rio.RequestMatching  `json:"request" yaml:"request"`
+	Response    *rio.Response         `json:"response" yaml:"response"`
+	Sequence    *rio.ResponseSequence `json:"sequence" yaml:"sequence"`
+	Proxy       *rio.Proxy            `json:"proxy" yaml:"proxy"`
+	Weight      int                   `json:"weight" yaml:"weight"`
+	Settings    rio.StubSettings      `json:"settings" yaml:"settings"`
+	Scenario    *rio.StubScenario     `json:"scenario" yaml:"scenario"`
+	Callbacks   []*rio.Callback       `json:"callbacks" yaml:"callbacks"`
+}
+
+func newStubPatch(stub *rio.Stub) stubPatch {
+	return stubPatch{
+		Description: stub.Description,
+		Request:     stub.Request,
+		Response:    stub.Response,
+		Sequence:    stub.Sequence,
+		Proxy:       stub.Proxy,
+		Weight:      stub.Weight,
+		Settings:    stub.Settings,
+		Scenario:    stub.Scenario,
+		Callbacks:   stub.Callbacks,
+	}
+}
+
+func (p stubPatch) apply(stub *rio.Stub) {
+	stub.Description = p.Description
+	stub.Request = p.Request
+	stub.Response = p.Response
+	stub.Sequence = p.Sequence
+	stub.Proxy = p.Proxy
+	stub.Weight = p.Weight
+	stub.Settings = p.Settings
+	stub.Scenario = p.Scenario
+	stub.Callbacks = p.Callbacks
+}
+
+// handleDeleteStub handles delete a stub by id
+// DeleteStub godoc
+// @Summary     Delete stub
+// @Description Delete a stub by id. The stub is kept as inactive in database, so that it can be activated again
+// @ID          delete-stub
+// @Tags        Stubs
+// @Param       id path int true "Stub ID"
+// @Success     200 {object}types.Map{}
+// @Failure     404 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /stub/{id} [delete]
+func (app *App) handleDeleteStub(ctx *gin.Context) {
+	stub, ok := app.findStub(ctx)
+	if !ok {
+		return
+	}
+
+	if err := app.stubStore.Delete(ctx, stub.ID); err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, "delete stub successfully", types.Map{})
+}
+
+// handleActivateStub handles activate a stub by id
+// ActivateStub godoc
+// @Summary     Activate stub
+// @Description Activate a stub by id
+// @ID          activate-stub
+// @Tags        Stubs
+// @Param       id path int true "Stub ID"
+// @Success     200 {object}types.Map{stub=rio.Stub}
+// @Failure     404 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /stub/{id}/activate [post]
+func (app *App) handleActivateStub(ctx *gin.Context) {
+	app.setStubActive(ctx, true)
+}
+
+// handleDeactivateStub handles deactivate a stub by id
+// DeactivateStub godoc
+// @Summary     Deactivate stub
+// @Description Deactivate a stub by id
+// @ID          deactivate-stub
+// @Tags        Stubs
+// @Param       id path int true "Stub ID"
+// @Success     200 {object}types.Map{stub=rio.Stub}
+// @Failure     404 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /stub/{id}/deactivate [post]
+func (app *App) handleDeactivateStub(ctx *gin.Context) {
+	app.setStubActive(ctx, false)
+}
+
+func (app *App) setStubActive(ctx *gin.Context, active bool) {
+	stub, ok := app.findStub(ctx)
+	if !ok {
+		return
+	}
+
+	stub.Active = active
+	if err := app.stubStore.Update(ctx, stub); err != nil {
+		sendUpdateError(ctx, stub.ID, err)
+		return
+	}
+
+	sendStub(ctx, "update stub status successfully", stub)
+}
+
+// findStub finds stub by id in path, sends error response and returns false if not found
+func (app *App) findStub(ctx *gin.Context) (*rio.Stub, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		SendJSON(ctx, http.StatusBadRequest, VerdictInvalidParameters, "invalid stub id", types.Map{})
+		return nil, false
+	}
+
+	stub, err := app.stubStore.Find(ctx, id)
+	if err != nil {
+		SendError(ctx, err)
+		return nil, false
+	}
+
+	if stub == nil {
+		SendJSON(ctx, http.StatusNotFound, VerdictNotFound, fmt.Sprintf("stub %d not found", id), types.Map{})
+		return nil, false
+	}
+
+	return stub, true
+}
+
+func (app *App) saveStub(ctx *gin.Context, message string, stub *rio.Stub) {
+	if err := stub.Validate(ctx); err != nil {
+		SendJSON(ctx, http.StatusBadRequest, VerdictInvalidParameters, "invalid stub - "+err.Error(), types.Map{})
+		return
+	}
+
+	stub.Protocol = resolveProtocol(stub)
+	if err := app.stubStore.Update(ctx, stub); err != nil {
+		sendUpdateError(ctx, stub.ID, err)
+		return
+	}
+
+	sendStub(ctx, message, stub)
+}
+
+// sendUpdateError sends not found if the stub has been deleted before updating
+func sendUpdateError(ctx *gin.Context, id int64, err error) {
+	if errors.Is(err, rio.ErrStubNotFound) {
+		SendJSON(ctx, http.StatusNotFound, VerdictNotFound, fmt.Sprintf("stub %d not found", id), types.Map{})
+		return
+	}
+
+	SendError(ctx, err)
+}
+
+func sendStub(ctx *gin.Context, message string, stub *rio.Stub) {
+	data, err := buildResponseStub(ctx, ctx.Query("return_encoded") != "true", stub)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, message, data)
+}
+
+func resolveProtocol(stub *rio.Stub) string {
+	if stub.Request != nil && stub.Request.Method == rio.MethodGrpc {
+		return rio.ProtocolGrpc
+	}
+
+	return rio.ProtocolHTTP
+}
+
 // handleUpload
 // Upload godoc
 // @Summary Upload file API
@@ -253,6 +508,46 @@ func (app *App) handleGetIncomingRequest(ctx *gin.Context) {
 	SendSuccess(ctx, "get incoming request successfully", types.Map{"requests": requests})
 }
 
+// handleVerifyIncomingRequest handles verify the number of requests which are matched with the request matching
+// VerifyRequests godoc
+// @Summary     Verify requests
+// @Description Verify the number of requests which are matched with the request matching. Near misses are returned if verification is failed
+// @ID          verify-requests
+// @Tags        Requests
+// @Param       request body rio.Verification true "request body"
+// @Success     200 {object}types.Map{result=rio.VerificationResult}
+// @Failure     400 {object}types.Map{message=string}
+// @Failure     500 {object}types.Map{message=string}
+// @Router      /incoming_request/verify [post]
+func (app *App) handleVerifyIncomingRequest(ctx *gin.Context) {
+	params := rio.Verification{}
+	if err := ctx.ShouldBind(&params); err != nil {
+		log.Error(ctx, err)
+		SendError(ctx, err)
+		return
+	}
+
+	if err := params.Validate(ctx); err != nil {
+		SendJSON(ctx, http.StatusBadRequest, VerdictInvalidParameters, err.Error(), types.Map{})
+		return
+	}
+
+	requests, err := app.stubStore.GetIncomingRequests(ctx, &rio.IncomingQueryOption{Namespace: params.Namespace})
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	// Schema files of json_schema operator are loaded from file storage
+	result, err := rio.VerifyRequests(rio.ContextWithFileStorage(ctx, app.fileStorage), requests, &params)
+	if err != nil {
+		SendError(ctx, err)
+		return
+	}
+
+	SendSuccess(ctx, "verify incoming request successfully", types.Map{"result": result})
+}
+
 // handleReset handles reset stubs by a namespace. If the namespace is "reset_all", then reset all stubs
 // Reset godoc
 // @Summary     Reset stubs
@@ -289,33 +584,55 @@ func buildResponseStubs(ctx context.Context, shouldDecode bool, stubs []*rio.Stu
 
 	stubsMap := make([]types.Map, len(stubs))
 	for i, stub := range stubs {
-		m, err := types.CreateMapFromStruct(stub)
+		m, err := decodeStub(ctx, stub)
 		if err != nil {
-			log.Error(ctx, err)
 			return nil, err
 		}
 
 		stubsMap[i] = m
-		res := stub.Response
+	}
 
-		if res == nil || res.Body == nil || res.Header == nil {
-			log.Info(ctx, "body is nil")
-			continue
-		}
+	return types.Map{"stubs": stubsMap}, nil
+}
 
-		contentType := res.Header[rio.HeaderContentType]
-		if strings.Contains(contentType, rio.ContentTypeJSON) {
-			bodyMap, err := types.CreateMapFromReader(bytes.NewReader(res.Body))
-			if err != nil {
-				log.Error(ctx, err, string(res.Body))
-				return nil, err
-			}
-
-			m.ForceMap("response")["body"] = bodyMap
-			log.Info(ctx, "decoded body from base64 to raw json", contentType, m.ForceJSON())
-			continue
+func buildResponseStub(ctx context.Context, shouldDecode bool, stub *rio.Stub) (types.Map, error) {
+	if !shouldDecode {
+		return types.Map{"stub": stub}, nil
+	}
+
+	m, err := decodeStub(ctx, stub)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.Map{"stub": m}, nil
+}
+
+// decodeStub converts stub to map and decodes JSON response body from base64 to raw JSON
+func decodeStub(ctx context.Context, stub *rio.Stub) (types.Map, error) {
+	m, err := types.CreateMapFromStruct(stub)
+	if err != nil {
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	res := stub.Response
+	if res == nil || res.Body == nil || res.Header == nil {
+		log.Info(ctx, "body is nil")
+		return m, nil
+	}
+
+	contentType := res.Header[rio.HeaderContentType]
+	if strings.Contains(contentType, rio.ContentTypeJSON) {
+		bodyMap, err := types.CreateMapFromReader(bytes.NewReader(res.Body))
+		if err != nil {
+			log.Error(ctx, err, string(res.Body))
+			return nil, err
 		}
+
+		m.ForceMap("response")["body"] = bodyMap
+		log.Info(ctx, "decoded body from base64 to raw json", contentType, m.ForceJSON())
 	}
 
-	return types.Map{"stubs": stubsMap}, nil
+	return m, nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -146,6 +147,93 @@ func TestGetStubs(t *testing.T) {
 	}
 }
 
+func TestStubHandlers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, err := NewApp(ctx, config.NewConfig())
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	stub := rio.NewStub().
+		WithNamespace(namespace).
+		For("GET", rio.Contains("animal/get")).
+		WillReturn(rio.NewResponse().WithBody(rio.MustToJSON(types.Map{"data": uuid.NewString()})))
+	require.NoError(t, app.stubStore.Create(ctx, stub))
+
+	type stubData struct {
+		Stub *rio.Stub `json:"stub"`
+	}
+
+	stubPath := fmt.Sprintf("/stub/%d", stub.ID)
+	getStub := func(t *testing.T) *rio.Stub {
+		tc := netkit.NewTestCase("get", http.MethodGet, stubPath, types.Map{"return_encoded": true}, http.StatusOK, VerdictSuccess)
+		return netkit.ExecuteTestCase[stubData](t, tc, app.kit).Body.Data.Stub
+	}
+
+	getStubs := func(t *testing.T) []*rio.Stub {
+		tc := netkit.NewTestCase("list", http.MethodGet, "/stub/list", types.Map{"namespace": namespace}, http.StatusOK, VerdictSuccess)
+		return netkit.ExecuteTestCase[rio.ArrayStubs](t, tc, app.kit).Body.Data.Stubs
+	}
+
+	tc := netkit.NewTestCase("not_found", http.MethodGet, fmt.Sprintf("/stub/%d", stub.ID+1000), types.Map{}, http.StatusNotFound, VerdictNotFound)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+
+	tc = netkit.NewTestCase("invalid_id", http.MethodGet, "/stub/abc", types.Map{}, http.StatusBadRequest, VerdictInvalidParameters)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+
+	// Decoded JSON body by default
+	tc = netkit.NewTestCase("get_decoded", http.MethodGet, stubPath, types.Map{}, http.StatusOK, VerdictSuccess)
+	res := netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+	require.NotEmpty(t, res.Body.Data.ForceMap("stub").ForceMap("response").ForceMap("body").ForceString("data"))
+
+	description := uuid.NewString()
+	updateParams, err := types.CreateMapFromStruct(rio.NewStub().
+		WithNamespace(uuid.NewString()).
+		WithDescription(description).
+		For("POST", rio.Contains("animal/create")).
+		WillReturn(rio.NewResponse().WithStatusCode(http.StatusCreated)))
+	require.NoError(t, err)
+
+	tc = netkit.NewTestCase("update", http.MethodPut, stubPath, updateParams, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+
+	updatedStub := getStub(t)
+	require.Equal(t, description, updatedStub.Description)
+	require.Equal(t, "POST", updatedStub.Request.Method)
+	require.Equal(t, http.StatusCreated, updatedStub.Response.StatusCode)
+	require.Equal(t, namespace, updatedStub.Namespace)
+	require.True(t, updatedStub.Active)
+
+	tc = netkit.NewTestCase("update_invalid", http.MethodPut, stubPath, types.Map{"namespace": namespace}, http.StatusBadRequest, VerdictInvalidParameters)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+
+	// Only matching, response and settings fields can be patched
+	patchParams := types.Map{"weight": 10, "namespace": uuid.NewString(), "active": false, "id": stub.ID + 1000}
+	tc = netkit.NewTestCase("patch", http.MethodPatch, stubPath, patchParams, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+
+	patchedStub := getStub(t)
+	require.Equal(t, 10, patchedStub.Weight)
+	require.Equal(t, description, patchedStub.Description)
+	require.Equal(t, namespace, patchedStub.Namespace)
+	require.True(t, patchedStub.Active)
+
+	tc = netkit.NewTestCase("deactivate", http.MethodPost, stubPath+"/deactivate", types.Map{}, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+	require.False(t, getStub(t).Active)
+	require.Empty(t, getStubs(t))
+
+	tc = netkit.NewTestCase("activate", http.MethodPost, stubPath+"/activate", types.Map{}, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+	require.True(t, getStub(t).Active)
+	require.Len(t, getStubs(t), 1)
+
+	tc = netkit.NewTestCase("delete", http.MethodDelete, stubPath, types.Map{}, http.StatusOK, VerdictSuccess)
+	_ = netkit.ExecuteTestCase[types.Map](t, tc, app.kit)
+	require.Empty(t, getStubs(t))
+}
+
 func TestUploadFile(t *testing.T) {
 	t.Parallel()
 
@@ -269,6 +357,62 @@ func TestGetIncomingRequest(t *testing.T) {
 	}
 }
 
+func TestVerifyIncomingRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, err := NewApp(ctx, config.NewConfig())
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	requestID := uuid.NewString()
+	for _, id := range []string{requestID, uuid.NewString()} {
+		request := &rio.IncomingRequest{
+			Namespace: namespace,
+			URL:       "/echo/animal/create",
+			Method:    http.MethodPost,
+			Header:    types.Map{"X-Request-Id": []string{id}},
+		}
+
+		require.NoError(t, app.stubStore.CreateIncomingRequest(ctx, request))
+	}
+
+	matching := rio.NewStub().For(http.MethodPost, rio.Contains("animal/create")).WithHeader("X-Request-Id", rio.EqualTo(requestID)).Request
+	verifiedParams := types.Map{"namespace": namespace, "request": matching, "count": rio.Exactly(1)}
+	failedParams := types.Map{"namespace": namespace, "request": matching, "count": rio.AtLeast(2)}
+	invalidParams := types.Map{"namespace": namespace, "request": matching, "count": types.Map{"condition": "between"}}
+
+	testCases := []*netkit.TestCase{
+		netkit.NewTestCase("verified", http.MethodPost, "/incoming_request/verify", verifiedParams, http.StatusOK, VerdictSuccess),
+		netkit.NewTestCase("failed", http.MethodPost, "/incoming_request/verify", failedParams, http.StatusOK, VerdictSuccess),
+		netkit.NewTestCase("invalid_count", http.MethodPost, "/incoming_request/verify", invalidParams, http.StatusBadRequest, VerdictInvalidParameters),
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			res := netkit.ExecuteTestCase[struct {
+				Result *rio.VerificationResult `json:"result"`
+			}](t, tc, app.kit)
+			if tc.ExpectStatus != http.StatusOK {
+				return
+			}
+
+			result := res.Body.Data.Result
+			require.Equal(t, tc.Name == "verified", result.Verified)
+			require.Equal(t, 1, result.Actual)
+
+			if tc.Name == "failed" {
+				require.Len(t, result.NearMisses, 1)
+				require.Equal(t, "header", result.NearMisses[0].Mismatches[0].Field)
+			}
+		})
+	}
+}
+
 func TestEchoHandler(t *testing.T) {
 	t.Parallel()
 
@@ -302,6 +446,63 @@ func TestEchoHandler(t *testing.T) {
 	}
 }
 
+func TestEchoHandler_VirtualHost(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := uuid.NewString()
+	cfg := config.NewConfig()
+	cfg.HostBindings = "*.partner.com=" + namespace
+	cfg.TrustForwardedHeaders = true
+
+	app, err := NewApp(ctx, cfg)
+	require.NoError(t, err)
+
+	expectedData := types.Map{"data": uuid.NewString(), "verdict": VerdictSuccess}
+	stub := rio.NewStub().
+		WithNamespace(namespace).
+		For("GET").
+		WithHost(rio.EqualTo("api.partner.com")).
+		WithScheme(rio.SchemeHTTPS).
+		WithPath("/ping/{id:int}").
+		WillReturn(rio.NewResponse().WithBody(rio.MustToJSON(expectedData)))
+	require.NoError(t, app.stubStore.Create(ctx, stub))
+
+	send := func(host string, proto string, requestPath string) *http.Response {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestPath, nil)
+		require.NoError(t, err)
+		req.Host = host
+		req.Header.Set(rio.HeaderXForwardedProto, proto)
+		app.kit.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	// The path is matched without /echo/ prefix since the host is bound to namespace
+	res := send("api.partner.com", "https", "/ping/10")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	resBody, err := netkit.ParseResponse[netkit.InternalBody[string]](ctx, res)
+	require.NoError(t, err)
+	require.Equal(t, expectedData["data"], resBody.Body.Data)
+
+	require.Equal(t, http.StatusNotFound, send("api.partner.com", "http", "/ping/10").StatusCode)
+	require.Equal(t, http.StatusNotFound, send("www.partner.com", "https", "/ping/10").StatusCode)
+
+	// The routes of admin api take precedence over the bound host
+	require.Equal(t, http.StatusOK, send("api.partner.com", "https", "/ping").StatusCode)
+	require.Equal(t, http.StatusOK, send("api.partner.com", "https", "/stub/list").StatusCode)
+	require.Equal(t, http.StatusOK, send("localhost", "https", "/ping").StatusCode)
+
+	// The echo routes are handled in the bound namespace with the whole path
+	require.Equal(t, http.StatusNotFound, send("api.partner.com", "https", "/echo/ping/10").StatusCode)
+
+	// The forwarded scheme is ignored if forwarded headers are not trusted
+	cfg.TrustForwardedHeaders = false
+	app, err = NewApp(ctx, cfg)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, send("api.partner.com", "https", "/ping/10").StatusCode)
+}
+
 func TestEchoHandler_Reverse(t *testing.T) {
 	t.Parallel()
 
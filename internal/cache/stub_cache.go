@@ -83,6 +83,19 @@ func (s *stubCache) GetAll(ctx context.Context, namespace string) ([]*rio.Stub,
 	return stubs, nil
 }
 
+// Update updates stub and invalidates the local cache
+// Updated time is compared in seconds, so the cache in this instance must be cleared to avoid stale data
+func (s *stubCache) Update(ctx context.Context, stub *rio.Stub) error {
+	defer s.stubCache.Flush()
+	return s.StubStore.Update(ctx, stub)
+}
+
+// Delete deletes stub and invalidates the local cache
+func (s *stubCache) Delete(ctx context.Context, id int64) error {
+	defer s.stubCache.Flush()
+	return s.StubStore.Delete(ctx, id)
+}
+
 func (s *stubCache) GetProtos(ctx context.Context) ([]*rio.Proto, error) {
 	last, err := s.statusStore.GetLastUpdatedProto(ctx)
 	if err != nil {
@@ -137,6 +150,18 @@ func (s *stubAsideCache) GetAll(ctx context.Context, namespace string) ([]*rio.S
 	return stubs, nil
 }
 
+// Update updates stub and invalidates the local cache
+func (s *stubAsideCache) Update(ctx context.Context, stub *rio.Stub) error {
+	defer s.stubCache.Flush()
+	return s.StubStore.Update(ctx, stub)
+}
+
+// Delete deletes stub and invalidates the local cache
+func (s *stubAsideCache) Delete(ctx context.Context, id int64) error {
+	defer s.stubCache.Flush()
+	return s.StubStore.Delete(ctx, id)
+}
+
 func (s *stubAsideCache) GetProtos(ctx context.Context) ([]*rio.Proto, error) {
 	if item := s.protoCache.get(ctx, protoKey, nil); item != nil {
 		return item.items, nil
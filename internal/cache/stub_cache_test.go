@@ -83,3 +83,35 @@ func TestStubCache_GetProtos(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, gotProtos)
 }
+
+func TestStubCache_Update(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	cfg := config.NewConfig()
+	namespace := uuid.NewString()
+	last := &rio.LastUpdatedRecord{}
+	stub := rio.NewStub().For("POST", rio.Contains("animal/create")).WithNamespace(namespace)
+
+	statusStore := mock.NewMockStatusStore(ctrl)
+	statusStore.EXPECT().GetLastUpdatedStub(gomock.Any(), namespace).Return(last, nil).Times(3)
+
+	stubStore := mock.NewMockStubStore(ctrl)
+	stubStore.EXPECT().GetAll(gomock.Any(), namespace).Return([]*rio.Stub{stub}, nil).Times(2)
+	stubStore.EXPECT().Update(gomock.Any(), stub).Return(nil).Times(1)
+
+	cache := NewStubCache(stubStore, statusStore, cfg)
+	_, err := cache.GetAll(ctx, namespace)
+	require.NoError(t, err)
+
+	// Cache is invalidated, so stubs are reloaded from db
+	require.NoError(t, cache.Update(ctx, stub))
+	_, err = cache.GetAll(ctx, namespace)
+	require.NoError(t, err)
+
+	_, err = cache.GetAll(ctx, namespace)
+	require.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -18,6 +19,30 @@ const (
 	dbSchema                    = "rio_services"
 )
 
+const (
+	pgServer   = "0.0.0.0:5432"
+	pgDatabase = "rio_services"
+	pgOption   = "sslmode=disable"
+)
+
+const sqlitePath = "rio.db"
+
+const s3Endpoint = "s3.amazonaws.com"
+
+// Defines supported file storage types
+const (
+	StorageTypeLocal = "local"
+	StorageTypeS3    = "s3"
+)
+
+// Defines supported database types
+const (
+	DBTypeMySQL    = "mysql"
+	DBTypePostgres = "postgres"
+	DBTypeSQLite   = "sqlite"
+	DBTypeMemory   = "memory"
+)
+
 const (
 	serverHost = "0.0.0.0"
 	serverPort = "8896"
@@ -37,26 +62,76 @@ type MySQLConfig struct {
 	EnableTracing             bool   `json:"enable_tracing" yaml:"enable_tracing"`
 }
 
+// PostgresConfig contains config data to connect to Postgres database
+type PostgresConfig struct {
+	Server                    string `json:"server" yaml:"server"`
+	Database                  string `json:"database" yaml:"database"`
+	User                      string `json:"user" yaml:"user"`
+	Password                  string `json:"password" yaml:"password"`
+	Option                    string `json:"option" yaml:"option"`
+	ConnectionLifetimeSeconds int    `json:"connection_lifetime_seconds" yaml:"connection_lifetime_seconds"`
+	MaxIdleConnections        int    `json:"max_idle_connections" yaml:"max_idle_connections"`
+	MaxOpenConnections        int    `json:"max_open_connections" yaml:"max_open_connections"`
+	EnableTracing             bool   `json:"enable_tracing" yaml:"enable_tracing"`
+}
+
+// SQLiteConfig contains config data of the embedded SQLite database
+type SQLiteConfig struct {
+	// Path is the path of database file, it is created if not existed
+	Path          string `json:"path" yaml:"path"`
+	EnableTracing bool   `json:"enable_tracing" yaml:"enable_tracing"`
+}
+
 // Config defines config for application
 type Config struct {
 	ServerAddress      string
 	FileStorageType    string
 	FileStorage        interface{}
+	DBType             string
 	DB                 *MySQLConfig
+	Postgres           *PostgresConfig
+	SQLite             *SQLiteConfig
 	StubCacheTTL       time.Duration
 	StubCacheStrategy  string
 	BodyStoreThreshold int
+
+	// HostBindings is a comma separated list of host=namespace. For example: partner-a.com=partner_a,*.partner-b.com=partner_b
+	// Requests to a bound host are handled in the namespace without /echo/ prefix
+	HostBindings string
+
+	// TrustForwardedHeaders enables X-Forwarded-Host and X-Forwarded-Proto to resolve the origin of request
+	// This must be enabled only if the server is behind a proxy which overrides these headers
+	TrustForwardedHeaders bool
+
+	// CallbackWorkers is the maximum number of callbacks which are sent concurrently
+	CallbackWorkers int
+
+	// CallbackAllowedHosts is a comma separated list of hosts which callbacks can be sent to. For example: localhost,*.partner.com
+	// Callbacks are denied by default since stubs can be created by anyone who can access the server. Use * to allow any host
+	CallbackAllowedHosts string
+
+	// GrpcServerAddress is used to start the grpc server in the same process with the http server
+	// The grpc server is not started if it is empty
+	GrpcServerAddress string
 }
 
 func NewConfig() *Config {
 	return &Config{
-		ServerAddress:      serverHost + ":" + EVString("SERVER_PORT", serverPort),
-		DB:                 NewDBConfig(),
-		FileStorageType:    getStorageType(),
-		FileStorage:        getFileStorageConfig(),
-		StubCacheTTL:       EVDuration("STUB_CACHE_TTL", time.Hour),
-		StubCacheStrategy:  EVString("STUB_CACHE_STRATEGY", "default"),
-		BodyStoreThreshold: EVInt("BODY_STORE_THRESHOLD", 1<<20),
+		ServerAddress:         serverHost + ":" + EVString("SERVER_PORT", serverPort),
+		GrpcServerAddress:     getGrpcServerAddress(),
+		DBType:                EVString("DB_TYPE", DBTypeMySQL),
+		DB:                    NewDBConfig(),
+		Postgres:              NewPostgresConfig(),
+		SQLite:                NewSQLiteConfig(),
+		FileStorageType:       getStorageType(),
+		FileStorage:           getFileStorageConfig(getStorageType(), EVString("DB_TYPE", DBTypeMySQL)),
+		StubCacheTTL:          EVDuration("STUB_CACHE_TTL", time.Hour),
+		StubCacheStrategy:     EVString("STUB_CACHE_STRATEGY", "default"),
+		BodyStoreThreshold:    EVInt("BODY_STORE_THRESHOLD", 1<<20),
+		HostBindings:          EVString("HOST_BINDINGS", ""),
+		TrustForwardedHeaders: EVBool("TRUST_FORWARDED_HEADERS", false),
+		CallbackWorkers:       EVInt("CALLBACK_WORKERS", 100),
+		CallbackAllowedHosts:  EVString("CALLBACK_ALLOWED_HOSTS", ""),
 	}
 }
 
@@ -73,7 +148,55 @@ func NewDBConfig() *MySQLConfig {
 	}
 }
 
-func getFileStorageConfig() interface{} {
+// NewPostgresConfig loads postgres config
+func NewPostgresConfig() *PostgresConfig {
+	return &PostgresConfig{
+		Server:                    EVString("PG_SERVER", pgServer),
+		Database:                  EVString("PG_DATABASE", pgDatabase),
+		User:                      EVString("PG_USER", dbUser),
+		Password:                  EVString("PG_PASSWORD", dbPassword),
+		Option:                    EVString("PG_OPTION", pgOption),
+		ConnectionLifetimeSeconds: EVInt("DB_CONNECTION_LIFETIME_SECONDS", dbConnectionLifetimeSeconds),
+		MaxIdleConnections:        EVInt("DB_MAX_IDLE_CONNECTIONS", dbMaxIdleConnection),
+		MaxOpenConnections:        EVInt("DB_MAX_OPEN_CONNECTIONS", dbMaxOpenConnection),
+	}
+}
+
+// NewSQLiteConfig loads SQLite config
+func NewSQLiteConfig() *SQLiteConfig {
+	return &SQLiteConfig{
+		Path: EVString("SQLITE_PATH", sqlitePath),
+	}
+}
+
+func getGrpcServerAddress() string {
+	port := EVString("GRPC_SERVER_PORT", "")
+	if len(port) == 0 {
+		return ""
+	}
+
+	return serverHost + ":" + port
+}
+
+func getFileStorageConfig(storageType string, dbType string) interface{} {
+	if storageType == StorageTypeS3 {
+		return fs.S3StorageConfig{
+			Endpoint:        EVString("S3_ENDPOINT", s3Endpoint),
+			Region:          EVString("S3_REGION", ""),
+			Bucket:          EVString("S3_BUCKET", ""),
+			AccessKeyID:     EVString("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: EVString("S3_SECRET_ACCESS_KEY", ""),
+			Prefix:          EVString("S3_PREFIX", ""),
+			UseSSL:          EVBool("S3_USE_SSL", true),
+		}
+	}
+
+	// The standalone server keeps the uploaded files across restarts, default is next to the SQLite file
+	if dbType == DBTypeSQLite || dbType == DBTypeMemory {
+		defaultDir := filepath.Join(filepath.Dir(EVString("SQLITE_PATH", sqlitePath)), "uploaded_files")
+		return fs.LocalStorageConfig{StoragePath: EVString("FILE_DIR", defaultDir)}
+	}
+
 	return fs.LocalStorageConfig{
 		UseTempDir:  true,
 		StoragePath: EVString("FILE_DIR", "uploaded_files"),
@@ -81,7 +204,7 @@ func getFileStorageConfig() interface{} {
 }
 
 func getStorageType() string {
-	return EVString("FILE_STORAGE_TYPE", "local")
+	return EVString("FILE_STORAGE_TYPE", StorageTypeLocal)
 }
 
 func EVString(name string, fallback string) string {
@@ -120,3 +243,17 @@ func EVDuration(name string, fallback time.Duration) time.Duration {
 
 	return d
 }
+
+func EVBool(name string, fallback bool) bool {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/log"
 	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -17,6 +20,7 @@ import (
 const (
 	mysqlOption                           = "charset=utf8&parseTime=True&loc=Local&multiStatements=True&maxAllowedPacket=0"
 	defaultMySQLConnectionLifetimeSeconds = 300
+	sqliteOption                          = "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
 )
 
 // Connect setups connections to MySQL database
@@ -42,28 +46,109 @@ func Connect(ctx context.Context, config *config.MySQLConfig) (*gorm.DB, error)
 		return nil, err
 	}
 
+	pool := poolConfig{
+		name:                      config.Schema,
+		connectionLifetimeSeconds: connectionLifetimeSeconds,
+		maxIdleConnections:        config.MaxIdleConnections,
+		maxOpenConnections:        config.MaxOpenConnections,
+		enableTracing:             config.EnableTracing,
+	}
+
+	return setupConnection(ctx, db, pool)
+}
+
+// ConnectPostgres setups connections to Postgres database
+func ConnectPostgres(ctx context.Context, config *config.PostgresConfig) (*gorm.DB, error) {
+	connectionLifetimeSeconds := config.ConnectionLifetimeSeconds
+	if connectionLifetimeSeconds == 0 {
+		connectionLifetimeSeconds = defaultMySQLConnectionLifetimeSeconds
+	}
+
+	cfg := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	}
+
+	db, err := gorm.Open(postgres.Open(resolvePostgresURL(config)), cfg)
+	if err != nil {
+		log.Error(ctx, "cannot connect to database", config.Database)
+		return nil, err
+	}
+
+	pool := poolConfig{
+		name:                      config.Database,
+		connectionLifetimeSeconds: connectionLifetimeSeconds,
+		maxIdleConnections:        config.MaxIdleConnections,
+		maxOpenConnections:        config.MaxOpenConnections,
+		enableTracing:             config.EnableTracing,
+	}
+
+	return setupConnection(ctx, db, pool)
+}
+
+// ConnectSQLite opens the embedded SQLite database, the database file is created if not existed
+// SQLite allows a single writer, so all queries are serialized through a single connection
+func ConnectSQLite(ctx context.Context, config *config.SQLiteConfig) (*gorm.DB, error) {
+	if dir := filepath.Dir(config.Path); len(dir) > 0 {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			log.Error(ctx, "cannot create database directory", err)
+			return nil, err
+		}
+	}
+
+	cfg := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	}
+
+	db, err := gorm.Open(sqlite.Open(config.Path+sqliteOption), cfg)
+	if err != nil {
+		log.Error(ctx, "cannot connect to database", config.Path)
+		return nil, err
+	}
+
+	pool := poolConfig{
+		name:               config.Path,
+		maxIdleConnections: 1,
+		maxOpenConnections: 1,
+		enableTracing:      config.EnableTracing,
+	}
+
+	return setupConnection(ctx, db, pool)
+}
+
+type poolConfig struct {
+	name                      string
+	connectionLifetimeSeconds int
+	maxIdleConnections        int
+	maxOpenConnections        int
+	enableTracing             bool
+}
+
+func setupConnection(ctx context.Context, db *gorm.DB, pool poolConfig) (*gorm.DB, error) {
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Error(ctx, "cannot obtain sql database object", config.Schema)
+		log.Error(ctx, "cannot obtain sql database object", pool.name)
 		return nil, err
 	}
 
-	sqlDB.SetConnMaxLifetime(time.Duration(connectionLifetimeSeconds) * time.Second)
-	sqlDB.SetMaxIdleConns(config.MaxIdleConnections)
-	sqlDB.SetMaxOpenConns(config.MaxOpenConnections)
+	if pool.connectionLifetimeSeconds > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(pool.connectionLifetimeSeconds) * time.Second)
+	}
+
+	sqlDB.SetMaxIdleConns(pool.maxIdleConnections)
+	sqlDB.SetMaxOpenConns(pool.maxOpenConnections)
 
-	if config.EnableTracing {
-		if err = db.WithContext(ctx).Use(otelgorm.NewPlugin(otelgorm.WithDBName(config.Schema))); err != nil {
-			log.Error(ctx, "cannot enable tracing for gorm", config.Schema)
+	if pool.enableTracing {
+		if err = db.WithContext(ctx).Use(otelgorm.NewPlugin(otelgorm.WithDBName(pool.name))); err != nil {
+			log.Error(ctx, "cannot enable tracing for gorm", pool.name)
 			return nil, err
 		}
 	}
 
-	log.Info(ctx, "connected to database", config.Schema)
+	log.Info(ctx, "connected to database", pool.name)
 	return db, nil
 }
 
-// Disconnect closes the connections to the MySQL database
+// Disconnect closes the connections to the database
 func Disconnect(ctx context.Context, db *gorm.DB) error {
 	sqlDB, err := db.WithContext(ctx).DB()
 	if err != nil {
@@ -79,7 +164,7 @@ func Disconnect(ctx context.Context, db *gorm.DB) error {
 	return nil
 }
 
-// ExecuteFileScript runs a specific migration file on a MySQL database using specific path
+// ExecuteFileScript runs a specific migration file on a database using specific path
 func ExecuteFileScript(ctx context.Context, db *gorm.DB, filePath string) error {
 	migrationSQL, err := os.ReadFile(filePath)
 	if err != nil {
@@ -12,21 +12,12 @@ import (
 
 func TestMain(m *testing.M) {
 	ctx := context.Background()
-	dbConfig := config.NewDBConfig()
-	gormDB, err := Connect(ctx, dbConfig)
-	if err != nil {
-		panic(err)
-	}
-
-	if err := ExecuteFileScript(ctx, gormDB, "../../schema/reset_db.sql"); err != nil {
-		panic(err)
-	}
-
-	if err := Migrate(ctx, dbConfig, "../../schema/migration"); err != nil {
+	cfg := config.NewConfig()
+	if err := resetDB(ctx, cfg); err != nil {
 		panic(err)
 	}
 
-	store, err := NewStubDBStore(ctx, dbConfig)
+	store, err := newTestStore(ctx)
 	if err != nil {
 		panic(err)
 	}
@@ -43,3 +34,52 @@ func TestMain(m *testing.M) {
 	code := m.Run()
 	os.Exit(code)
 }
+
+// newTestStore creates store for the database type in config (DB_TYPE)
+func newTestStore(ctx context.Context) (*StubDBStore, error) {
+	cfg := config.NewConfig()
+	switch cfg.DBType {
+	case config.DBTypePostgres:
+		return NewStubPostgresStore(ctx, cfg.Postgres)
+	case config.DBTypeSQLite:
+		return NewStubSQLiteStore(ctx, cfg.SQLite)
+	}
+
+	return NewStubDBStore(ctx, cfg.DB)
+}
+
+func resetDB(ctx context.Context, cfg *config.Config) error {
+	if cfg.DBType == config.DBTypeSQLite {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if err := os.Remove(cfg.SQLite.Path + suffix); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		return MigrateSQLite(ctx, cfg.SQLite, "../../schema/sqlite/migration")
+	}
+
+	if cfg.DBType == config.DBTypePostgres {
+		gormDB, err := ConnectPostgres(ctx, cfg.Postgres)
+		if err != nil {
+			return err
+		}
+
+		if err := ExecuteFileScript(ctx, gormDB, "../../schema/postgres/reset_db.sql"); err != nil {
+			return err
+		}
+
+		return MigratePostgres(ctx, cfg.Postgres, "../../schema/postgres/migration")
+	}
+
+	gormDB, err := Connect(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+
+	if err := ExecuteFileScript(ctx, gormDB, "../../schema/reset_db.sql"); err != nil {
+		return err
+	}
+
+	return Migrate(ctx, cfg.DB, "../../schema/migration")
+}
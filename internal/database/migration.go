@@ -4,20 +4,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/log"
+	"gorm.io/gorm"
 
 	// blank import
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
+
+// Migrate runs MySQL migrations in the given directory
 func Migrate(ctx context.Context, config *config.MySQLConfig, dir string) error {
 	connectionString := fmt.Sprintf("mysql://%s", resolveDatabaseConnectionURL(config))
+	return migrateUp(ctx, connectionString, dir)
+}
+
+// MigratePostgres runs Postgres migrations in the given directory
+func MigratePostgres(ctx context.Context, config *config.PostgresConfig, dir string) error {
+	return migrateUp(ctx, resolvePostgresURL(config), dir)
+}
+
+func migrateUp(ctx context.Context, connectionString string, dir string) error {
 	m, err := migrate.New(fmt.Sprintf("file://%s", dir), connectionString)
 	if err != nil {
 		return err
@@ -51,3 +71,111 @@ func resolveDatabaseConnectionURL(config *config.MySQLConfig) string {
 	}
 	return format.FormatDSN()
 }
+
+func resolvePostgresURL(config *config.PostgresConfig) string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.User, config.Password),
+		Host:     config.Server,
+		Path:     config.Database,
+		RawQuery: config.Option,
+	}
+
+	return u.String()
+}
+
+// schemaMigration records the applied SQLite migrations
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time
+}
+
+// MigrateSQLite runs SQLite migrations in the given directory
+// golang-migrate is not used since its SQLite driver conflicts with the pure Go driver which is used by gorm
+func MigrateSQLite(ctx context.Context, config *config.SQLiteConfig, dir string) error {
+	db, err := ConnectSQLite(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = Disconnect(ctx, db) }()
+
+	if err := db.WithContext(ctx).AutoMigrate(&schemaMigration{}); err != nil {
+		log.Error(ctx, "cannot create migration table", err)
+		return err
+	}
+
+	files, err := readMigrationFiles(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	var current int64
+	if err := db.WithContext(ctx).Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&current).Error; err != nil {
+		log.Error(ctx, "cannot get migration version", err)
+		return err
+	}
+
+	applied := 0
+	for _, file := range files {
+		if file.version <= current {
+			continue
+		}
+
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := ExecuteFileScript(ctx, tx, file.path); err != nil {
+				return err
+			}
+
+			return tx.Create(&schemaMigration{Version: file.version}).Error
+		})
+		if err != nil {
+			log.Error(ctx, "cannot apply migration", file.path, err)
+			return err
+		}
+
+		applied++
+	}
+
+	if applied == 0 {
+		log.Info(ctx, "no migration needed")
+	}
+
+	return nil
+}
+
+type migrationFile struct {
+	version int64
+	path    string
+}
+
+// readMigrationFiles returns up migration files which are sorted by version
+func readMigrationFiles(ctx context.Context, dir string) ([]*migrationFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Error(ctx, "cannot read migration directory", err)
+		return nil, err
+	}
+
+	files := make([]*migrationFile, 0, len(entries))
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || len(matches) == 0 {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			log.Error(ctx, "invalid migration version", entry.Name())
+			return nil, err
+		}
+
+		files = append(files, &migrationFile{version: version, path: filepath.Join(dir, entry.Name())})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].version < files[j].version
+	})
+
+	return files, nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hungdv136/rio"
+	"github.com/hungdv136/rio/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSQLite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := &config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "data", "rio.db")}
+
+	require.NoError(t, MigrateSQLite(ctx, cfg, "../../schema/sqlite/migration"))
+
+	// Migrations are applied only once
+	require.NoError(t, MigrateSQLite(ctx, cfg, "../../schema/sqlite/migration"))
+
+	store, err := NewStubSQLiteStore(ctx, cfg)
+	require.NoError(t, err)
+
+	stub := rio.NewStub().For("GET", rio.Contains("animal/get"))
+	require.NoError(t, store.Create(ctx, stub))
+
+	// Data is kept after reopening the database file
+	require.NoError(t, Disconnect(ctx, store.db))
+	store, err = NewStubSQLiteStore(ctx, cfg)
+	require.NoError(t, err)
+
+	stubs, err := store.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+	require.Equal(t, stub.ID, stubs[0].ID)
+
+	last, err := store.GetLastUpdatedStub(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, stub.ID, last.ID)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // The stub schema version
@@ -34,6 +35,28 @@ func NewStubDBStore(ctx context.Context, config *config.MySQLConfig) (*StubDBSto
 	return &StubDBStore{db: db}, nil
 }
 
+// NewStubSQLiteStore init a new instance for storage using the embedded SQLite database
+func NewStubSQLiteStore(ctx context.Context, config *config.SQLiteConfig) (*StubDBStore, error) {
+	db, err := ConnectSQLite(ctx, config)
+	if err != nil {
+		log.Error(ctx, "cannot connect db", err)
+		return nil, err
+	}
+
+	return &StubDBStore{db: db}, nil
+}
+
+// NewStubPostgresStore init a new instance for storage using Postgres
+func NewStubPostgresStore(ctx context.Context, config *config.PostgresConfig) (*StubDBStore, error) {
+	db, err := ConnectPostgres(ctx, config)
+	if err != nil {
+		log.Error(ctx, "cannot connect db", err)
+		return nil, err
+	}
+
+	return &StubDBStore{db: db}, nil
+}
+
 // Create creates a new stub
 func (s *StubDBStore) Create(ctx context.Context, stubs ...*rio.Stub) error {
 	for _, stub := range stubs {
@@ -100,6 +123,32 @@ func (s *StubDBStore) Find(ctx context.Context, id int64) (*rio.Stub, error) {
 	return &stub, nil
 }
 
+// Update updates all fields of stub by id, returns ErrStubNotFound if it does not exist
+// The existence is checked separately because MySQL reports the number of changed rows, which is zero if nothing is changed
+func (s *StubDBStore) Update(ctx context.Context, stub *rio.Stub) error {
+	stub.Settings.StoreVersion = LatestVersion
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&rio.Stub{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", stub.ID).Count(&count).Error; err != nil {
+			log.Error(ctx, "cannot find stub", stub.ID, err)
+			return err
+		}
+
+		if count == 0 {
+			log.Error(ctx, rio.ErrStubNotFound, stub.ID)
+			return rio.ErrStubNotFound
+		}
+
+		if err := tx.Model(stub).Select("*").Omit("id", "created_at").Updates(stub).Error; err != nil {
+			log.Error(ctx, "cannot update stub", stub.ID, err)
+			return err
+		}
+
+		return nil
+	})
+}
+
 // CreateIncomingRequest saves the income request
 func (s *StubDBStore) CreateIncomingRequest(ctx context.Context, r *rio.IncomingRequest) error {
 	if err := s.db.WithContext(ctx).Create(r).Error; err != nil {
@@ -118,7 +167,16 @@ func (s *StubDBStore) GetIncomingRequests(ctx context.Context, option *rio.Incom
 		db = db.Where("id IN (?)", option.Ids)
 	}
 
-	if err := db.Order("id DESC").Limit(option.Limit).Find(&r).Error; err != nil {
+	if option.Unmatched {
+		db = db.Where("stub_id = 0")
+	}
+
+	// Zero limit means no limit
+	if option.Limit > 0 {
+		db = db.Limit(option.Limit)
+	}
+
+	if err := db.Order("id DESC").Find(&r).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -147,21 +205,25 @@ func (s *StubDBStore) GetProtos(ctx context.Context) ([]*rio.Proto, error) {
 
 // Reset clear data
 func (s *StubDBStore) Reset(ctx context.Context, option *rio.ResetQueryOption) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		resetQuery := s.db.WithContext(ctx)
-		resetRequest := s.db.WithContext(ctx)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		resetQuery := tx
+		resetRequest := tx
+		resetCallback := tx
 
 		if option.Namespace == rio.ResetAll {
 			resetQuery = resetQuery.Where("1 = 1")
 			resetRequest = resetRequest.Where("1 = 1")
+			resetCallback = resetCallback.Where("1 = 1")
 		} else {
 			resetQuery = resetQuery.Where("namespace = ?", option.Namespace)
 			resetRequest = resetRequest.Where("namespace = ?", option.Namespace)
+			resetCallback = resetCallback.Where("namespace = ?", option.Namespace)
 		}
 
 		if len(option.Tag) > 0 {
 			resetQuery = resetQuery.Where("tag = ?", option.Tag)
 			resetRequest = resetRequest.Where("tag = ?", option.Tag)
+			resetCallback = resetCallback.Where("tag = ?", option.Tag)
 		}
 
 		if err := resetQuery.Delete(&rio.Stub{}).Error; err != nil {
@@ -174,10 +236,178 @@ func (s *StubDBStore) Reset(ctx context.Context, option *rio.ResetQueryOption) e
 			return err
 		}
 
+		if err := resetCallback.Delete(&rio.CallbackResult{}).Error; err != nil {
+			log.Error(ctx, "cannot delete callback results", err)
+			return err
+		}
+
+		// Scenarios and counters are not tagged, so they are only reset with the whole namespace
+		if len(option.Tag) == 0 {
+			resetScenario := tx
+			resetCounter := tx
+			if option.Namespace == rio.ResetAll {
+				resetScenario = resetScenario.Where("1 = 1")
+				resetCounter = resetCounter.Where("1 = 1")
+			} else {
+				resetScenario = resetScenario.Where("namespace = ?", option.Namespace)
+				resetCounter = resetCounter.Where("namespace = ?", option.Namespace)
+			}
+
+			if err := resetScenario.Delete(&rio.Scenario{}).Error; err != nil {
+				log.Error(ctx, "cannot delete scenarios", err)
+				return err
+			}
+
+			if err := resetCounter.Delete(&rio.StubCounter{}).Error; err != nil {
+				log.Error(ctx, "cannot delete stub counters", err)
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
+// GetScenarios gets started scenarios. Scenarios which have not been started are not returned
+func (s *StubDBStore) GetScenarios(ctx context.Context, option *rio.ScenarioQueryOption) ([]*rio.Scenario, error) {
+	scenarios := []*rio.Scenario{}
+	db := s.db.WithContext(ctx).Where("namespace = ?", option.Namespace)
+	if len(option.Name) > 0 {
+		db = db.Where("name = ?", option.Name)
+	}
+
+	if err := db.Order("id ASC").Find(&scenarios).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		log.Error(ctx, "cannot get scenarios", err)
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+// SetScenarioState creates or updates state of a scenario
+func (s *StubDBStore) SetScenarioState(ctx context.Context, scenario *rio.Scenario) error {
+	db := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "namespace"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"state"}),
+	})
+
+	if err := db.Create(scenario).Error; err != nil {
+		log.Error(ctx, "cannot set scenario state", err)
+		return err
+	}
+
+	return nil
+}
+
+// TransitScenarioState moves scenario to the new state if its state is the current state
+// This is a compare and swap operation, returns false if the state has been changed by another request
+func (s *StubDBStore) TransitScenarioState(ctx context.Context, namespace string, name string, currentState string, newState string) (bool, error) {
+	db := s.db.WithContext(ctx).
+		Model(rio.Scenario{}).
+		Where("namespace = ? AND name = ? AND state = ?", namespace, name, currentState).
+		Update("state", newState)
+	if err := db.Error; err != nil {
+		log.Error(ctx, "cannot update scenario state", err)
+		return false, err
+	}
+
+	if db.RowsAffected > 0 || currentState != rio.ScenarioStateStarted {
+		return db.RowsAffected > 0, nil
+	}
+
+	// Scenario in the initial state is not saved, insert it unless another request has done
+	scenario := &rio.Scenario{Namespace: namespace, Name: name, State: newState}
+	db = s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(scenario)
+	if err := db.Error; err != nil {
+		log.Error(ctx, "cannot create scenario", err)
+		return false, err
+	}
+
+	return db.RowsAffected > 0, nil
+}
+
+// ResetScenarios resets scenarios to the initial state
+func (s *StubDBStore) ResetScenarios(ctx context.Context, option *rio.ScenarioQueryOption) error {
+	db := s.db.WithContext(ctx).Where("namespace = ?", option.Namespace)
+	if len(option.Name) > 0 {
+		db = db.Where("name = ?", option.Name)
+	}
+
+	if err := db.Delete(&rio.Scenario{}).Error; err != nil {
+		log.Error(ctx, "cannot reset scenarios", err)
+		return err
+	}
+
+	return nil
+}
+
+// IncreaseStubCounter increases the counter of stub in namespace by one and returns the new value
+// The upsert locks the counter row until the transaction is committed, so concurrent requests get different values
+func (s *StubDBStore) IncreaseStubCounter(ctx context.Context, namespace string, stubID int64) (int64, error) {
+	var counter int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record := &rio.StubCounter{Namespace: namespace, StubID: stubID, Counter: 1}
+		db := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "namespace"}, {Name: "stub_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"counter": gorm.Expr("stub_counters.counter + 1")}),
+		})
+
+		if err := db.Create(record).Error; err != nil {
+			log.Error(ctx, "cannot increase stub counter", err)
+			return err
+		}
+
+		db = tx.Model(rio.StubCounter{}).Where("namespace = ? AND stub_id = ?", namespace, stubID).Select("counter")
+		if err := db.Scan(&counter).Error; err != nil {
+			log.Error(ctx, "cannot get stub counter", err)
+			return err
+		}
+
+		return nil
+	})
+
+	return counter, err
+}
+
+// CreateCallbackResult saves the result of a callback
+func (s *StubDBStore) CreateCallbackResult(ctx context.Context, r *rio.CallbackResult) error {
+	if err := s.db.WithContext(ctx).Create(r).Error; err != nil {
+		log.Error(ctx, "cannot create callback result", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetCallbackResults returns the latest callback results
+func (s *StubDBStore) GetCallbackResults(ctx context.Context, option *rio.CallbackQueryOption) ([]*rio.CallbackResult, error) {
+	r := []*rio.CallbackResult{}
+	db := s.db.WithContext(ctx).Where("namespace = ?", option.Namespace)
+	if option.StubID > 0 {
+		db = db.Where("stub_id = ?", option.StubID)
+	}
+
+	if option.IncomingRequestID > 0 {
+		db = db.Where("incoming_request_id = ?", option.IncomingRequestID)
+	}
+
+	// Zero limit means no limit
+	if option.Limit > 0 {
+		db = db.Limit(option.Limit)
+	}
+
+	if err := db.Order("id DESC").Find(&r).Error; err != nil {
+		log.Error(ctx, "cannot get callback results", err)
+		return nil, err
+	}
+
+	return r, nil
+}
+
 func (s *StubDBStore) GetLastUpdatedStub(ctx context.Context, namespace string) (*rio.LastUpdatedRecord, error) {
 	var r rio.LastUpdatedRecord
 	db := s.db.WithContext(ctx).
@@ -3,13 +3,13 @@ package database
 import (
 	"bytes"
 	"context"
+	"math"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hungdv136/rio"
-	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/types"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +18,7 @@ func TestStubDbStore_Create(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-	store, err := NewStubDBStore(ctx, config.NewDBConfig())
+	store, err := newTestStore(ctx)
 	require.NoError(t, err)
 
 	data := types.Map{"id": uuid.NewString()}
@@ -52,7 +52,7 @@ func TestStubDbStore_Delete(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-	store, err := NewStubDBStore(ctx, config.NewDBConfig())
+	store, err := newTestStore(ctx)
 	require.NoError(t, err)
 
 	stub := rio.NewStub().For("POST", rio.Contains("animal/create"))
@@ -66,11 +66,46 @@ func TestStubDbStore_Delete(t *testing.T) {
 	require.False(t, foundStub.Active)
 }
 
+func TestStubDbStore_Update(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx)
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	stub := rio.NewStub().For("POST", rio.Contains("animal/create")).WithNamespace(namespace)
+	require.NoError(t, store.Create(ctx, stub))
+
+	foundStub, err := store.Find(ctx, stub.ID)
+	require.NoError(t, err)
+
+	foundStub.Description = uuid.NewString()
+	foundStub.Request = rio.NewStub().For("PUT", rio.Contains("animal/update")).Request
+	foundStub.Active = false
+	require.NoError(t, store.Update(ctx, foundStub))
+	require.ErrorIs(t, store.Update(ctx, rio.NewStub().WithID(math.MaxInt64)), rio.ErrStubNotFound)
+
+	// Updating with the same data does not change any row, but the stub still exists
+	require.NoError(t, store.Update(ctx, foundStub))
+
+	updatedStub, err := store.Find(ctx, stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, foundStub.Description, updatedStub.Description)
+	require.Equal(t, "PUT", updatedStub.Request.Method)
+	require.False(t, updatedStub.Active)
+	require.WithinDuration(t, stub.CreatedAt, updatedStub.CreatedAt, time.Second)
+
+	stubs, err := store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Empty(t, stubs)
+}
+
 func TestStubDbStore_GetAll(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-	store, err := NewStubDBStore(ctx, config.NewDBConfig())
+	store, err := newTestStore(ctx)
 	require.NoError(t, err)
 
 	t.Run("with_namespace", func(t *testing.T) {
@@ -109,7 +144,7 @@ func TestStubDbStore_CreateIncomeRequest(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-	store, err := NewStubDBStore(ctx, config.NewDBConfig())
+	store, err := newTestStore(ctx)
 	require.NoError(t, err)
 
 	body, err := os.ReadFile("stub_store_test.go")
@@ -122,9 +157,12 @@ func TestStubDbStore_CreateIncomeRequest(t *testing.T) {
 		Header: types.Map{
 			"key": uuid.NewString(),
 		},
-		CURL:   uuid.NewString(),
-		Body:   body,
-		StubID: 1,
+		CURL:       uuid.NewString(),
+		Body:       body,
+		StubID:     1,
+		PathParams: map[string]string{"id": uuid.NewString()},
+		Host:       "partner.com:8443",
+		Scheme:     "https",
 	}
 
 	err = store.CreateIncomingRequest(ctx, request)
@@ -144,13 +182,16 @@ func TestStubDbStore_CreateIncomeRequest(t *testing.T) {
 	require.Equal(t, request.CURL, foundRequests[0].CURL)
 	require.Equal(t, request.StubID, foundRequests[0].StubID)
 	require.Equal(t, body, foundRequests[0].Body)
+	require.Equal(t, request.PathParams, foundRequests[0].PathParams)
+	require.Equal(t, request.Host, foundRequests[0].Host)
+	require.Equal(t, request.Scheme, foundRequests[0].Scheme)
 }
 
 func TestStubDbStore_GetProtos(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-	store, err := NewStubDBStore(ctx, config.NewDBConfig())
+	store, err := newTestStore(ctx)
 	require.NoError(t, err)
 
 	proto := &rio.Proto{
@@ -182,7 +223,7 @@ func TestStubDbStore_GetLastUpdatedStub(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-	store, err := NewStubDBStore(ctx, config.NewDBConfig())
+	store, err := newTestStore(ctx)
 	require.NoError(t, err)
 
 	t.Run("with_namespace", func(t *testing.T) {
@@ -211,3 +252,157 @@ func TestStubDbStore_GetLastUpdatedStub(t *testing.T) {
 		require.GreaterOrEqual(t, last.ID, inactiveStub.ID)
 	})
 }
+
+func TestStubDbStore_Scenario(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx)
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	name := uuid.NewString()
+	option := &rio.ScenarioQueryOption{Namespace: namespace, Name: name}
+
+	transited, err := store.TransitScenarioState(ctx, namespace, name, "pending", "done")
+	require.NoError(t, err)
+	require.False(t, transited)
+
+	transited, err = store.TransitScenarioState(ctx, namespace, name, rio.ScenarioStateStarted, "pending")
+	require.NoError(t, err)
+	require.True(t, transited)
+
+	transited, err = store.TransitScenarioState(ctx, namespace, name, rio.ScenarioStateStarted, "done")
+	require.NoError(t, err)
+	require.False(t, transited)
+
+	scenarios, err := store.GetScenarios(ctx, option)
+	require.NoError(t, err)
+	require.Len(t, scenarios, 1)
+	require.Equal(t, "pending", scenarios[0].State)
+
+	require.NoError(t, store.SetScenarioState(ctx, &rio.Scenario{Namespace: namespace, Name: name, State: "failed"}))
+	scenarios, err = store.GetScenarios(ctx, option)
+	require.NoError(t, err)
+	require.Len(t, scenarios, 1)
+	require.Equal(t, "failed", scenarios[0].State)
+
+	require.NoError(t, store.ResetScenarios(ctx, option))
+	scenarios, err = store.GetScenarios(ctx, option)
+	require.NoError(t, err)
+	require.Empty(t, scenarios)
+}
+
+func TestStubDbStore_IncreaseStubCounter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx)
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	stub := rio.NewStub().
+		WithNamespace(namespace).
+		For("GET", rio.Contains("order/status")).
+		WillReturnSequence(rio.RandomResponses(rio.NewResponse(), rio.NewResponse().WithStatusCode(500)).WithWeights(9, 1))
+	require.NoError(t, store.Create(ctx, stub))
+
+	foundStub, err := store.Find(ctx, stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, rio.SequencePolicyRandom, foundStub.Sequence.Policy)
+	require.Equal(t, []int{9, 1}, foundStub.Sequence.Weights)
+	require.Len(t, foundStub.Sequence.Responses, 2)
+	require.Equal(t, 500, foundStub.Sequence.Responses[1].StatusCode)
+
+	// Counters are increased atomically by concurrent requests
+	const nbRequests = 20
+	counters := make(chan int64, nbRequests)
+	for i := 0; i < nbRequests; i++ {
+		go func() {
+			counter, err := store.IncreaseStubCounter(ctx, namespace, stub.ID)
+			require.NoError(t, err)
+			counters <- counter
+		}()
+	}
+
+	seen := map[int64]bool{}
+	for i := 0; i < nbRequests; i++ {
+		seen[<-counters] = true
+	}
+
+	require.Len(t, seen, nbRequests)
+	for i := int64(1); i <= nbRequests; i++ {
+		require.True(t, seen[i], i)
+	}
+
+	// The counter is tracked per namespace
+	counter, err := store.IncreaseStubCounter(ctx, uuid.NewString(), stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter)
+
+	require.NoError(t, store.Reset(ctx, &rio.ResetQueryOption{Namespace: namespace}))
+	counter, err = store.IncreaseStubCounter(ctx, namespace, stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter)
+}
+
+func TestStubDbStore_CallbackResults(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx)
+	require.NoError(t, err)
+
+	namespace := uuid.NewString()
+	callback := rio.NewCallback("http://localhost/webhook").WithBodyTemplate(`{"id": "{{ .PathParams.id }}"}`).WithRetry(3, time.Second)
+	stub := rio.NewStub().
+		WithNamespace(namespace).
+		For("POST", rio.Contains("payment")).
+		WillReturn(rio.NewResponse()).
+		WithCallback(callback)
+	require.NoError(t, store.Create(ctx, stub))
+
+	foundStub, err := store.Find(ctx, stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, []*rio.Callback{callback}, foundStub.Callbacks)
+
+	results := []*rio.CallbackResult{
+		{
+			Namespace:         namespace,
+			StubID:            stub.ID,
+			IncomingRequestID: 1,
+			URL:               "http://localhost/webhook",
+			Method:            "POST",
+			Header:            map[string]string{rio.HeaderContentType: rio.ContentTypeJSON},
+			Body:              []byte(`{"id": "1"}`),
+			StatusCode:        200,
+			Attempts:          2,
+			Delivered:         true,
+		},
+		{Namespace: namespace, StubID: stub.ID, IncomingRequestID: 2, URL: "http://localhost/webhook", Method: "POST", Attempts: 3, Error: "connection refused"},
+	}
+
+	for _, r := range results {
+		require.NoError(t, store.CreateCallbackResult(ctx, r))
+		require.NotZero(t, r.ID)
+	}
+
+	found, err := store.GetCallbackResults(ctx, &rio.CallbackQueryOption{Namespace: namespace, StubID: stub.ID})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, results[1].ID, found[0].ID)
+	require.Equal(t, "connection refused", found[0].Error)
+
+	found, err = store.GetCallbackResults(ctx, &rio.CallbackQueryOption{Namespace: namespace, IncomingRequestID: 1})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.True(t, found[0].Delivered)
+	require.Equal(t, 2, found[0].Attempts)
+	require.Equal(t, results[0].Header, found[0].Header)
+	require.Equal(t, results[0].Body, found[0].Body)
+
+	require.NoError(t, store.Reset(ctx, &rio.ResetQueryOption{Namespace: namespace}))
+	found, err = store.GetCallbackResults(ctx, &rio.CallbackQueryOption{Namespace: namespace})
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
@@ -1,11 +1,11 @@
 package grpc
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
+	"io"
 
 	"github.com/hungdv136/rio"
 	"github.com/hungdv136/rio/internal/log"
@@ -26,15 +26,21 @@ type requestContext struct {
 	methodDesc *desc.MethodDescriptor
 	protoInput *dynamic.Message
 	jsonInput  []byte
-	mapInput   types.Map
 	stub       *rio.Stub
 	descriptor *Descriptor
+
+	// grpcRequest holds the decoded messages which are used to render the response template
+	grpcRequest *rio.GrpcRequest
+
+	// headerSent is true if the header has been sent, the header can only be sent once in a bidirectional stream
+	headerSent bool
 }
 
 type handler struct {
-	descriptor  *ServiceDescriptor
-	stubStore   rio.StubStore
-	fileStorage fs.FileStorage
+	descriptor     *ServiceDescriptor
+	stubStore      rio.StubStore
+	fileStorage    fs.FileStorage
+	callbackSender *rio.CallbackSender
 }
 
 func newHandler(stubStore rio.StubStore, fileStorage fs.FileStorage, descriptor *ServiceDescriptor) *handler {
@@ -47,7 +53,7 @@ func newHandler(stubStore rio.StubStore, fileStorage fs.FileStorage, descriptor
 
 // handleRequest is a generic handler to handle incoming grpc stream
 func (h *handler) handleRequest(srv interface{}, stream grpc.ServerStream) error {
-	ctx := stream.Context()
+	ctx := rio.ContextWithFileStorage(stream.Context(), h.fileStorage)
 	tranStream := grpc.ServerTransportStreamFromContext(ctx)
 	if tranStream == nil {
 		err := errors.New("cannot get transport from context")
@@ -60,8 +66,17 @@ func (h *handler) handleRequest(srv interface{}, stream grpc.ServerStream) error
 	fullMethod := tranStream.Method()
 	incomingRequest := captureIncomingRequest(ctx, fullMethod)
 
+	// The request is still recorded if the client cancels it while waiting for delay
+	// Callbacks are dispatched after the request is recorded, so that their results are linked to the request
+	var callbacks []*rio.CallbackDispatcher
 	defer util.CloseSilently(ctx, func() error {
-		return h.stubStore.CreateIncomingRequest(ctx, incomingRequest)
+		ctx := context.WithoutCancel(ctx)
+		err := h.stubStore.CreateIncomingRequest(ctx, incomingRequest)
+		for _, c := range callbacks {
+			c.Dispatch(ctx, incomingRequest)
+		}
+
+		return err
 	})
 
 	descriptor, err := h.getProtoDescriptor(ctx, fullMethod)
@@ -74,32 +89,121 @@ func (h *handler) handleRequest(srv interface{}, stream grpc.ServerStream) error
 		return err
 	}
 
-	rawInput := dynamic.NewMessage(methodDesc.GetInputType())
-	if err := stream.RecvMsg(rawInput); err != nil {
-		log.Error(ctx, "cannot parse input", err)
-		return err
+	reqCtx := &requestContext{
+		fullMethod: fullMethod,
+		stream:     stream,
+		methodDesc: methodDesc,
+		descriptor: descriptor,
+	}
+
+	if methodDesc.IsClientStreaming() && methodDesc.IsServerStreaming() {
+		return h.handleBidiStream(ctx, reqCtx, incomingRequest, &callbacks)
 	}
 
-	inputData, err := marshalJSONPB(ctx, rawInput)
+	rawInputs, err := receiveMessages(ctx, stream, methodDesc)
 	if err != nil {
 		return err
 	}
 
-	inputMap, err := types.CreateMapFromReader(bytes.NewReader(inputData))
+	inputMaps := make([]types.Map, len(rawInputs))
+	for i, rawInput := range rawInputs {
+		if inputMaps[i], err = messageToMap(ctx, rawInput); err != nil {
+			return err
+		}
+	}
+
+	inputData, err := encodeInputData(ctx, methodDesc, rawInputs, inputMaps)
 	if err != nil {
-		log.Error(ctx, "cannot marshal input to map", err)
 		return err
 	}
 
-	grpcRequest := &rio.GrpcRequest{FullMethod: fullMethod, InputData: inputMap}
-	stub, err := h.getMatchedStub(ctx, grpcRequest)
+	grpcRequest := &rio.GrpcRequest{FullMethod: fullMethod, Messages: inputMaps}
+	if len(inputMaps) > 0 {
+		grpcRequest.InputData = inputMaps[0]
+		reqCtx.protoInput = rawInputs[0]
+	}
+
+	incomingRequest.Body = inputData
+	reqCtx.jsonInput = inputData
+
+	dispatcher, err := h.respond(ctx, reqCtx, grpcRequest, incomingRequest)
+	if dispatcher != nil {
+		callbacks = append(callbacks, dispatcher)
+	}
+
+	return err
+}
+
+// handleBidiStream replies each message of a bidirectional stream as soon as it is received
+// A message is matched with all messages received so far, the message is not replied if no stub is matched
+// So that clients can wait for a reply before sending the next message
+func (h *handler) handleBidiStream(ctx context.Context, r *requestContext, incomingRequest *rio.IncomingRequest, callbacks *[]*rio.CallbackDispatcher) error {
+	var rawInputs []*dynamic.Message
+	var inputMaps []types.Map
+	var notFoundErr error
+	replied := false
+
+	for {
+		rawInput := dynamic.NewMessage(r.methodDesc.GetInputType())
+		if err := r.stream.RecvMsg(rawInput); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Error(ctx, "cannot parse input", err)
+				return err
+			}
+
+			log.Info(ctx, "received messages from bidirectional stream", len(rawInputs))
+			if !replied {
+				return notFoundErr
+			}
+
+			incomingRequest.Diagnostics = nil
+			return nil
+		}
+
+		inputMap, err := messageToMap(ctx, rawInput)
+		if err != nil {
+			return err
+		}
+
+		rawInputs = append(rawInputs, rawInput)
+		inputMaps = append(inputMaps, inputMap)
+		if incomingRequest.Body, err = encodeInputData(ctx, r.methodDesc, rawInputs, inputMaps); err != nil {
+			return err
+		}
+
+		r.protoInput = rawInput
+		r.jsonInput = incomingRequest.Body
+		grpcRequest := &rio.GrpcRequest{FullMethod: r.fullMethod, Messages: inputMaps, InputData: inputMap}
+
+		dispatcher, err := h.respond(ctx, r, grpcRequest, incomingRequest)
+		if dispatcher != nil {
+			*callbacks = append(*callbacks, dispatcher)
+		}
+
+		if status.Code(err) == codes.NotFound && r.stub == nil {
+			notFoundErr = err
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		replied = true
+		r.stub = nil
+	}
+}
+
+// respond matches the received messages with stubs, then writes the response of the matched stub
+// Callbacks are returned only if the response is written, even the response is an error status
+func (h *handler) respond(ctx context.Context, r *requestContext, grpcRequest *rio.GrpcRequest, incomingRequest *rio.IncomingRequest) (*rio.CallbackDispatcher, error) {
+	stub, err := h.getMatchedStub(ctx, grpcRequest, incomingRequest)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	incomingRequest.StubID = stub.ID
 	incomingRequest.Tag = stub.Tag
-	incomingRequest.Body = inputData
 
 	if stub.Settings.DeactivateWhenMatched {
 		log.Info(ctx, "remove used stub", stub.ID)
@@ -108,30 +212,85 @@ func (h *handler) handleRequest(srv interface{}, stream grpc.ServerStream) error
 		}
 	}
 
-	if stub.Settings.DelayDuration > 0 {
-		log.Info(ctx, "delay response", stub.Settings.DelayDuration)
-		time.Sleep(stub.Settings.DelayDuration)
+	callbacks, err := rio.NewCallbackDispatcher(ctx, h.callbackSender, stub, &rio.TemplateData{Grpc: grpcRequest})
+	if err != nil {
+		return nil, err
 	}
 
-	reqCtx := &requestContext{
-		fullMethod: fullMethod,
-		stream:     stream,
-		methodDesc: methodDesc,
-		protoInput: rawInput,
-		jsonInput:  inputData,
-		stub:       stub,
-		descriptor: descriptor,
+	if delay := stub.Settings.SampleDelay(); delay > 0 {
+		log.Info(ctx, "delay response", delay)
+		if err := rio.Sleep(ctx, delay); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
 	}
 
+	r.stub = stub
+	r.grpcRequest = grpcRequest
+
+	var st *status.Status
 	if stub.IsReversed() {
-		return h.reverseProxy(ctx, reqCtx)
+		st, err = h.reverseProxy(ctx, r)
+	} else {
+		if err := h.processResponse(ctx, r); err != nil {
+			return nil, err
+		}
+
+		st, err = h.writeGrpcResponse(ctx, r)
 	}
 
-	if err := h.processResponse(ctx, reqCtx); err != nil {
-		return err
+	if err != nil {
+		return nil, err
 	}
 
-	return writeGrpcResponse(ctx, reqCtx)
+	return callbacks, st.Err()
+}
+
+// receiveMessages receives a single message for unary and server streaming methods
+// For client streaming and bidirectional streaming methods, it receives all messages until the client closes the stream
+func receiveMessages(ctx context.Context, stream grpc.ServerStream, methodDesc *desc.MethodDescriptor) ([]*dynamic.Message, error) {
+	if !methodDesc.IsClientStreaming() {
+		rawInput := dynamic.NewMessage(methodDesc.GetInputType())
+		if err := stream.RecvMsg(rawInput); err != nil {
+			log.Error(ctx, "cannot parse input", err)
+			return nil, err
+		}
+
+		return []*dynamic.Message{rawInput}, nil
+	}
+
+	var rawInputs []*dynamic.Message
+	for {
+		rawInput := dynamic.NewMessage(methodDesc.GetInputType())
+		if err := stream.RecvMsg(rawInput); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			log.Error(ctx, "cannot parse input", err)
+			return nil, err
+		}
+
+		rawInputs = append(rawInputs, rawInput)
+	}
+
+	log.Info(ctx, "received messages from client stream", len(rawInputs))
+	return rawInputs, nil
+}
+
+// encodeInputData encodes the received messages to save to incoming request
+// For client streaming methods, all messages are encoded as a JSON array
+func encodeInputData(ctx context.Context, methodDesc *desc.MethodDescriptor, rawInputs []*dynamic.Message, inputMaps []types.Map) ([]byte, error) {
+	if !methodDesc.IsClientStreaming() {
+		return marshalJSONPB(ctx, rawInputs[0])
+	}
+
+	b, err := json.Marshal(inputMaps)
+	if err != nil {
+		log.Error(ctx, "cannot encode input", err)
+		return nil, err
+	}
+
+	return b, nil
 }
 
 func (h *handler) getProtoDescriptor(ctx context.Context, fullMethod string) (*Descriptor, error) {
@@ -151,7 +310,7 @@ func (h *handler) getProtoDescriptor(ctx context.Context, fullMethod string) (*D
 	return nil, err
 }
 
-func (h *handler) getMatchedStub(ctx context.Context, r *rio.GrpcRequest) (*rio.Stub, error) {
+func (h *handler) getMatchedStub(ctx context.Context, r *rio.GrpcRequest, incomingRequest *rio.IncomingRequest) (*rio.Stub, error) {
 	stubs, err := h.stubStore.GetAll(ctx, "")
 	if err != nil {
 		return nil, err
@@ -176,14 +335,37 @@ func (h *handler) getMatchedStub(ctx context.Context, r *rio.GrpcRequest) (*rio.
 	}
 
 	if len(matchedStubs) == 0 {
+		md, _ := metadata.FromIncomingContext(ctx)
+		incomingRequest.Diagnostics = rio.DiagnoseGrpcRequest(ctx, stubs, r, md)
 		err := status.Errorf(codes.NotFound, "no matched stub found for %s", r.FullMethod)
+		if len(incomingRequest.Diagnostics) > 0 {
+			err = status.Errorf(codes.NotFound, "no matched stub found for %s, closest stubs:\n%s", r.FullMethod, incomingRequest.Diagnostics)
+		}
+
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	// The stubs are loaded from a single namespace, so the matched stubs share the same scenario states
+	namespace := matchedStubs[0].Namespace
+	stub, err := rio.SelectStubWithScenario(ctx, h.stubStore, namespace, matchedStubs)
+	if err != nil {
+		return nil, err
+	}
+
+	if stub == nil {
+		incomingRequest.Diagnostics, err = rio.DiagnoseScenario(ctx, h.stubStore, namespace, matchedStubs)
+		if err != nil {
+			return nil, err
+		}
+
+		err := status.Errorf(codes.NotFound, "no matched stub found for current scenario state of %s, closest stubs:\n%s", r.FullMethod, incomingRequest.Diagnostics)
 		log.Error(ctx, err)
 		return nil, err
 	}
 
-	stub := rio.SelectStubs(matchedStubs)
 	log.Info(ctx, "matched stub", stub.ID, stub.Description, "nb stubs", len(stubs))
-	return stub, nil
+	return rio.SelectSequenceResponse(ctx, h.stubStore, stub.Namespace, stub)
 }
 
 func (h *handler) processResponse(ctx context.Context, r *requestContext) error {
@@ -194,8 +376,7 @@ func (h *handler) processResponse(ctx context.Context, r *requestContext) error
 	}
 
 	if r.stub.HasTemplate() {
-		grpcRequest := &rio.GrpcRequest{FullMethod: r.fullMethod, InputData: r.mapInput}
-		if err := r.stub.Response.LoadBodyFromTemplate(ctx, &rio.TemplateData{Grpc: grpcRequest}); err != nil {
+		if err := r.stub.Response.LoadBodyFromTemplate(ctx, &rio.TemplateData{Grpc: r.grpcRequest}); err != nil {
 			return err
 		}
 	}
@@ -203,7 +384,13 @@ func (h *handler) processResponse(ctx context.Context, r *requestContext) error
 	return nil
 }
 
-func (h *handler) reverseProxy(ctx context.Context, r *requestContext) error {
+func (h *handler) reverseProxy(ctx context.Context, r *requestContext) (*status.Status, error) {
+	if r.methodDesc.IsClientStreaming() || r.methodDesc.IsServerStreaming() {
+		err := status.Errorf(codes.Unimplemented, "reverse proxy is not supported for streaming method %s", r.fullMethod)
+		log.Error(ctx, err)
+		return nil, err
+	}
+
 	log.Info(ctx, "forward", getFullMethod(r.methodDesc), "to", r.stub.Proxy.TargetURL)
 
 	md, _ := metadata.FromIncomingContext(ctx)
@@ -216,7 +403,7 @@ func (h *handler) reverseProxy(ctx context.Context, r *requestContext) error {
 	if len(header) > 0 {
 		if err := r.stream.SendHeader(header); err != nil {
 			log.Error(ctx, "cannot send header", err)
-			return err
+			return nil, err
 		}
 	}
 
@@ -224,7 +411,7 @@ func (h *handler) reverseProxy(ctx context.Context, r *requestContext) error {
 	if output != nil && grpcErr == nil {
 		if err := r.stream.SendMsg(output); err != nil {
 			log.Error(ctx, "cannot send message", err)
-			return err
+			return nil, err
 		}
 	}
 
@@ -232,7 +419,8 @@ func (h *handler) reverseProxy(ctx context.Context, r *requestContext) error {
 		log.Error(ctx, "cannot record response", err)
 	}
 
-	return grpcErr
+	// The status of target is relayed to client as the response
+	return status.Convert(grpcErr), nil
 }
 
 func (h *handler) recordResponse(ctx context.Context, r *requestContext, output *dynamic.Message, grpcErr error) error {
@@ -270,28 +458,61 @@ func (h *handler) recordResponse(ctx context.Context, r *requestContext, output
 	return nil
 }
 
-func writeGrpcResponse(ctx context.Context, r *requestContext) error {
-	if len(r.stub.Response.Header) > 0 {
+// writeGrpcResponse writes the header and messages of response, then returns the status which ends the stream
+// A non-nil error is returned if the response cannot be written
+func (h *handler) writeGrpcResponse(ctx context.Context, r *requestContext) (*status.Status, error) {
+	if r.stub.Response.Fault == rio.FaultUnavailable {
+		log.Info(ctx, "inject fault", r.stub.Response.Fault)
+		return status.New(codes.Unavailable, "service is unavailable"), nil
+	}
+
+	if len(r.stub.Response.Header) > 0 && !r.headerSent {
 		if err := r.stream.SendHeader(metadata.New(r.stub.Response.Header)); err != nil {
 			log.Error(ctx, "cannot send header", err)
-			return err
+			return nil, err
 		}
+
+		r.headerSent = true
 	}
 
-	if len(r.stub.Response.Body) > 0 {
-		outputData := dynamic.NewMessage(r.methodDesc.GetOutputType())
-		if err := outputData.UnmarshalJSON(r.stub.Response.Body); err != nil {
-			log.Error(ctx, "cannot encode message", err)
-			return err
-		}
+	if r.methodDesc.IsServerStreaming() && len(r.stub.Response.Stream) > 0 {
+		for _, message := range r.stub.Response.Stream {
+			if err := rio.Sleep(ctx, message.Delay); err != nil {
+				return nil, status.FromContextError(err).Err()
+			}
 
-		if err := r.stream.SendMsg(outputData); err != nil {
-			log.Error(ctx, "cannot send message", err)
-			return err
+			if err := sendMessage(ctx, r, message.Body); err != nil {
+				return nil, err
+			}
+		}
+	} else if len(r.stub.Response.Body) > 0 {
+		if err := sendMessage(ctx, r, r.stub.Response.Body); err != nil {
+			return nil, err
 		}
 	}
 
-	return convertGrpcStatus(ctx, r.descriptor, r.stub.Response).Err()
+	// The messages are still delivered, then only this stream is aborted with an error which is not a status
+	if r.stub.Response.Fault == rio.FaultCloseMidStream {
+		log.Info(ctx, "inject fault", r.stub.Response.Fault)
+		return status.New(codes.Unknown, "stream is aborted"), nil
+	}
+
+	return convertGrpcStatus(ctx, r.descriptor, r.stub.Response), nil
+}
+
+func sendMessage(ctx context.Context, r *requestContext, body []byte) error {
+	outputData := dynamic.NewMessage(r.methodDesc.GetOutputType())
+	if err := outputData.UnmarshalJSON(body); err != nil {
+		log.Error(ctx, "cannot encode message", err)
+		return err
+	}
+
+	if err := r.stream.SendMsg(outputData); err != nil {
+		log.Error(ctx, "cannot send message", err)
+		return err
+	}
+
+	return nil
 }
 
 func captureIncomingRequest(ctx context.Context, fullMethod string) *rio.IncomingRequest {
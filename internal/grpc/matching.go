@@ -7,6 +7,7 @@ import (
 	"github.com/PaesslerAG/jsonpath"
 	"github.com/hungdv136/rio"
 	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/types"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -27,6 +28,10 @@ func match(ctx context.Context, r *rio.GrpcRequest, s *rio.Stub) (bool, error) {
 		return false, err
 	}
 
+	if matched, err := matchStream(ctx, r, s); err != nil || !matched {
+		return false, err
+	}
+
 	return true, nil
 }
 
@@ -45,17 +50,18 @@ func matchBody(ctx context.Context, r *rio.GrpcRequest, s *rio.Stub) (bool, erro
 		return true, nil
 	}
 
-	input := map[string]interface{}(r.InputData)
 	for _, op := range s.Request.Body {
-		val, err := jsonpath.Get(op.KeyPath, input)
-		if err != nil {
-			if !strings.Contains(err.Error(), "unknown key") {
-				log.Error(ctx, "error when executing json path", err)
-				return false, err
-			}
+		if matched, err := matchMessage(ctx, op.KeyPath, op.Operator, r.InputData); err != nil || !matched {
+			return false, err
 		}
+	}
 
-		if matched, err := rio.Match(ctx, op.Operator, val); err != nil || !matched {
+	return true, nil
+}
+
+func matchStream(ctx context.Context, r *rio.GrpcRequest, s *rio.Stub) (bool, error) {
+	for _, op := range s.Request.Stream {
+		if matched, err := rio.MatchStream(ctx, op, r.Messages); err != nil || !matched {
 			return false, err
 		}
 	}
@@ -63,6 +69,18 @@ func matchBody(ctx context.Context, r *rio.GrpcRequest, s *rio.Stub) (bool, erro
 	return true, nil
 }
 
+func matchMessage(ctx context.Context, keyPath string, op rio.Operator, message types.Map) (bool, error) {
+	val, err := jsonpath.Get(keyPath, map[string]interface{}(message))
+	if err != nil {
+		if !strings.Contains(err.Error(), "unknown key") {
+			log.Error(ctx, "error when executing json path", err)
+			return false, err
+		}
+	}
+
+	return rio.Match(ctx, op, val)
+}
+
 func matchHeader(ctx context.Context, s *rio.Stub) (bool, error) {
 	if len(s.Request.Header) == 0 {
 		return true, nil
@@ -74,25 +92,10 @@ func matchHeader(ctx context.Context, s *rio.Stub) (bool, error) {
 	}
 
 	for _, op := range s.Request.Header {
-		if matched, err := matchAnyString(ctx, op.Operator, metadata.Get(op.FieldName)); err != nil || !matched {
+		if matched, err := rio.MatchValues(ctx, op.GetQuantifier(rio.QuantifierAny), op.Operator, metadata.Get(op.FieldName)); err != nil || !matched {
 			return false, err
 		}
 	}
 
 	return true, nil
 }
-
-func matchAnyString(ctx context.Context, op rio.Operator, arr []string) (bool, error) {
-	if len(arr) == 0 {
-		// Assert whether matching rule accepts empty value (rio.Empty())
-		return rio.Match(ctx, op, "")
-	}
-
-	for _, val := range arr {
-		if matched, err := rio.Match(ctx, op, val); err != nil || matched {
-			return matched, err
-		}
-	}
-
-	return false, nil
-}
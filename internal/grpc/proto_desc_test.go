@@ -1,10 +1,16 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/setup"
 	fs "github.com/hungdv136/rio/internal/storage"
 	"github.com/stretchr/testify/require"
 )
@@ -28,6 +34,38 @@ func TestServiceDescriptor(t *testing.T) {
 	require.Equal(t, "ValidateOffer", method.GetName())
 }
 
+func TestServiceDescriptor_Restart(t *testing.T) {
+	ctx := log.SaveID(context.Background(), t.Name())
+	dataDir := t.TempDir()
+	t.Setenv("DB_TYPE", config.DBTypeSQLite)
+	t.Setenv("SQLITE_PATH", filepath.Join(dataDir, "rio.db"))
+
+	storage, err := setup.ProvideFileStorage(ctx, config.NewConfig())
+	require.NoError(t, err)
+
+	content, err := os.ReadFile("../../testdata/offer_proto")
+	require.NoError(t, err)
+
+	fileID := uuid.NewString()
+	_, err = storage.UploadFile(ctx, fileID, bytes.NewReader(content))
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dataDir, "uploaded_files", fileID))
+
+	// The restarted server has a new file storage and an empty proto cache
+	restartedStorage, err := setup.ProvideFileStorage(ctx, config.NewConfig())
+	require.NoError(t, err)
+
+	sd := NewServiceDescriptor(restartedStorage)
+	sd.cachedDir = t.TempDir()
+
+	d, err := sd.GetDescriptor(ctx, fileID)
+	require.NoError(t, err)
+
+	method, err := d.GetMethod(ctx, "/offers.v1.OfferService/ValidateOffer")
+	require.NoError(t, err)
+	require.Equal(t, "ValidateOffer", method.GetName())
+}
+
 func TestDescriptor(t *testing.T) {
 	t.Parallel()
 
@@ -35,12 +73,17 @@ func TestDescriptor(t *testing.T) {
 	s := NewDescriptor()
 	err := s.init(ctx, "../../testdata/proto")
 	require.NoError(t, err)
-	require.Len(t, s.sdMap, 1)
+	require.Len(t, s.sdMap, 2)
 
 	method, err := s.GetMethod(ctx, "/offers.v1.OfferService/ValidateOffer")
 	require.NoError(t, err)
 	require.NotNil(t, method)
 	require.Equal(t, "ValidateOffer", method.GetName())
+
+	method, err = s.GetMethod(ctx, "/events.v1.EventService/Sync")
+	require.NoError(t, err)
+	require.True(t, method.IsClientStreaming())
+	require.True(t, method.IsServerStreaming())
 }
 
 func cleanup(t *testing.T, d *ServiceDescriptor) {
@@ -19,6 +19,7 @@ import (
 type Server struct {
 	listener   net.Listener
 	grpcServer *grpc.Server
+	handler    *handler
 }
 
 func NewServer(stubStore rio.StubStore, fileStorage fs.FileStorage, descriptor *ServiceDescriptor) *Server {
@@ -26,7 +27,14 @@ func NewServer(stubStore rio.StubStore, fileStorage fs.FileStorage, descriptor *
 	grpcServer := grpc.NewServer(grpc.UnknownServiceHandler(handler.handleRequest))
 	health.RegisterHealthServer(grpcServer, &HealthService{})
 	reflection.Register(grpcServer)
-	return &Server{grpcServer: grpcServer}
+	return &Server{grpcServer: grpcServer, handler: handler}
+}
+
+// WithCallbackSender sets the sender of callbacks. Callbacks are skipped if the sender is not set
+// The caller owns the sender, which can be shared with the http server in the same process and must be closed after the server is stopped
+func (s *Server) WithCallbackSender(sender *rio.CallbackSender) *Server {
+	s.handler.callbackSender = sender
+	return s
 }
 
 // Start starts the grpc server
@@ -63,7 +71,9 @@ func (s *Server) serve(ctx context.Context) error {
 	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGKILL)
 	defer cancel()
 
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-ctx.Done()
 
 		log.Info(ctx, "shutting down server")
@@ -71,5 +81,11 @@ func (s *Server) serve(ctx context.Context) error {
 	}()
 
 	log.Info(ctx, "starting server at address", s.listener.Addr().String())
-	return s.grpcServer.Serve(s.listener)
+	if err := s.grpcServer.Serve(s.listener); err != nil {
+		return err
+	}
+
+	// Serve returns as soon as the server is stopping, wait until the pending requests are completed
+	<-stopped
+	return nil
 }
@@ -2,16 +2,23 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hungdv136/rio"
 	"github.com/hungdv136/rio/internal/config"
-	"github.com/hungdv136/rio/internal/database"
 	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/setup"
 	fs "github.com/hungdv136/rio/internal/storage"
 	"github.com/hungdv136/rio/internal/types"
+	"github.com/jhump/protoreflect/dynamic"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -24,7 +31,7 @@ func TestServerEndToEnd(t *testing.T) {
 	ctx := log.SaveID(context.Background(), t.Name())
 	storageCfg := fs.LocalStorageConfig{StoragePath: "../../testdata"}
 	storage := fs.NewLocalStorage(storageCfg)
-	stubStore, err := database.NewStubDBStore(ctx, config.NewDBConfig())
+	stubStore, err := setup.ProvideStubStore(ctx, config.NewConfig())
 	require.NoError(t, err)
 
 	sd := NewServiceDescriptor(storage)
@@ -71,6 +78,27 @@ func TestServerEndToEnd(t *testing.T) {
 		require.Equal(t, actualOutputMap, outputMap)
 	})
 
+	t.Run("matched_json_equal", func(t *testing.T) {
+		t.Parallel()
+
+		requestID := uuid.NewString()
+		outputMap := types.Map{"id": uuid.NewString(), "request_id": uuid.NewString()}
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestBody(rio.BodyJSONEqual(types.Map{"request_id": requestID}, rio.IgnoreExtraFields())).
+			WillReturn(rio.NewResponse().WithBody(rio.MustToJSON(outputMap)))))
+
+		input, err := mapToMessage(ctx, types.Map{"request_id": requestID}, m.GetInputType())
+		require.NoError(t, err)
+
+		actualOutput, err := invokeGrpc(ctx, serverAddr, m, input)
+		require.NoError(t, err)
+
+		actualOutputMap, err := messageToMap(ctx, actualOutput)
+		require.NoError(t, err)
+		require.Equal(t, actualOutputMap, outputMap)
+	})
+
 	t.Run("not_matched_input_param", func(t *testing.T) {
 		t.Parallel()
 
@@ -124,6 +152,28 @@ func TestServerEndToEnd(t *testing.T) {
 		require.Equal(t, actualOutputMap, proxyOutputMap)
 	})
 
+	t.Run("delay_cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		requestID := uuid.NewString()
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestBody(rio.BodyJSONPath("$.request_id", rio.EqualTo(requestID))).
+			ShouldDelayWith(rio.UniformDelay(time.Hour, 2*time.Hour)).
+			WillReturn(rio.NewResponse())))
+
+		input, err := mapToMessage(ctx, types.Map{"request_id": requestID}, m.GetInputType())
+		require.NoError(t, err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = invokeGrpc(timeoutCtx, serverAddr, m, input)
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		require.Less(t, time.Since(start), time.Second)
+	})
+
 	t.Run("error", func(t *testing.T) {
 		t.Parallel()
 
@@ -157,3 +207,292 @@ func TestServerEndToEnd(t *testing.T) {
 		require.Equal(t, "success", resErr.Details[0].Value.ForceString("verdict"))
 	})
 }
+
+func TestServerStreaming(t *testing.T) {
+	t.Parallel()
+
+	ctx := log.SaveID(context.Background(), t.Name())
+	storageCfg := fs.LocalStorageConfig{StoragePath: "../../testdata"}
+	storage := fs.NewLocalStorage(storageCfg)
+	stubStore := rio.NewStubMemory()
+
+	sd := NewServiceDescriptor(storage)
+	sd.cachedDir = uuid.NewString()
+	cleanup(t, sd)
+
+	callbackSender := rio.NewCallbackSender(stubStore)
+	t.Cleanup(callbackSender.Close)
+
+	server := NewServer(stubStore, storage, sd).WithCallbackSender(callbackSender)
+	require.NoError(t, server.StartAsync(ctx, ""))
+	serverAddr := server.listener.Addr().String()
+
+	proto := &rio.Proto{
+		Name:   "event",
+		FileID: "event_proto",
+		Methods: []string{
+			"/events.v1.EventService/Subscribe",
+			"/events.v1.EventService/Upload",
+			"/events.v1.EventService/Sync",
+		},
+	}
+	require.NoError(t, stubStore.CreateProto(ctx, proto))
+
+	descriptor, err := sd.GetDescriptor(ctx, proto.FileID)
+	require.NoError(t, err)
+
+	conn, closeConn, err := newConnection(ctx, serverAddr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = closeConn() })
+
+	// invokeStream sends all inputs then receives all outputs until the server closes the stream
+	invokeStream := func(t *testing.T, fullMethod string, inputs ...types.Map) ([]types.Map, error) {
+		m, err := descriptor.GetMethod(ctx, fullMethod)
+		require.NoError(t, err)
+
+		streamDesc := &grpc.StreamDesc{ServerStreams: m.IsServerStreaming(), ClientStreams: m.IsClientStreaming()}
+		stream, err := conn.NewStream(ctx, streamDesc, fullMethod)
+		require.NoError(t, err)
+
+		for _, input := range inputs {
+			msg, err := mapToMessage(ctx, input, m.GetInputType())
+			require.NoError(t, err)
+			require.NoError(t, stream.SendMsg(msg))
+		}
+		require.NoError(t, stream.CloseSend())
+
+		var outputs []types.Map
+		for {
+			output := dynamic.NewMessage(m.GetOutputType())
+			if err := stream.RecvMsg(output); err != nil {
+				if errors.Is(err, io.EOF) {
+					return outputs, nil
+				}
+
+				return outputs, err
+			}
+
+			outputMap, err := messageToMap(ctx, output)
+			require.NoError(t, err)
+			outputs = append(outputs, outputMap)
+		}
+	}
+
+	t.Run("server_streaming", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Subscribe"
+		topic := uuid.NewString()
+		events := []types.Map{
+			{"id": uuid.NewString(), "name": "created"},
+			{"id": uuid.NewString(), "name": "updated"},
+		}
+
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestBody(rio.BodyJSONPath("$.topic", rio.EqualTo(topic))).
+			WillReturn(rio.NewResponse().WithStreamMessages(
+				rio.JSONStreamMessage(events[0], 0),
+				rio.JSONStreamMessage(events[1], 10*time.Millisecond),
+			))))
+
+		outputs, err := invokeStream(t, fullMethod, types.Map{"topic": topic})
+		require.NoError(t, err)
+		require.Equal(t, events, outputs)
+	})
+
+	t.Run("client_streaming", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Upload"
+		firstID := uuid.NewString()
+		lastID := uuid.NewString()
+
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestStream(rio.StreamFirst("$.id", rio.EqualTo(firstID))).
+			WithRequestStream(rio.StreamLast("$.id", rio.EqualTo(lastID))).
+			WithRequestStream(rio.StreamAll("$.name", rio.StartWith("upload"))).
+			WithRequestStream(rio.StreamCount(rio.EqualTo(3))).
+			WillReturn(rio.NewResponse().WithBody(rio.MustToJSON(types.Map{"count": 3})))))
+
+		outputs, err := invokeStream(t, fullMethod,
+			types.Map{"id": firstID, "name": "upload_1"},
+			types.Map{"id": uuid.NewString(), "name": "upload_2"},
+			types.Map{"id": lastID, "name": "upload_3"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []types.Map{{"count": json.Number("3")}}, outputs)
+
+		_, err = invokeStream(t, fullMethod,
+			types.Map{"id": firstID, "name": "upload_1"},
+			types.Map{"id": lastID, "name": "upload_3"},
+		)
+		require.Equal(t, codes.NotFound, status.Code(err))
+		require.Contains(t, status.Convert(err).Message(), "stream[count]: expected equal_to 3, actual 2")
+	})
+
+	t.Run("client_streaming_template", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Upload"
+		id := uuid.NewString()
+
+		// All received messages are available in template
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestStream(rio.StreamFirst("$.id", rio.EqualTo(id))).
+			WillReturn(&rio.Response{Template: &rio.Template{Script: `body: '{"count": {{ len .Grpc.Messages }}}'`}})))
+
+		outputs, err := invokeStream(t, fullMethod,
+			types.Map{"id": id, "name": "upload_1"},
+			types.Map{"id": uuid.NewString(), "name": "upload_2"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []types.Map{{"count": json.Number("2")}}, outputs)
+	})
+
+	t.Run("bidirectional_streaming", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Sync"
+		id := uuid.NewString()
+		output := types.Map{"id": id, "name": "synced"}
+
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestStream(rio.StreamAny("$.id", rio.EqualTo(id))).
+			WillReturn(rio.NewResponse().WithStreamMessages(
+				rio.JSONStreamMessage(output, 0),
+				rio.JSONStreamMessage(output, 0),
+			))))
+
+		outputs, err := invokeStream(t, fullMethod,
+			types.Map{"id": uuid.NewString(), "name": "sync"},
+			types.Map{"id": id, "name": "sync"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []types.Map{output, output}, outputs)
+	})
+
+	t.Run("bidirectional_ping_pong", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Sync"
+		id := uuid.NewString()
+
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestStream(rio.StreamLast("$.id", rio.EqualTo(id))).
+			WillReturn(rio.NewResponse().WithStreamMessages(rio.JSONStreamMessage(types.Map{"id": id, "name": "pong"}, 0)))))
+
+		m, err := descriptor.GetMethod(ctx, fullMethod)
+		require.NoError(t, err)
+
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, fullMethod)
+		require.NoError(t, err)
+
+		// The client waits for the reply before sending the next message
+		for i := 0; i < 3; i++ {
+			msg, err := mapToMessage(ctx, types.Map{"id": id, "name": "ping"}, m.GetInputType())
+			require.NoError(t, err)
+			require.NoError(t, stream.SendMsg(msg))
+
+			output := dynamic.NewMessage(m.GetOutputType())
+			require.NoError(t, stream.RecvMsg(output))
+			require.Equal(t, "pong", output.GetFieldByName("name"))
+		}
+
+		require.NoError(t, stream.CloseSend())
+		require.ErrorIs(t, stream.RecvMsg(dynamic.NewMessage(m.GetOutputType())), io.EOF)
+	})
+
+	t.Run("fault", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Subscribe"
+		closeTopic := uuid.NewString()
+		unavailableTopic := uuid.NewString()
+		event := types.Map{"id": uuid.NewString(), "name": "created"}
+
+		require.NoError(t, stubStore.Create(ctx,
+			rio.NewStub().
+				ForGRPC(rio.EqualTo(fullMethod)).
+				WithRequestBody(rio.BodyJSONPath("$.topic", rio.EqualTo(closeTopic))).
+				WillReturn(rio.NewResponse().WithStreamMessages(rio.JSONStreamMessage(event, 0)).WithFault(rio.FaultCloseMidStream)),
+			rio.NewStub().
+				ForGRPC(rio.EqualTo(fullMethod)).
+				WithRequestBody(rio.BodyJSONPath("$.topic", rio.EqualTo(unavailableTopic))).
+				WillReturn(rio.NewResponse().WithStreamMessages(rio.JSONStreamMessage(event, 0)).WithFault(rio.FaultUnavailable)),
+		))
+
+		// The messages are delivered before the stream is aborted
+		outputs, err := invokeStream(t, fullMethod, types.Map{"topic": closeTopic})
+		require.Equal(t, codes.Unknown, status.Code(err))
+		require.Equal(t, []types.Map{event}, outputs)
+
+		outputs, err = invokeStream(t, fullMethod, types.Map{"topic": unavailableTopic})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		require.Empty(t, outputs)
+
+		// Faults are scoped to the stream, so the shared connection is still usable
+		_, err = invokeStream(t, fullMethod, types.Map{"topic": uuid.NewString()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("sequence", func(t *testing.T) {
+		t.Parallel()
+
+		fullMethod := "/events.v1.EventService/Subscribe"
+		topic := uuid.NewString()
+		events := []types.Map{
+			{"id": uuid.NewString(), "name": "created"},
+			{"id": uuid.NewString(), "name": "updated"},
+		}
+
+		require.NoError(t, stubStore.Create(ctx, rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestBody(rio.BodyJSONPath("$.topic", rio.EqualTo(topic))).
+			WillReturnSequence(rio.RoundRobinResponses(
+				rio.NewResponse().WithStreamMessages(rio.JSONStreamMessage(events[0], 0)),
+				rio.NewResponse().WithStreamMessages(rio.JSONStreamMessage(events[1], 0)),
+			))))
+
+		for _, expected := range []types.Map{events[0], events[1], events[0]} {
+			outputs, err := invokeStream(t, fullMethod, types.Map{"topic": topic})
+			require.NoError(t, err)
+			require.Equal(t, []types.Map{expected}, outputs)
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		t.Parallel()
+
+		webhookServer := rio.NewLocalServerWithReporter(t)
+		require.NoError(t, rio.NewStub().For(http.MethodPost, rio.Contains("events")).
+			WillReturn(rio.JSONResponse(types.Map{"received": true})).
+			Send(ctx, webhookServer))
+
+		fullMethod := "/events.v1.EventService/Subscribe"
+		topic := uuid.NewString()
+		stub := rio.NewStub().
+			ForGRPC(rio.EqualTo(fullMethod)).
+			WithRequestBody(rio.BodyJSONPath("$.topic", rio.EqualTo(topic))).
+			WillReturn(rio.NewResponse().WithStreamMessages(rio.JSONStreamMessage(types.Map{"id": topic}, 0))).
+			WithCallback(rio.NewCallback(webhookServer.GetURL(ctx) + "/events").WithBodyTemplate(`{"topic": "{{ .JSONBody.topic }}"}`))
+		require.NoError(t, stubStore.Create(ctx, stub))
+
+		_, err := invokeStream(t, fullMethod, types.Map{"topic": topic})
+		require.NoError(t, err)
+
+		var callbacks []*rio.CallbackResult
+		require.Eventually(t, func() bool {
+			callbacks, err = stubStore.GetCallbackResults(ctx, &rio.CallbackQueryOption{StubID: stub.ID})
+			require.NoError(t, err)
+			return len(callbacks) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.True(t, callbacks[0].Delivered)
+		require.JSONEq(t, `{"topic": "`+topic+`"}`, string(callbacks[0].Body))
+	})
+}
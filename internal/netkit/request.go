@@ -117,7 +117,24 @@ func NewQueryRequest(ctx context.Context, method, url string, queries types.Map)
 // PostJSON executes request with POST method and JSON as body, then parse response
 // Body is structure of response body
 func PostJSON[Body any](ctx context.Context, url string, body interface{}) (*Response[Body], error) {
-	req, err := NewJSONRequest(ctx, http.MethodPost, url, body)
+	return SendJSON[Body](ctx, http.MethodPost, url, body)
+}
+
+// SendJSON executes request with the given method and JSON as body, then parse response
+// The request is sent without body if body is nil
+// Body is structure of response body
+func SendJSON[Body any](ctx context.Context, method string, url string, body interface{}) (*Response[Body], error) {
+	var (
+		req *http.Request
+		err error
+	)
+
+	if body == nil {
+		req, err = NewQueryRequest(ctx, method, url, nil)
+	} else {
+		req, err = NewJSONRequest(ctx, method, url, body)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -2,16 +2,25 @@ package setup
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 
 	"github.com/hungdv136/rio"
 	"github.com/hungdv136/rio/internal/cache"
 	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/database"
+	"github.com/hungdv136/rio/internal/log"
 	fs "github.com/hungdv136/rio/internal/storage"
 )
 
 func ProvideStubStore(ctx context.Context, cfg *config.Config) (rio.StubStore, error) {
-	db, err := database.NewStubDBStore(ctx, cfg.DB)
+	// In-memory store is fast enough, no need to cache
+	if cfg.DBType == config.DBTypeMemory {
+		log.Info(ctx, "use in-memory stub store, data are lost when the server is stopped")
+		return rio.NewStubMemory(), nil
+	}
+
+	db, err := provideDBStore(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -19,6 +28,53 @@ func ProvideStubStore(ctx context.Context, cfg *config.Config) (rio.StubStore, e
 	return cache.NewStubCache(db, db, cfg), nil
 }
 
+// ProvideCallbackSender provides the sender of callbacks which must be closed when the server is shut down
+func ProvideCallbackSender(cfg *config.Config, stubStore rio.StubStore) *rio.CallbackSender {
+	return rio.NewCallbackSender(stubStore).
+		WithWorkers(cfg.CallbackWorkers).
+		WithAllowedHosts(rio.ParseCallbackHosts(cfg.CallbackAllowedHosts))
+}
+
+func provideDBStore(ctx context.Context, cfg *config.Config) (*database.StubDBStore, error) {
+	switch cfg.DBType {
+	case config.DBTypePostgres:
+		return database.NewStubPostgresStore(ctx, cfg.Postgres)
+	case config.DBTypeSQLite:
+		return database.NewStubSQLiteStore(ctx, cfg.SQLite)
+	case config.DBTypeMySQL:
+		return database.NewStubDBStore(ctx, cfg.DB)
+	}
+
+	err := fmt.Errorf("unsupported database type %s", cfg.DBType)
+	log.Error(ctx, err)
+	return nil, err
+}
+
+// Migrate runs the migrations of the configured database in the given base directory
+func Migrate(ctx context.Context, cfg *config.Config, baseDir string) error {
+	switch cfg.DBType {
+	case config.DBTypeMemory:
+		log.Info(ctx, "no migration needed for in-memory store")
+		return nil
+	case config.DBTypePostgres:
+		return database.MigratePostgres(ctx, cfg.Postgres, filepath.Join(baseDir, "schema/postgres/migration"))
+	case config.DBTypeSQLite:
+		return database.MigrateSQLite(ctx, cfg.SQLite, filepath.Join(baseDir, "schema/sqlite/migration"))
+	case config.DBTypeMySQL:
+		return database.Migrate(ctx, cfg.DB, filepath.Join(baseDir, "schema/migration"))
+	}
+
+	err := fmt.Errorf("unsupported database type %s", cfg.DBType)
+	log.Error(ctx, err)
+	return err
+}
+
 func ProvideFileStorage(ctx context.Context, cfg *config.Config) (fs.FileStorage, error) {
-	return fs.NewFileStorage(cfg.FileStorage), nil
+	storage, err := fs.NewFileStorage(cfg.FileStorage)
+	if err != nil {
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	return storage, nil
 }
@@ -5,6 +5,7 @@ package storage
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path"
@@ -25,10 +26,18 @@ type FileStorage interface {
 	Reset(ctx context.Context) error
 }
 
-// NewFileStorage creates a new file storages
-// TODO: Support other types (GCS, S3) to deploy mock server as a cluster
-func NewFileStorage(cfg interface{}) FileStorage {
-	return NewLocalStorage(cfg.(LocalStorageConfig))
+// NewFileStorage creates a new file storage from the given config
+// S3 storage is required to deploy mock server as a cluster
+// TODO: Support GCS
+func NewFileStorage(cfg interface{}) (FileStorage, error) {
+	switch c := cfg.(type) {
+	case S3StorageConfig:
+		return NewS3Storage(c)
+	case LocalStorageConfig:
+		return NewLocalStorage(c), nil
+	default:
+		return nil, fmt.Errorf("unsupported file storage config %T", cfg)
+	}
 }
 
 // LocalStorageConfig defines config for local storage
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/hungdv136/rio/internal/log"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var _ FileStorage = (*S3Storage)(nil)
+
+// S3StorageConfig defines config for S3 or any S3 compatible storage (MinIO, ...)
+type S3StorageConfig struct {
+	// Endpoint is the host of storage without scheme, for example: s3.amazonaws.com or localhost:9000
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to the object keys, so that a bucket can be shared with other services
+	Prefix string
+	UseSSL bool
+}
+
+// S3Storage stores files in a S3 bucket which can be shared by multiple instances
+type S3Storage struct {
+	config S3StorageConfig
+	client *minio.Client
+}
+
+// NewS3Storage creates a new instance of S3Storage
+func NewS3Storage(config S3StorageConfig) (*S3Storage, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Storage{config: config, client: client}, nil
+}
+
+// UploadFile reads from reader and puts to the bucket
+func (s *S3Storage) UploadFile(ctx context.Context, objectKey string, reader io.Reader) (string, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		log.Error(ctx, err)
+		return "", err
+	}
+
+	// Streaming signature is not supported by some S3 compatible storages
+	key := s.getKey(objectKey)
+	opts := minio.PutObjectOptions{DisableContentSha256: true}
+	if _, err := s.client.PutObject(ctx, s.config.Bucket, key, bytes.NewReader(content), int64(len(content)), opts); err != nil {
+		log.Error(ctx, "cannot upload file", key, err)
+		return "", err
+	}
+
+	return path.Join(s.config.Bucket, key), nil
+}
+
+// DownloadFile returns the content of object from the bucket
+func (s *S3Storage) DownloadFile(ctx context.Context, objectKey string) (io.ReadCloser, error) {
+	key := s.getKey(objectKey)
+	object, err := s.client.GetObject(ctx, s.config.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		log.Error(ctx, "cannot download file", key, err)
+		return nil, err
+	}
+
+	// GetObject is lazy, stat to make sure that object exists
+	if _, err := object.Stat(); err != nil {
+		_ = object.Close()
+		log.Error(ctx, "cannot download file", key, err)
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// DeleteFile deletes object from the bucket
+func (s *S3Storage) DeleteFile(ctx context.Context, objectKey string) error {
+	key := s.getKey(objectKey)
+	if err := s.client.RemoveObject(ctx, s.config.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		log.Error(ctx, "cannot delete file", key, err)
+		return err
+	}
+
+	return nil
+}
+
+// ListFiles list all files under the configured prefix
+func (s *S3Storage) ListFiles(ctx context.Context) ([]string, error) {
+	prefix := s.getKey("")
+	fileNames := []string{}
+	for object := range s.client.ListObjects(ctx, s.config.Bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			log.Error(ctx, "cannot list files", prefix, object.Err)
+			return []string{}, object.Err
+		}
+
+		// Sub directories are skipped as local storage
+		name := strings.TrimPrefix(object.Key, prefix)
+		if len(name) > 0 && !strings.HasSuffix(name, "/") {
+			fileNames = append(fileNames, name)
+		}
+	}
+
+	return fileNames, nil
+}
+
+// Reset removes all files under the configured prefix
+func (s *S3Storage) Reset(ctx context.Context) error {
+	prefix := s.getKey("")
+	for object := range s.client.ListObjects(ctx, s.config.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			log.Error(ctx, "cannot list files", prefix, object.Err)
+			return object.Err
+		}
+
+		if err := s.client.RemoveObject(ctx, s.config.Bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+			log.Error(ctx, "cannot remove file", object.Key, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *S3Storage) getKey(objectKey string) string {
+	prefix := strings.Trim(s.config.Prefix, "/")
+	if len(prefix) == 0 {
+		return objectKey
+	}
+
+	return prefix + "/" + objectKey
+}
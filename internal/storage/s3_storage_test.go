@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestS3Storage(t *testing.T, prefix string) *S3Storage {
+	backend := s3mem.New()
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	t.Cleanup(server.Close)
+
+	bucket := "rio"
+	require.NoError(t, backend.CreateBucket(bucket))
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	storage, err := NewS3Storage(S3StorageConfig{
+		Endpoint:        serverURL.Host,
+		Region:          "us-east-1",
+		Bucket:          bucket,
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+		Prefix:          prefix,
+	})
+	require.NoError(t, err)
+
+	return storage
+}
+
+func TestS3Storage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := newTestS3Storage(t, "uploaded_files")
+	content := []byte(uuid.NewString())
+	objectKey := uuid.NewString()
+
+	location, err := storage.UploadFile(ctx, objectKey, bytes.NewReader(content))
+	require.NoError(t, err)
+	require.Equal(t, "rio/uploaded_files/"+objectKey, location)
+
+	reader, err := storage.DownloadFile(ctx, objectKey)
+	require.NoError(t, err)
+	downloaded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, content, downloaded)
+
+	_, err = storage.UploadFile(ctx, "nested/"+objectKey, bytes.NewReader(content))
+	require.NoError(t, err)
+
+	files, err := storage.ListFiles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{objectKey}, files)
+
+	require.NoError(t, storage.DeleteFile(ctx, objectKey))
+	_, err = storage.DownloadFile(ctx, objectKey)
+	require.Error(t, err)
+
+	require.NoError(t, storage.Reset(ctx))
+	_, err = storage.DownloadFile(ctx, "nested/"+objectKey)
+	require.Error(t, err)
+}
+
+func TestS3Storage_Prefix(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := newTestS3Storage(t, "")
+	shared := *storage
+	shared.config.Prefix = "other"
+
+	_, err := storage.UploadFile(ctx, "a.proto", bytes.NewReader([]byte("a")))
+	require.NoError(t, err)
+	_, err = shared.UploadFile(ctx, "b.proto", bytes.NewReader([]byte("b")))
+	require.NoError(t, err)
+
+	files, err := shared.ListFiles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b.proto"}, files)
+
+	require.NoError(t, shared.Reset(ctx))
+
+	files, err = storage.ListFiles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto"}, files)
+}
@@ -2,26 +2,62 @@ package test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 
 	"github.com/hungdv136/rio/internal/config"
 	"github.com/hungdv136/rio/internal/database"
+	"github.com/hungdv136/rio/internal/setup"
+	"gorm.io/gorm"
 )
 
 // ResetDB resets DB for testing
 func ResetDB(ctx context.Context, basePath string) {
-	dbConfig := config.NewDBConfig()
-	gormDB, err := database.Connect(ctx, dbConfig)
+	cfg := config.NewConfig()
+	if cfg.DBType == config.DBTypeMemory {
+		return
+	}
+
+	if cfg.DBType == config.DBTypeSQLite {
+		removeSQLiteFiles(cfg.SQLite.Path)
+		if err := setup.Migrate(ctx, cfg, basePath); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	var (
+		gormDB      *gorm.DB
+		err         error
+		resetScript = "schema/reset_db.sql"
+	)
+
+	if cfg.DBType == config.DBTypePostgres {
+		gormDB, err = database.ConnectPostgres(ctx, cfg.Postgres)
+		resetScript = "schema/postgres/reset_db.sql"
+	} else {
+		gormDB, err = database.Connect(ctx, cfg.DB)
+	}
+
 	if err != nil {
 		panic(err)
 	}
 
-	err = database.ExecuteFileScript(ctx, gormDB, filepath.Join(basePath, "schema/reset_db.sql"))
+	err = database.ExecuteFileScript(ctx, gormDB, filepath.Join(basePath, resetScript))
 	if err != nil {
 		panic(err)
 	}
 
-	if err := database.Migrate(ctx, dbConfig, filepath.Join(basePath, "schema/migration")); err != nil {
+	if err := setup.Migrate(ctx, cfg, basePath); err != nil {
 		panic(err)
 	}
 }
+
+func removeSQLiteFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			panic(err)
+		}
+	}
+}
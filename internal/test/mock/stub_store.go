@@ -54,6 +54,20 @@ func (mr *MockStubStoreMockRecorder) Create(ctx interface{}, stubs ...interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStubStore)(nil).Create), varargs...)
 }
 
+// CreateCallbackResult mocks base method.
+func (m *MockStubStore) CreateCallbackResult(ctx context.Context, r *rio.CallbackResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCallbackResult", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCallbackResult indicates an expected call of CreateCallbackResult.
+func (mr *MockStubStoreMockRecorder) CreateCallbackResult(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCallbackResult", reflect.TypeOf((*MockStubStore)(nil).CreateCallbackResult), ctx, r)
+}
+
 // CreateIncomingRequest mocks base method.
 func (m *MockStubStore) CreateIncomingRequest(ctx context.Context, r *rio.IncomingRequest) error {
 	m.ctrl.T.Helper()
@@ -101,6 +115,21 @@ func (mr *MockStubStoreMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStubStore)(nil).Delete), ctx, id)
 }
 
+// Find mocks base method.
+func (m *MockStubStore) Find(ctx context.Context, id int64) (*rio.Stub, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", ctx, id)
+	ret0, _ := ret[0].(*rio.Stub)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockStubStoreMockRecorder) Find(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockStubStore)(nil).Find), ctx, id)
+}
+
 // GetAll mocks base method.
 func (m *MockStubStore) GetAll(ctx context.Context, namespace string) ([]*rio.Stub, error) {
 	m.ctrl.T.Helper()
@@ -116,6 +145,21 @@ func (mr *MockStubStoreMockRecorder) GetAll(ctx, namespace interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockStubStore)(nil).GetAll), ctx, namespace)
 }
 
+// GetCallbackResults mocks base method.
+func (m *MockStubStore) GetCallbackResults(ctx context.Context, option *rio.CallbackQueryOption) ([]*rio.CallbackResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCallbackResults", ctx, option)
+	ret0, _ := ret[0].([]*rio.CallbackResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCallbackResults indicates an expected call of GetCallbackResults.
+func (mr *MockStubStoreMockRecorder) GetCallbackResults(ctx, option interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCallbackResults", reflect.TypeOf((*MockStubStore)(nil).GetCallbackResults), ctx, option)
+}
+
 // GetIncomingRequests mocks base method.
 func (m *MockStubStore) GetIncomingRequests(ctx context.Context, option *rio.IncomingQueryOption) ([]*rio.IncomingRequest, error) {
 	m.ctrl.T.Helper()
@@ -146,6 +190,36 @@ func (mr *MockStubStoreMockRecorder) GetProtos(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProtos", reflect.TypeOf((*MockStubStore)(nil).GetProtos), ctx)
 }
 
+// GetScenarios mocks base method.
+func (m *MockStubStore) GetScenarios(ctx context.Context, option *rio.ScenarioQueryOption) ([]*rio.Scenario, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScenarios", ctx, option)
+	ret0, _ := ret[0].([]*rio.Scenario)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScenarios indicates an expected call of GetScenarios.
+func (mr *MockStubStoreMockRecorder) GetScenarios(ctx, option interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScenarios", reflect.TypeOf((*MockStubStore)(nil).GetScenarios), ctx, option)
+}
+
+// IncreaseStubCounter mocks base method.
+func (m *MockStubStore) IncreaseStubCounter(ctx context.Context, namespace string, stubID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseStubCounter", ctx, namespace, stubID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncreaseStubCounter indicates an expected call of IncreaseStubCounter.
+func (mr *MockStubStoreMockRecorder) IncreaseStubCounter(ctx, namespace, stubID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseStubCounter", reflect.TypeOf((*MockStubStore)(nil).IncreaseStubCounter), ctx, namespace, stubID)
+}
+
 // Reset mocks base method.
 func (m *MockStubStore) Reset(ctx context.Context, option *rio.ResetQueryOption) error {
 	m.ctrl.T.Helper()
@@ -160,6 +234,63 @@ func (mr *MockStubStoreMockRecorder) Reset(ctx, option interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockStubStore)(nil).Reset), ctx, option)
 }
 
+// ResetScenarios mocks base method.
+func (m *MockStubStore) ResetScenarios(ctx context.Context, option *rio.ScenarioQueryOption) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetScenarios", ctx, option)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetScenarios indicates an expected call of ResetScenarios.
+func (mr *MockStubStoreMockRecorder) ResetScenarios(ctx, option interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetScenarios", reflect.TypeOf((*MockStubStore)(nil).ResetScenarios), ctx, option)
+}
+
+// SetScenarioState mocks base method.
+func (m *MockStubStore) SetScenarioState(ctx context.Context, scenario *rio.Scenario) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetScenarioState", ctx, scenario)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetScenarioState indicates an expected call of SetScenarioState.
+func (mr *MockStubStoreMockRecorder) SetScenarioState(ctx, scenario interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetScenarioState", reflect.TypeOf((*MockStubStore)(nil).SetScenarioState), ctx, scenario)
+}
+
+// TransitScenarioState mocks base method.
+func (m *MockStubStore) TransitScenarioState(ctx context.Context, namespace, name, currentState, newState string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitScenarioState", ctx, namespace, name, currentState, newState)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitScenarioState indicates an expected call of TransitScenarioState.
+func (mr *MockStubStoreMockRecorder) TransitScenarioState(ctx, namespace, name, currentState, newState interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitScenarioState", reflect.TypeOf((*MockStubStore)(nil).TransitScenarioState), ctx, namespace, name, currentState, newState)
+}
+
+// Update mocks base method.
+func (m *MockStubStore) Update(ctx context.Context, stub *rio.Stub) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, stub)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStubStoreMockRecorder) Update(ctx, stub interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStubStore)(nil).Update), ctx, stub)
+}
+
 // MockStatusStore is a mock of StatusStore interface.
 type MockStatusStore struct {
 	ctrl     *gomock.Controller
@@ -0,0 +1,361 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// Defines placeholders which can be used as values in the expected document of json_equal operator
+const (
+	PlaceholderAny    = "${any}"
+	PlaceholderUUID   = "${uuid}"
+	PlaceholderString = "${string}"
+	PlaceholderNumber = "${number}"
+	PlaceholderBool   = "${bool}"
+	PlaceholderDate   = "${date}"
+)
+
+// JSONEqualValue is the value of json_equal operator
+type JSONEqualValue struct {
+	// Expected is the expected json document which is compared with the whole value at the key path
+	// String values can be placeholders, for example: ${any}, ${uuid}, ${string}, ${number}, ${bool} or ${date}
+	Expected interface{} `json:"expected" yaml:"expected"`
+
+	// IgnoreExtraFields allows actual objects to have fields which are not defined in expected document
+	IgnoreExtraFields bool `json:"ignore_extra_fields,omitempty" yaml:"ignore_extra_fields"`
+
+	// IgnoreArrayOrder compares arrays regardless of the order of elements
+	IgnoreArrayOrder bool `json:"ignore_array_order,omitempty" yaml:"ignore_array_order"`
+
+	// IgnorePaths are paths which are not compared. Paths are relative to the compared value
+	// For example: $.id, $.items[*].created_at or $.items[0]
+	IgnorePaths []string `json:"ignore_paths,omitempty" yaml:"ignore_paths"`
+}
+
+// JSONEqualOption is function to modify json_equal operator
+type JSONEqualOption func(v *JSONEqualValue)
+
+// IgnoreExtraFields allows actual objects to have fields which are not defined in expected document
+func IgnoreExtraFields() JSONEqualOption {
+	return func(v *JSONEqualValue) {
+		v.IgnoreExtraFields = true
+	}
+}
+
+// IgnoreArrayOrder compares arrays regardless of the order of elements
+func IgnoreArrayOrder() JSONEqualOption {
+	return func(v *JSONEqualValue) {
+		v.IgnoreArrayOrder = true
+	}
+}
+
+// IgnorePaths ignores the given paths when comparing. For example: $.id, $.items[*].created_at
+func IgnorePaths(paths ...string) JSONEqualOption {
+	return func(v *JSONEqualValue) {
+		v.IgnorePaths = append(v.IgnorePaths, paths...)
+	}
+}
+
+// getJSONEqualValue converts operator value to JSONEqualValue
+func getJSONEqualValue(value interface{}) (*JSONEqualValue, error) {
+	switch v := value.(type) {
+	case *JSONEqualValue:
+		return v, nil
+	case JSONEqualValue:
+		return &v, nil
+	}
+
+	v := JSONEqualValue{}
+	if err := decodeOperatorValue(value, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// decodeOperatorValue decodes a structured operator value to target
+// Value is a map if the stub is decoded from JSON or YAML
+func decodeOperatorValue(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// normalizeJSON converts value to the generic json types, numbers are decoded as json.Number
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func validateJSONEqualValue(value interface{}) error {
+	v, err := getJSONEqualValue(value)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range v.IgnorePaths {
+		if !strings.HasPrefix(p, "$") {
+			return fmt.Errorf("ignore path must start with $: %s", p)
+		}
+	}
+
+	return nil
+}
+
+func executeJSONEqualOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	v, err := getJSONEqualValue(op.Value)
+	if err != nil {
+		log.Error(ctx, "invalid json_equal value", err)
+		return false, err
+	}
+
+	expected, err := normalizeJSON(v.Expected)
+	if err != nil {
+		log.Error(ctx, "cannot normalize expected value", err)
+		return false, err
+	}
+
+	actual, err := normalizeJSON(value)
+	if err != nil {
+		log.Error(ctx, "cannot normalize actual value", err)
+		return false, err
+	}
+
+	c := &jsonComparator{option: v, ignorePaths: make([][]string, len(v.IgnorePaths))}
+	for i, p := range v.IgnorePaths {
+		c.ignorePaths[i] = splitJSONPath(p)
+	}
+
+	return c.equal([]string{"$"}, expected, actual), nil
+}
+
+type jsonComparator struct {
+	option      *JSONEqualValue
+	ignorePaths [][]string
+}
+
+func (c *jsonComparator) equal(path []string, expected interface{}, actual interface{}) bool {
+	if c.isIgnored(path) {
+		return true
+	}
+
+	if s, ok := expected.(string); ok && isPlaceholder(s) {
+		return matchPlaceholder(s, actual)
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		return ok && c.equalObject(path, e, a)
+
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		return ok && c.equalArray(path, e, a)
+
+	case json.Number:
+		cmp, ok := compareNumbers(actual, e)
+		_, isNumber := actual.(json.Number)
+		return ok && isNumber && cmp == 0
+
+	default:
+		return expected == actual
+	}
+}
+
+func (c *jsonComparator) equalObject(path []string, expected map[string]interface{}, actual map[string]interface{}) bool {
+	for key, e := range expected {
+		fieldPath := appendPath(path, key)
+		a, ok := actual[key]
+		if !ok {
+			// Missing field is accepted only if it is ignored
+			if !c.isIgnored(fieldPath) {
+				return false
+			}
+
+			continue
+		}
+
+		if !c.equal(fieldPath, e, a) {
+			return false
+		}
+	}
+
+	if c.option.IgnoreExtraFields {
+		return true
+	}
+
+	for key := range actual {
+		if _, ok := expected[key]; !ok && !c.isIgnored(appendPath(path, key)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *jsonComparator) equalArray(path []string, expected []interface{}, actual []interface{}) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+
+	if !c.option.IgnoreArrayOrder {
+		for i := range expected {
+			if !c.equal(appendPath(path, "["+strconv.Itoa(i)+"]"), expected[i], actual[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return c.matchUnordered(path, expected, actual, 0, make([]bool, len(actual)))
+}
+
+// matchUnordered finds a distinct actual element for each expected element with backtracking
+// Element index in path is the index of actual element
+func (c *jsonComparator) matchUnordered(path []string, expected []interface{}, actual []interface{}, i int, used []bool) bool {
+	if i == len(expected) {
+		return true
+	}
+
+	for j := range actual {
+		if used[j] || !c.equal(appendPath(path, "["+strconv.Itoa(j)+"]"), expected[i], actual[j]) {
+			continue
+		}
+
+		used[j] = true
+		if c.matchUnordered(path, expected, actual, i+1, used) {
+			return true
+		}
+		used[j] = false
+	}
+
+	return false
+}
+
+func (c *jsonComparator) isIgnored(path []string) bool {
+	for _, ignorePath := range c.ignorePaths {
+		if matchIgnorePath(ignorePath, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchIgnorePath returns true if path is the ignored path or its descendant
+func matchIgnorePath(ignorePath []string, path []string) bool {
+	if len(path) < len(ignorePath) {
+		return false
+	}
+
+	for i, segment := range ignorePath {
+		if segment == path[i] {
+			continue
+		}
+
+		if segment == "[*]" && strings.HasPrefix(path[i], "[") {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// splitJSONPath splits a simple json path to segments. For example: $.items[0].id => [$, items, [0], id]
+func splitJSONPath(p string) []string {
+	segments := []string{}
+	for _, part := range strings.Split(p, ".") {
+		for len(part) > 0 {
+			idx := strings.Index(part, "[")
+			if idx < 0 {
+				segments = append(segments, part)
+				break
+			}
+
+			if idx > 0 {
+				segments = append(segments, part[:idx])
+			}
+
+			end := strings.Index(part, "]")
+			if end < idx {
+				segments = append(segments, part[idx:])
+				break
+			}
+
+			segments = append(segments, part[idx:end+1])
+			part = part[end+1:]
+		}
+	}
+
+	return segments
+}
+
+func appendPath(path []string, segment string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, segment)
+}
+
+func isPlaceholder(s string) bool {
+	switch s {
+	case PlaceholderAny, PlaceholderUUID, PlaceholderString, PlaceholderNumber, PlaceholderBool, PlaceholderDate:
+		return true
+	}
+
+	return false
+}
+
+func matchPlaceholder(placeholder string, actual interface{}) bool {
+	switch placeholder {
+	case PlaceholderAny:
+		return true
+	case PlaceholderUUID:
+		s, ok := actual.(string)
+		if !ok {
+			return false
+		}
+
+		_, err := uuid.Parse(s)
+		return err == nil
+	case PlaceholderString:
+		_, ok := actual.(string)
+		return ok
+	case PlaceholderNumber:
+		_, ok := actual.(json.Number)
+		return ok
+	case PlaceholderBool:
+		_, ok := actual.(bool)
+		return ok
+	case PlaceholderDate:
+		_, ok := getTime(actual)
+		return ok
+	}
+
+	return false
+}
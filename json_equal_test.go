@@ -0,0 +1,136 @@
+package rio
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExecuteJSONEqualOperator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	actual := map[string]interface{}{
+		"id":         uuid.NewString(),
+		"name":       "cat",
+		"age":        json.Number("2"),
+		"vaccinated": true,
+		"created_at": "2023-01-01T00:00:00Z",
+		"tags":       []interface{}{"pet", "mammal"},
+		"owners": []interface{}{
+			map[string]interface{}{"id": json.Number("1"), "name": "A"},
+			map[string]interface{}{"id": json.Number("2"), "name": "B"},
+		},
+	}
+
+	expected := types.Map{
+		"id":         uuid.NewString(),
+		"name":       "cat",
+		"age":        2.0,
+		"vaccinated": true,
+		"created_at": "2023-01-01T00:00:00Z",
+		"tags":       []string{"pet", "mammal"},
+		"owners": []types.Map{
+			{"id": 1, "name": "A"},
+			{"id": 2, "name": "B"},
+		},
+	}
+
+	testCases := []struct {
+		Name           string
+		Operator       CreateOperator
+		ActualValue    interface{}
+		ExpectedResult bool
+	}{
+		{"different_id", JSONEqual(expected), actual, false},
+		{"ignore_id", JSONEqual(expected, IgnorePaths("$.id")), actual, true},
+		{"placeholders", JSONEqual(types.Map{
+			"id":         PlaceholderUUID,
+			"name":       PlaceholderString,
+			"age":        PlaceholderNumber,
+			"vaccinated": PlaceholderBool,
+			"created_at": PlaceholderDate,
+			"tags":       PlaceholderAny,
+			"owners":     PlaceholderAny,
+		}), actual, true},
+		{"placeholder_unmatched", JSONEqual(types.Map{"name": PlaceholderNumber}, IgnoreExtraFields()), actual, false},
+		{"missing_field", JSONEqual(types.Map{"name": "cat"}), actual, false},
+		{"ignore_extra_fields", JSONEqual(types.Map{"name": "cat", "owners": []types.Map{{"id": 1}, {"id": 2}}}, IgnoreExtraFields()), actual, true},
+		{"expected_field_not_found", JSONEqual(types.Map{"color": "black"}, IgnoreExtraFields()), actual, false},
+		{"array_order", JSONEqual(types.Map{"tags": []string{"mammal", "pet"}}, IgnoreExtraFields()), actual, false},
+		{"ignore_array_order", JSONEqual(types.Map{"tags": []string{"mammal", "pet"}}, IgnoreExtraFields(), IgnoreArrayOrder()), actual, true},
+		{"ignore_array_order_length", JSONEqual(types.Map{"tags": []string{"pet"}}, IgnoreExtraFields(), IgnoreArrayOrder()), actual, false},
+		{"ignore_array_order_placeholder", JSONEqual([]interface{}{PlaceholderAny, "pet"}, IgnoreArrayOrder()), []interface{}{"pet", "mammal"}, true},
+		{"ignore_wildcard_path", JSONEqual(types.Map{"owners": []types.Map{{"id": 10, "name": "A"}, {"id": 20, "name": "B"}}}, IgnoreExtraFields(), IgnorePaths("$.owners[*].id")), actual, true},
+		{"ignore_index_path", JSONEqual(types.Map{"owners": []types.Map{{"id": 10, "name": "A"}, {"id": 2, "name": "B"}}}, IgnoreExtraFields(), IgnorePaths("$.owners[0]")), actual, true},
+		{"subtree", JSONEqual(types.Map{"id": 2, "name": "B"}), map[string]interface{}{"id": json.Number("2"), "name": "B"}, true},
+		{"number_precision", JSONEqual(9007199254740993), json.Number("9007199254740992"), false},
+		{"number_not_string", JSONEqual(1), "1", false},
+		{"null", JSONEqual(types.Map{"name": nil}), map[string]interface{}{"name": nil}, true},
+	}
+
+	for _, tc := range testCases {
+		matched, err := Match(ctx, tc.Operator(), tc.ActualValue)
+		require.NoError(t, err, tc.Name)
+		require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+	}
+}
+
+func TestJSONEqualOperator_Decode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	yamlData := `
+name: json_equal
+value:
+  expected:
+    id: ${uuid}
+    items:
+      - sku: B
+        price: 20
+      - sku: A
+        price: 10.5
+  ignore_extra_fields: true
+  ignore_array_order: true
+  ignore_paths:
+    - $.items[*].price
+`
+	op := Operator{}
+	require.NoError(t, yaml.Unmarshal([]byte(yamlData), &op))
+	require.NoError(t, validateOp(ctx, op))
+
+	actual := types.Map{
+		"id":    uuid.NewString(),
+		"note":  "extra",
+		"items": []types.Map{{"sku": "A", "price": 11}, {"sku": "B", "price": 20}},
+	}
+
+	matched, err := Match(ctx, op, actual)
+	require.NoError(t, err)
+	require.True(t, matched)
+
+	data, err := json.Marshal(JSONEqual(types.Map{"id": 1}, IgnorePaths("$.id"))())
+	require.NoError(t, err)
+
+	fromJSON := Operator{}
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	require.NoError(t, validateOp(ctx, fromJSON))
+
+	matched, err = Match(ctx, fromJSON, types.Map{"id": 2})
+	require.NoError(t, err)
+	require.True(t, matched)
+}
+
+func TestSplitJSONPath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"$"}, splitJSONPath("$"))
+	require.Equal(t, []string{"$", "id"}, splitJSONPath("$.id"))
+	require.Equal(t, []string{"$", "items", "[*]", "price"}, splitJSONPath("$.items[*].price"))
+	require.Equal(t, []string{"$", "matrix", "[0]", "[1]"}, splitJSONPath("$.matrix[0][1]"))
+}
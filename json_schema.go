@@ -0,0 +1,279 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hungdv136/rio/internal/log"
+	fs "github.com/hungdv136/rio/internal/storage"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// The schema is compiled as an in-memory resource with this url
+const jsonSchemaURL = "rio://schema.json"
+
+// JSONSchemaValue is the value of json_schema operator. Either schema or file id is required
+type JSONSchemaValue struct {
+	// Schema is the inline json schema
+	Schema interface{} `json:"schema,omitempty" yaml:"schema"`
+
+	// FileID is the id of uploaded schema file in file storage
+	FileID string `json:"file_id,omitempty" yaml:"file_id"`
+
+	// compiled caches the compiled schema for the lifetime of the value
+	compiled *compiledJSONSchema
+}
+
+type compiledJSONSchema struct {
+	schema *jsonschema.Schema
+	l      sync.Mutex
+}
+
+func newJSONSchemaValue(schema interface{}, fileID string) *JSONSchemaValue {
+	return &JSONSchemaValue{Schema: schema, FileID: fileID, compiled: &compiledJSONSchema{}}
+}
+
+type fileStorageContextKey struct{}
+
+// ContextWithFileStorage returns a new context which carries file storage
+// File storage is used to load the schema file of json_schema operator
+func ContextWithFileStorage(ctx context.Context, fileStorage fs.FileStorage) context.Context {
+	return context.WithValue(ctx, fileStorageContextKey{}, fileStorage)
+}
+
+func fileStorageFromContext(ctx context.Context) fs.FileStorage {
+	fileStorage, _ := ctx.Value(fileStorageContextKey{}).(fs.FileStorage)
+	return fileStorage
+}
+
+// getJSONSchemaValue converts operator value to JSONSchemaValue
+func getJSONSchemaValue(value interface{}) (*JSONSchemaValue, error) {
+	switch v := value.(type) {
+	case *JSONSchemaValue:
+		return v, nil
+	case JSONSchemaValue:
+		return &v, nil
+	}
+
+	v := JSONSchemaValue{}
+	if err := decodeOperatorValue(value, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func validateJSONSchemaValue(ctx context.Context, value interface{}) error {
+	v, err := getJSONSchemaValue(value)
+	if err != nil {
+		return err
+	}
+
+	if (v.Schema == nil) == (len(v.FileID) == 0) {
+		return errors.New("either schema or file_id is required")
+	}
+
+	// Schema file is compiled when it is used since file storage is not available here
+	if v.Schema != nil {
+		if _, err := v.compile(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func executeJSONSchemaOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	schema, actual, err := prepareJSONSchema(ctx, op, value)
+	if err != nil {
+		return false, err
+	}
+
+	return schema.Validate(actual) == nil, nil
+}
+
+// explainJSONSchema returns validation errors of the value, which is reported in diagnostics
+func explainJSONSchema(ctx context.Context, op Operator, value interface{}) string {
+	schema, actual, err := prepareJSONSchema(ctx, op, value)
+	if err != nil {
+		return err.Error()
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(schema.Validate(actual), &validationErr) {
+		return ""
+	}
+
+	messages := []string{}
+	var collect func(e *jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			messages = append(messages, fmt.Sprintf("%s: %s", jsonPointerToPath(e.InstanceLocation), e.Message))
+			return
+		}
+
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+	}
+
+	collect(validationErr)
+	return strings.Join(messages, "; ")
+}
+
+// prepareJSONSchema returns the compiled schema and the normalized value to be validated
+func prepareJSONSchema(ctx context.Context, op Operator, value interface{}) (*jsonschema.Schema, interface{}, error) {
+	v, err := getJSONSchemaValue(op.Value)
+	if err != nil {
+		log.Error(ctx, "invalid json_schema value", err)
+		return nil, nil, err
+	}
+
+	schema, err := v.compile(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actual, err := normalizeJSON(value)
+	if err != nil {
+		log.Error(ctx, "cannot normalize actual value", err)
+		return nil, nil, err
+	}
+
+	return schema, actual, nil
+}
+
+// jsonPointerToPath converts instance location to json path. For example: /items/0/id => $.items.0.id
+func jsonPointerToPath(pointer string) string {
+	return "$" + strings.ReplaceAll(pointer, "/", ".")
+}
+
+// compile returns the cached schema or compiles it if it is not compiled yet
+// The schema of a value which is not created by JSONSchema, JSONSchemaFile or loaded with stub is compiled every time
+func (v *JSONSchemaValue) compile(ctx context.Context) (*jsonschema.Schema, error) {
+	if v.compiled == nil {
+		return compileJSONSchema(ctx, v)
+	}
+
+	v.compiled.l.Lock()
+	defer v.compiled.l.Unlock()
+
+	if v.compiled.schema != nil {
+		return v.compiled.schema, nil
+	}
+
+	s, err := compileJSONSchema(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	v.compiled.schema = s
+	return s, nil
+}
+
+func compileJSONSchema(ctx context.Context, v *JSONSchemaValue) (*jsonschema.Schema, error) {
+	data, err := loadJSONSchema(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("external schema reference is not supported: %s", s)
+	}
+
+	if err := compiler.AddResource(jsonSchemaURL, bytes.NewReader(data)); err != nil {
+		log.Error(ctx, "cannot parse json schema", err)
+		return nil, err
+	}
+
+	s, err := compiler.Compile(jsonSchemaURL)
+	if err != nil {
+		log.Error(ctx, "cannot compile json schema", err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// loadJSONSchema returns the inline schema or downloads the schema file from file storage
+func loadJSONSchema(ctx context.Context, v *JSONSchemaValue) ([]byte, error) {
+	if len(v.FileID) == 0 {
+		return json.Marshal(v.Schema)
+	}
+
+	fileStorage := fileStorageFromContext(ctx)
+	if fileStorage == nil {
+		err := fmt.Errorf("file storage is required to load schema file %s", v.FileID)
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	reader, err := fileStorage.DownloadFile(ctx, v.FileID)
+	if err != nil {
+		log.Error(ctx, "cannot download schema file", v.FileID, err)
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		log.Error(ctx, "cannot read schema file", v.FileID, err)
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// loadJSONSchemas returns a copy of request matching whose json_schema operators cache the compiled schema
+// This is called when a stub is loaded, so the schema is compiled once for the lifetime of the loaded stub
+// The updated stub is loaded again, which compiles its schemas again
+func loadJSONSchemas(r *RequestMatching) *RequestMatching {
+	if r == nil {
+		return nil
+	}
+
+	loaded := *r
+	loaded.URL = loadJSONSchemaOperators(r.URL, func(op *Operator) *Operator { return op })
+	loaded.Host = loadJSONSchemaOperators(r.Host, func(op *Operator) *Operator { return op })
+	loaded.Port = loadJSONSchemaOperators(r.Port, func(op *Operator) *Operator { return op })
+	loaded.Header = loadJSONSchemaOperators(r.Header, func(op *FieldOperator) *Operator { return &op.Operator })
+	loaded.Cookie = loadJSONSchemaOperators(r.Cookie, func(op *FieldOperator) *Operator { return &op.Operator })
+	loaded.Query = loadJSONSchemaOperators(r.Query, func(op *FieldOperator) *Operator { return &op.Operator })
+	loaded.Body = loadJSONSchemaOperators(r.Body, func(op *BodyOperator) *Operator { return &op.Operator })
+	loaded.GraphQL = loadJSONSchemaOperators(r.GraphQL, func(op *GraphQLOperator) *Operator { return &op.Operator })
+	loaded.Stream = loadJSONSchemaOperators(r.Stream, func(op *StreamOperator) *Operator { return &op.Operator })
+	return &loaded
+}
+
+// loadJSONSchemaOperators copies the operators, so that the loaded values are not shared with the original request matching
+func loadJSONSchemaOperators[T any](ops []T, getOperator func(*T) *Operator) []T {
+	if ops == nil {
+		return nil
+	}
+
+	loaded := make([]T, len(ops))
+	for i := range ops {
+		loaded[i] = ops[i]
+		op := getOperator(&loaded[i])
+		op.Operators = loadJSONSchemaOperators(op.Operators, func(op *Operator) *Operator { return op })
+
+		if op.Name != OpJSONSchema {
+			continue
+		}
+
+		// Invalid value is kept as it is, which is reported when it is matched
+		if v, err := getJSONSchemaValue(op.Value); err == nil && v.compiled == nil {
+			op.Value = newJSONSchemaValue(v.Schema, v.FileID)
+		}
+	}
+
+	return loaded
+}
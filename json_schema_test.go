@@ -0,0 +1,193 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	fs "github.com/hungdv136/rio/internal/storage"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var testAnimalSchema = types.Map{
+	"type":     "object",
+	"required": []string{"name", "age"},
+	"properties": types.Map{
+		"name": types.Map{"type": "string", "minLength": 1},
+		"age":  types.Map{"type": "integer", "minimum": 0},
+		"tags": types.Map{"type": "array", "items": types.Map{"type": "string"}},
+	},
+}
+
+func TestExecuteJSONSchemaOperator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testCases := []struct {
+		Name           string
+		ActualValue    interface{}
+		ExpectedResult bool
+	}{
+		{"valid", types.Map{"name": "cat", "age": 2, "tags": []string{"pet"}}, true},
+		{"missing_required", types.Map{"name": "cat"}, false},
+		{"invalid_type", types.Map{"name": "cat", "age": "2"}, false},
+		{"invalid_item", types.Map{"name": "cat", "age": 2, "tags": []int{1}}, false},
+		{"not_object", "cat", false},
+	}
+
+	for _, tc := range testCases {
+		matched, err := Match(ctx, JSONSchema(testAnimalSchema)(), tc.ActualValue)
+		require.NoError(t, err, tc.Name)
+		require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+	}
+
+	reason := explainJSONSchema(ctx, JSONSchema(testAnimalSchema)(), types.Map{"name": "", "age": -1})
+	require.Contains(t, reason, "$.name: length must be >= 1")
+	require.Contains(t, reason, "$.age: must be >= 0")
+
+	_, err := Match(ctx, JSONSchema(types.Map{"type": "unknown"})(), "cat")
+	require.Error(t, err)
+}
+
+func TestExecuteJSONSchemaOperator_File(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileStorage := fs.NewLocalStorage(fs.LocalStorageConfig{UseTempDir: true, StoragePath: "uploaded_files"})
+	fileID := uuid.NewString()
+	_, err := fileStorage.UploadFile(ctx, fileID, bytes.NewReader([]byte(testAnimalSchema.ForceJSON())))
+	require.NoError(t, err)
+
+	op := JSONSchemaFile(fileID)()
+	require.NoError(t, validateOp(ctx, op))
+
+	_, err = Match(ctx, op, types.Map{"name": "cat", "age": 2})
+	require.Error(t, err, "file storage is required")
+
+	ctx = ContextWithFileStorage(ctx, fileStorage)
+	matched, err := Match(ctx, op, types.Map{"name": "cat", "age": 2})
+	require.NoError(t, err)
+	require.True(t, matched)
+
+	// Compiled schema is cached, deleting file does not affect matching
+	require.NoError(t, fileStorage.DeleteFile(ctx, fileID))
+	matched, err = Match(ctx, op, types.Map{"name": "cat"})
+	require.NoError(t, err)
+	require.False(t, matched)
+
+	_, err = Match(ctx, JSONSchemaFile(uuid.NewString())(), types.Map{"name": "cat"})
+	require.Error(t, err)
+}
+
+func TestJSONSchemaValue_Compile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	v := newJSONSchemaValue(testAnimalSchema, "")
+	s, err := v.compile(ctx)
+	require.NoError(t, err)
+
+	cached, err := v.compile(ctx)
+	require.NoError(t, err)
+	require.Same(t, s, cached)
+
+	// The value which is not created by builders is compiled every time
+	other := &JSONSchemaValue{Schema: testAnimalSchema}
+	compiled, err := other.compile(ctx)
+	require.NoError(t, err)
+	require.NotSame(t, s, compiled)
+
+	_, err = newJSONSchemaValue(types.Map{"$ref": "file:///etc/passwd"}, "").compile(ctx)
+	require.Error(t, err)
+}
+
+func TestLoadJSONSchemas(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stub := NewStub().For("POST", Contains("animal")).
+		WithRequestBody(BodyJSONPath("$", Not(JSONSchema(testAnimalSchema)))).
+		WithHeader("X-SCHEMA", JSONSchema(testAnimalSchema))
+
+	data, err := json.Marshal(stub.Request)
+	require.NoError(t, err)
+
+	r := &RequestMatching{}
+	require.NoError(t, r.Scan(data))
+
+	bodySchema, ok := r.Body[0].Operator.Operators[0].Value.(*JSONSchemaValue)
+	require.True(t, ok)
+	require.NotNil(t, bodySchema.compiled)
+
+	headerSchema, ok := r.Header[0].Operator.Value.(*JSONSchemaValue)
+	require.True(t, ok)
+	require.NotNil(t, headerSchema.compiled)
+
+	// Compiled schema is cached in the loaded stub
+	matched, err := Match(ctx, r.Body[0].Operator, types.Map{"name": "cat", "age": 2})
+	require.NoError(t, err)
+	require.False(t, matched)
+	require.NotNil(t, bodySchema.compiled.schema)
+
+	// Loading does not modify the original request matching
+	loaded := loadJSONSchemas(r)
+	require.NotSame(t, &r.Body[0], &loaded.Body[0])
+	require.Same(t, bodySchema, loaded.Body[0].Operator.Operators[0].Value)
+}
+
+func TestJSONSchemaOperator_Decode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	yamlData := `
+name: json_schema
+value:
+  schema:
+    type: object
+    required: [id]
+    properties:
+      id:
+        type: string
+        format: uuid
+`
+	op := Operator{}
+	require.NoError(t, yaml.Unmarshal([]byte(yamlData), &op))
+	require.NoError(t, validateOp(ctx, op))
+
+	matched, err := Match(ctx, op, types.Map{"id": uuid.NewString()})
+	require.NoError(t, err)
+	require.True(t, matched)
+
+	matched, err = Match(ctx, op, types.Map{"id": "abc"})
+	require.NoError(t, err)
+	require.False(t, matched)
+}
+
+func TestDiagnoseHTTPRequest_JSONSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stub := NewStub().WithID(1).
+		For("POST", Contains("animal/create")).
+		WithRequestBody(BodyJSONPath("$", JSONSchema(testAnimalSchema)))
+
+	body := bytes.NewReader([]byte(`{"name": "cat"}`))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/animal/create", body)
+	require.NoError(t, err)
+	req.Header.Set(HeaderContentType, ContentTypeJSON)
+
+	matched, err := matchHTTPRequest(ctx, stub, req)
+	require.NoError(t, err)
+	require.False(t, matched)
+
+	diagnostics := diagnoseHTTPRequest(ctx, []*Stub{stub}, req)
+	require.Len(t, diagnostics, 1)
+	require.Len(t, diagnostics[0].Mismatches, 1)
+	require.Equal(t, OpJSONSchema, diagnostics[0].Mismatches[0].Operator)
+	require.Contains(t, diagnostics[0].Mismatches[0].Reason, "missing properties: 'age'")
+}
@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/PaesslerAG/jsonpath"
 	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/types"
 	"github.com/hungdv136/rio/internal/util"
 )
 
-var matchingFunctions = map[OperatorName]matchingFunc{
+var matchingFunctions = map[OperatorName]MatchingFunc{
 	OpEqualTo:       executeEqualToOperator,
 	OpContaining:    executeContainingOperator,
 	OpNotContaining: executeNotContainingOperator,
@@ -21,20 +24,204 @@ var matchingFunctions = map[OperatorName]matchingFunc{
 	OpLength:        executeLengthOperator,
 	OpEmpty:         executeEmptyOperator,
 	OpNotEmpty:      executeNotEmptyOperator,
+
+	OpGreaterThan:        compareNumberWith(func(c int) bool { return c > 0 }),
+	OpGreaterThanOrEqual: compareNumberWith(func(c int) bool { return c >= 0 }),
+	OpLessThan:           compareNumberWith(func(c int) bool { return c < 0 }),
+	OpLessThanOrEqual:    compareNumberWith(func(c int) bool { return c <= 0 }),
+	OpBetween:            executeBetweenOperator,
+	OpBefore:             compareTimeWith(func(actual, expected time.Time) bool { return actual.Before(expected) }),
+	OpAfter:              compareTimeWith(func(actual, expected time.Time) bool { return actual.After(expected) }),
+
+	OpJSONEqual:  executeJSONEqualOperator,
+	OpJSONSchema: executeJSONSchemaOperator,
+
+	OpContainsAll: executeContainsAllOperator,
+	OpHash:        executeHashOperator,
+}
+
+// Composite operators are registered in init to avoid initialization cycle since they call Match recursively
+func init() {
+	matchingFunctions[OpAnyOf] = executeAnyOfOperator
+	matchingFunctions[OpAllOf] = executeAllOfOperator
+	matchingFunctions[OpNot] = executeNotOperator
 }
 
-type matchingFunc func(ctx context.Context, op Operator, value interface{}) (bool, error)
+// MatchingFunc matches the actual value with the operator
+// The expected value is available in op.Value
+type MatchingFunc func(ctx context.Context, op Operator, value interface{}) (bool, error)
 
 // Match compares input value with predefined operator
 func Match(ctx context.Context, op Operator, value interface{}) (bool, error) {
-	matchFunc, ok := matchingFunctions[op.Name]
+	if matchFunc, ok := matchingFunctions[op.Name]; ok {
+		return matchFunc(ctx, op, value)
+	}
+
+	if o, ok := customOperators.get(op.Name); ok {
+		return o.match(ctx, op, value)
+	}
+
+	err := fmt.Errorf("unsupported operator %s", op.Name)
+	log.Error(ctx, err)
+	return false, err
+}
+
+// MatchStream matches messages of a grpc client stream with predefined stream operator
+func MatchStream(ctx context.Context, op StreamOperator, messages []types.Map) (bool, error) {
+	switch op.Scope {
+	case StreamScopeCount:
+		return Match(ctx, op.Operator, len(messages))
+
+	case StreamScopeFirst, StreamScopeLast:
+		var message types.Map
+		if len(messages) > 0 {
+			message = messages[0]
+			if op.Scope == StreamScopeLast {
+				message = messages[len(messages)-1]
+			}
+		}
+
+		return matchJSONPath(ctx, op.KeyPath, op.Operator, message)
+
+	case StreamScopeAny:
+		for _, message := range messages {
+			if matched, err := matchJSONPath(ctx, op.KeyPath, op.Operator, message); err != nil || matched {
+				return matched, err
+			}
+		}
+
+		return false, nil
+
+	case StreamScopeAll:
+		if len(messages) == 0 {
+			return false, nil
+		}
+
+		for _, message := range messages {
+			if matched, err := matchJSONPath(ctx, op.KeyPath, op.Operator, message); err != nil || !matched {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	err := fmt.Errorf("unsupported stream scope %s", op.Scope)
+	log.Error(ctx, err)
+	return false, err
+}
+
+// MatchValues matches values of a field (header, query or metadata) by the quantifier
+// If there is no value, any and all quantifiers apply operator for empty string, so that empty operator can be used
+func MatchValues(ctx context.Context, quantifier Quantifier, op Operator, values []string) (bool, error) {
+	switch quantifier {
+	case QuantifierFirst, QuantifierCount, QuantifierValues:
+		return Match(ctx, op, quantifiedValue(quantifier, values))
+
+	case QuantifierAny:
+		if len(values) == 0 {
+			return Match(ctx, op, "")
+		}
+
+		for _, val := range values {
+			if matched, err := Match(ctx, op, val); err != nil || matched {
+				return matched, err
+			}
+		}
+
+		return false, nil
+
+	case QuantifierAll:
+		if len(values) == 0 {
+			return Match(ctx, op, "")
+		}
+
+		for _, val := range values {
+			if matched, err := Match(ctx, op, val); err != nil || !matched {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	err := fmt.Errorf("unsupported quantifier %s", quantifier)
+	log.Error(ctx, err)
+	return false, err
+}
+
+// quantifiedValue returns the value which is matched with operator, this is also reported in diagnostics
+func quantifiedValue(quantifier Quantifier, values []string) interface{} {
+	switch quantifier {
+	case QuantifierFirst:
+		if len(values) == 0 {
+			return ""
+		}
+
+		return values[0]
+
+	case QuantifierCount:
+		return len(values)
+
+	case QuantifierValues:
+		if values == nil {
+			return []string{}
+		}
+
+		return values
+	}
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values
+}
+
+func executeContainsAllOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	expected, ok := getSlice(op.Value)
 	if !ok {
-		err := fmt.Errorf("unsupported operator %s", op.Name)
+		err := fmt.Errorf("unsupported data type %s", op.String())
 		log.Error(ctx, err)
 		return false, err
 	}
 
-	return matchFunc(ctx, op, value)
+	for _, e := range expected {
+		ok, found := containsElement(value, e)
+		if !ok {
+			err := fmt.Errorf("unsupported data type - %T", value)
+			log.Error(ctx, err)
+			return false, err
+		}
+
+		if !found {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchJSONPath(ctx context.Context, keyPath string, op Operator, data map[string]interface{}) (bool, error) {
+	val, err := getJSONPath(ctx, keyPath, data)
+	if err != nil {
+		return false, err
+	}
+
+	return Match(ctx, op, val)
+}
+
+// getJSONPath returns nil if the key is not found
+func getJSONPath(ctx context.Context, keyPath string, data map[string]interface{}) (interface{}, error) {
+	val, err := jsonpath.Get(keyPath, data)
+	if err != nil {
+		if !strings.Contains(err.Error(), "unknown key") {
+			log.Error(ctx, "error when executing json path", err)
+			return nil, err
+		}
+	}
+
+	return val, nil
 }
 
 func executeEqualToOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
@@ -174,3 +361,97 @@ func executeEmptyOperator(_ context.Context, _ Operator, value interface{}) (boo
 func executeNotEmptyOperator(_ context.Context, _ Operator, value interface{}) (bool, error) {
 	return !isEmpty(value), nil
 }
+
+// compareNumberWith returns a matching function which compares actual number with expected number
+// Actual value which is not a number is considered as unmatched
+func compareNumberWith(isMatched func(c int) bool) MatchingFunc {
+	return func(ctx context.Context, op Operator, value interface{}) (bool, error) {
+		if _, ok := getFloat64(op.Value); !ok {
+			err := fmt.Errorf("unsupported data type %s", op.String())
+			log.Error(ctx, err)
+			return false, err
+		}
+
+		c, ok := compareNumbers(value, op.Value)
+		return ok && isMatched(c), nil
+	}
+}
+
+func executeBetweenOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	lower, upper, ok := getRange(op.Value)
+	if !ok {
+		err := fmt.Errorf("unsupported data type %s", op.String())
+		log.Error(ctx, err)
+		return false, err
+	}
+
+	if c, ok := compareNumbers(value, lower); !ok || c < 0 {
+		return false, nil
+	}
+
+	c, _ := compareNumbers(value, upper)
+	return c <= 0, nil
+}
+
+// compareTimeWith returns a matching function which compares actual date with expected date
+// Actual value which is not a RFC3339 date is considered as unmatched
+func compareTimeWith(isMatched func(actual, expected time.Time) bool) MatchingFunc {
+	return func(ctx context.Context, op Operator, value interface{}) (bool, error) {
+		s, ok := op.Value.(string)
+		if !ok {
+			err := fmt.Errorf("unsupported data type %s", op.String())
+			log.Error(ctx, err)
+			return false, err
+		}
+
+		expected, err := parseTimeExpression(s, time.Now())
+		if err != nil {
+			log.Error(ctx, err)
+			return false, err
+		}
+
+		actual, ok := getTime(value)
+		return ok && isMatched(actual, expected), nil
+	}
+}
+
+func executeAnyOfOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	for _, nested := range op.Operators {
+		matched, err := Match(ctx, nested, value)
+		if err != nil {
+			return false, err
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func executeAllOfOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	for _, nested := range op.Operators {
+		matched, err := Match(ctx, nested, value)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return len(op.Operators) > 0, nil
+}
+
+func executeNotOperator(ctx context.Context, op Operator, value interface{}) (bool, error) {
+	if len(op.Operators) != 1 {
+		err := fmt.Errorf("operator %s requires exactly one nested operator", op.Name)
+		log.Error(ctx, err)
+		return false, err
+	}
+
+	matched, err := Match(ctx, op.Operators[0], value)
+	if err != nil {
+		return false, err
+	}
+
+	return !matched, nil
+}
@@ -2,7 +2,9 @@ package rio
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/hungdv136/rio/internal/types"
 	"github.com/stretchr/testify/require"
@@ -275,3 +277,199 @@ func Benchmark_ExecuteRegexOperator(b *testing.B) {
 	require.NoError(b, err)
 	require.True(b, matched)
 }
+
+func TestExecuteNumericComparisonOperators(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testCases := []struct {
+		Name             string
+		Operator         CreateOperator
+		ActualValue      interface{}
+		ExpectedResult   bool
+		ExpectedHasError bool
+	}{
+		{"gt_int", GreaterThan(1000), 1001, true, false},
+		{"gt_equal", GreaterThan(1000), 1000, false, false},
+		{"gt_json_number", GreaterThan(1000), json.Number("1000.5"), true, false},
+		{"gt_numeric_string", GreaterThan(1000), "999", false, false},
+		{"gt_large_int", GreaterThan(int64(9007199254740992)), json.Number("9007199254740993"), true, false},
+		{"gte_equal", GreaterThanOrEqual(10.5), 10.5, true, false},
+		{"lt_float", LessThan(json.Number("0.5")), 0.25, true, false},
+		{"lt_not_number", LessThan(10), "abc", false, false},
+		{"lte_equal", LessThanOrEqual(10), int64(10), true, false},
+		{"lte_nil", LessThanOrEqual(10), nil, false, false},
+		{"between_in_range", Between(1, 10), 10, true, false},
+		{"between_lower", Between(1, 10), 1, true, false},
+		{"between_out_of_range", Between(1, 10), 10.1, false, false},
+		{"invalid_expected_value", GreaterThan("abc"), 10, false, true},
+		{"invalid_range", Between(1, "abc"), 10, false, true},
+	}
+
+	for _, tc := range testCases {
+		matched, err := Match(ctx, tc.Operator(), tc.ActualValue)
+		if tc.ExpectedHasError {
+			require.Error(t, err, tc.Name)
+		} else {
+			require.NoError(t, err, tc.Name)
+			require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+		}
+	}
+}
+
+func TestExecuteDateComparisonOperators(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	testCases := []struct {
+		Name             string
+		Operator         CreateOperator
+		ActualValue      interface{}
+		ExpectedResult   bool
+		ExpectedHasError bool
+	}{
+		{"before_now", Before("now"), now.Add(-time.Minute).Format(time.RFC3339), true, false},
+		{"before_relative", Before("now-1h"), now.Add(-30 * time.Minute).Format(time.RFC3339), false, false},
+		{"before_absolute", Before("2023-01-01T00:00:00Z"), "2022-12-31T23:59:59+07:00", true, false},
+		{"after_relative_days", After("now-7d"), now.AddDate(0, 0, -6).Format(time.RFC3339), true, false},
+		{"after_future", After("now+1h"), now.Format(time.RFC3339Nano), false, false},
+		{"after_not_date", After("now"), "tomorrow", false, false},
+		{"after_number", After("now"), 12, false, false},
+		{"invalid_expected_value", Before("yesterday"), now.Format(time.RFC3339), false, true},
+	}
+
+	for _, tc := range testCases {
+		matched, err := Match(ctx, tc.Operator(), tc.ActualValue)
+		if tc.ExpectedHasError {
+			require.Error(t, err, tc.Name)
+		} else {
+			require.NoError(t, err, tc.Name)
+			require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+		}
+	}
+}
+
+func TestParseTimeExpression(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		Expression       string
+		ExpectedTime     time.Time
+		ExpectedHasError bool
+	}{
+		{"now", now, false},
+		{"now-1h30m", now.Add(-90 * time.Minute), false},
+		{"now+30s", now.Add(30 * time.Second), false},
+		{"now-7d", now.AddDate(0, 0, -7), false},
+		{"2023-01-01T10:00:00+07:00", time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC), false},
+		{"now1h", time.Time{}, true},
+		{"now-xd", time.Time{}, true},
+		{"2023-01-01", time.Time{}, true},
+	}
+
+	for _, tc := range testCases {
+		actual, err := parseTimeExpression(tc.Expression, now)
+		if tc.ExpectedHasError {
+			require.Error(t, err, tc.Expression)
+		} else {
+			require.NoError(t, err, tc.Expression)
+			require.True(t, tc.ExpectedTime.Equal(actual), tc.Expression)
+		}
+	}
+}
+
+func TestExecuteCompositeOperators(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testCases := []struct {
+		Name             string
+		Operator         CreateOperator
+		ActualValue      interface{}
+		ExpectedResult   bool
+		ExpectedHasError bool
+	}{
+		{"any_of_matched", AnyOf(EqualTo("A"), EqualTo("B")), "B", true, false},
+		{"any_of_unmatched", AnyOf(EqualTo("A"), EqualTo("B")), "C", false, false},
+		{"all_of_matched", AllOf(StartWith("/api"), NotContains("admin")), "/api/animal", true, false},
+		{"all_of_unmatched", AllOf(StartWith("/api"), NotContains("admin")), "/api/admin", false, false},
+		{"not_matched", Not(StartWith("/internal")), "/api/animal", true, false},
+		{"not_unmatched", Not(StartWith("/internal")), "/internal/animal", false, false},
+		{"nested", AnyOf(AllOf(GreaterThan(10), LessThan(20)), EqualTo(0)), 0, true, false},
+		{"nested_not", Not(AnyOf(Empty(), EqualTo("unknown"))), "cat", true, false},
+		{"nested_error", AnyOf(Regex("cat")), 10, false, true},
+		{"not_without_operator", func() Operator { return Operator{Name: OpNot} }, "cat", false, true},
+	}
+
+	for _, tc := range testCases {
+		matched, err := Match(ctx, tc.Operator(), tc.ActualValue)
+		if tc.ExpectedHasError {
+			require.Error(t, err, tc.Name)
+		} else {
+			require.NoError(t, err, tc.Name)
+			require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+		}
+	}
+}
+
+func TestMatchValues(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	values := []string{"cat", "dog"}
+	testCases := []struct {
+		Name           string
+		Quantifier     Quantifier
+		Operator       CreateOperator
+		Values         []string
+		ExpectedResult bool
+	}{
+		{"first_matched", QuantifierFirst, EqualTo("cat"), values, true},
+		{"first_unmatched", QuantifierFirst, EqualTo("dog"), values, false},
+		{"first_empty", QuantifierFirst, Empty(), nil, true},
+		{"any_matched", QuantifierAny, EqualTo("dog"), values, true},
+		{"any_unmatched", QuantifierAny, EqualTo("bird"), values, false},
+		{"any_empty", QuantifierAny, Empty(), nil, true},
+		{"all_matched", QuantifierAll, Regex("^[a-z]{3}$"), values, true},
+		{"all_unmatched", QuantifierAll, EqualTo("cat"), values, false},
+		{"all_empty", QuantifierAll, NotEmpty(), nil, false},
+		{"count_matched", QuantifierCount, GreaterThanOrEqual(2), values, true},
+		{"count_unmatched", QuantifierCount, EqualTo(1), values, false},
+		{"count_empty", QuantifierCount, EqualTo(0), nil, true},
+		{"values_contains_all", QuantifierValues, ContainsAll("dog", "cat"), values, true},
+		{"values_contains_all_unmatched", QuantifierValues, ContainsAll("dog", "bird"), values, false},
+		{"values_contains", QuantifierValues, Contains("dog"), values, true},
+		{"values_empty", QuantifierValues, Empty(), nil, true},
+	}
+
+	for _, tc := range testCases {
+		matched, err := MatchValues(ctx, tc.Quantifier, tc.Operator(), tc.Values)
+		require.NoError(t, err, tc.Name)
+		require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+	}
+
+	_, err := MatchValues(ctx, "some", EqualTo("cat")(), values)
+	require.Error(t, err)
+}
+
+func TestExecuteContainsAllOperator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	matched, err := Match(ctx, ContainsAll("a", "c")(), []string{"a", "b", "c"})
+	require.NoError(t, err)
+	require.True(t, matched)
+
+	matched, err = Match(ctx, ContainsAll("cat", "dog")(), "cat and dog")
+	require.NoError(t, err)
+	require.True(t, matched)
+
+	matched, err = Match(ctx, Operator{Name: OpContainsAll, Value: []interface{}{"a", "d"}}, []interface{}{"a", "b"})
+	require.NoError(t, err)
+	require.False(t, matched)
+
+	_, err = Match(ctx, ContainsAll("a")(), 10)
+	require.Error(t, err)
+}
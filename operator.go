@@ -3,6 +3,8 @@ package rio
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/hungdv136/rio/internal/log"
 )
@@ -17,6 +19,20 @@ var AllSupportedOperators = []OperatorName{
 	OpLength,
 	OpEmpty,
 	OpNotEmpty,
+	OpGreaterThan,
+	OpGreaterThanOrEqual,
+	OpLessThan,
+	OpLessThanOrEqual,
+	OpBetween,
+	OpBefore,
+	OpAfter,
+	OpAnyOf,
+	OpAllOf,
+	OpNot,
+	OpJSONEqual,
+	OpJSONSchema,
+	OpContainsAll,
+	OpHash,
 }
 
 // Defines common operator name
@@ -31,6 +47,24 @@ const (
 	OpLength        OperatorName = "length"
 	OpEmpty         OperatorName = "empty"
 	OpNotEmpty      OperatorName = "not_empty"
+
+	OpGreaterThan        OperatorName = "gt"
+	OpGreaterThanOrEqual OperatorName = "gte"
+	OpLessThan           OperatorName = "lt"
+	OpLessThanOrEqual    OperatorName = "lte"
+	OpBetween            OperatorName = "between"
+	OpBefore             OperatorName = "before"
+	OpAfter              OperatorName = "after"
+
+	OpAnyOf OperatorName = "any_of"
+	OpAllOf OperatorName = "all_of"
+	OpNot   OperatorName = "not"
+
+	OpJSONEqual  OperatorName = "json_equal"
+	OpJSONSchema OperatorName = "json_schema"
+
+	OpContainsAll OperatorName = "contains_all"
+	OpHash        OperatorName = "hash"
 )
 
 // OperatorName is alias for operator name
@@ -51,10 +85,22 @@ type Operator struct {
 	//  - "length"
 	//  - "empty"
 	//  - "not_empty"
+	//  - "gt", "gte", "lt", "lte": numeric comparison
+	//  - "between": value is an array of [min, max], both are inclusive
+	//  - "before", "after": date comparison, value is RFC3339 date or relative expression. For example: now, now-1h, now+7d
+	//  - "any_of", "all_of", "not": composite operators, nested operators are defined in operators
+	//  - "json_equal": structural json equality, value is JSONEqualValue
+	//  - "json_schema": json schema validation, value is JSONSchemaValue
+	//  - "contains_all": actual value contains all elements of the given array
+	//  - "hash": checksum of actual value, value is <algorithm>:<hex digest>. For example: sha256:2cf24d...
 	Name OperatorName `json:"name" yaml:"name"`
 
 	// Value the expected value, which will be compared with value from incoming request
 	Value interface{} `json:"value" yaml:"value"`
+
+	// Operators are nested operators of composite operators which are applied for the same value
+	// The "not" operator requires exactly one nested operator
+	Operators []Operator `json:"operators,omitempty" yaml:"operators"`
 }
 
 // String returns string
@@ -62,6 +108,26 @@ func (o Operator) String() string {
 	return fmt.Sprintf("Operator Name: %s - Type: %T", o.Name, o.Value)
 }
 
+// IsComposite returns true if operator nests other operators
+func (o Operator) IsComposite() bool {
+	return o.Name == OpAnyOf || o.Name == OpAllOf || o.Name == OpNot
+}
+
+// expected returns the expected value which is reported in diagnostics
+func (o Operator) expected() interface{} {
+	if !o.IsComposite() {
+		return o.Value
+	}
+
+	parts := make([]string, len(o.Operators))
+	for i, nested := range o.Operators {
+		parts[i] = fmt.Sprintf("%s %v", nested.Name, nested.expected())
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// IsValid returns true if operator is a built-in operator or a registered custom operator
 func (o Operator) IsValid() bool {
 	for _, name := range AllSupportedOperators {
 		if o.Name == name {
@@ -69,14 +135,49 @@ func (o Operator) IsValid() bool {
 		}
 	}
 
-	return false
+	_, ok := customOperators.get(o.Name)
+	return ok
 }
 
+// Defines quantifiers to match a field which has multiple values. For example: ?tag=a&tag=b
+const (
+	// QuantifierFirst applies operator for the first value. This is default for http header and query
+	QuantifierFirst Quantifier = "first"
+
+	// QuantifierAny requires at least one value matches operator. This is default for grpc metadata
+	QuantifierAny Quantifier = "any"
+
+	// QuantifierAll requires all values match operator
+	QuantifierAll Quantifier = "all"
+
+	// QuantifierCount applies operator for the number of values
+	QuantifierCount Quantifier = "count"
+
+	// QuantifierValues applies operator for the list of values. For example: contains_all, length
+	QuantifierValues Quantifier = "values"
+)
+
+// Quantifier is alias for the quantifier of field operator
+type Quantifier string
+
 // FieldOperator defines operator with field name
 type FieldOperator struct {
 	// FieldName is header name, cookie name or parameter name
 	FieldName string   `json:"field_name" yaml:"field_name"`
 	Operator  Operator `json:"operator" yaml:"operator"`
+
+	// Quantifier defines how operator is applied if the field has multiple values
+	// This is supported for http header, query and grpc metadata
+	Quantifier Quantifier `json:"quantifier,omitempty" yaml:"quantifier"`
+}
+
+// GetQuantifier returns quantifier or the given default value if it is not defined
+func (o FieldOperator) GetQuantifier(defaultValue Quantifier) Quantifier {
+	if len(o.Quantifier) == 0 {
+		return defaultValue
+	}
+
+	return o.Quantifier
 }
 
 // String returns string
@@ -151,6 +252,129 @@ func Length(v int) CreateOperator {
 	}
 }
 
+// GreaterThan checks actual value should be greater than given number
+// Actual value is converted to number, for example: json number or numeric string
+func GreaterThan(v interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpGreaterThan, Value: v}
+	}
+}
+
+// GreaterThanOrEqual checks actual value should be greater than or equal to given number
+func GreaterThanOrEqual(v interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpGreaterThanOrEqual, Value: v}
+	}
+}
+
+// LessThan checks actual value should be less than given number
+func LessThan(v interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpLessThan, Value: v}
+	}
+}
+
+// LessThanOrEqual checks actual value should be less than or equal to given number
+func LessThanOrEqual(v interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpLessThanOrEqual, Value: v}
+	}
+}
+
+// Between checks actual value should be in range [min, max]
+func Between(lower interface{}, upper interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpBetween, Value: []interface{}{lower, upper}}
+	}
+}
+
+// Before checks actual date should be before given date
+// Given date is RFC3339 date or relative expression to current time. For example: now, now-1h, now+7d
+// Actual value must be RFC3339 date string
+func Before(v string) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpBefore, Value: v}
+	}
+}
+
+// After checks actual date should be after given date
+// Given date is RFC3339 date or relative expression to current time. For example: now, now-1h, now+7d
+// Actual value must be RFC3339 date string
+func After(v string) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpAfter, Value: v}
+	}
+}
+
+// ContainsAll checks actual value should contain all the given values
+// This is usually used with QuantifierValues to match multiple values of header or query
+func ContainsAll(values ...interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpContainsAll, Value: values}
+	}
+}
+
+// Hash checks the checksum of actual value should be equal to the given hex digest
+// Supported algorithms: md5, sha1, sha256 and sha512
+func Hash(algorithm string, hexDigest string) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpHash, Value: algorithm + ":" + hexDigest}
+	}
+}
+
+// AnyOf checks actual value should match at least one of the given operators
+func AnyOf(createOperators ...CreateOperator) CreateOperator {
+	return newCompositeOperator(OpAnyOf, createOperators...)
+}
+
+// AllOf checks actual value should match all of the given operators
+func AllOf(createOperators ...CreateOperator) CreateOperator {
+	return newCompositeOperator(OpAllOf, createOperators...)
+}
+
+// Not checks actual value should not match the given operator
+func Not(createOperator CreateOperator) CreateOperator {
+	return newCompositeOperator(OpNot, createOperator)
+}
+
+// JSONEqual checks actual value should be structurally equal to the expected json document
+// Expected document can be a map, a slice or any value which can be marshaled to json
+func JSONEqual(expected interface{}, options ...JSONEqualOption) CreateOperator {
+	return func() Operator {
+		v := &JSONEqualValue{Expected: expected}
+		for _, option := range options {
+			option(v)
+		}
+
+		return Operator{Name: OpJSONEqual, Value: v}
+	}
+}
+
+// JSONSchema checks actual value should be valid against the given json schema
+func JSONSchema(schema interface{}) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpJSONSchema, Value: newJSONSchemaValue(schema, "")}
+	}
+}
+
+// JSONSchemaFile checks actual value should be valid against the json schema which is uploaded to file storage
+func JSONSchemaFile(fileID string) CreateOperator {
+	return func() Operator {
+		return Operator{Name: OpJSONSchema, Value: newJSONSchemaValue(nil, fileID)}
+	}
+}
+
+func newCompositeOperator(name OperatorName, createOperators ...CreateOperator) CreateOperator {
+	return func() Operator {
+		ops := make([]Operator, len(createOperators))
+		for i, createOperator := range createOperators {
+			ops[i] = createOperator()
+		}
+
+		return Operator{Name: name, Operators: ops}
+	}
+}
+
 // BodyOperator define operator for matching body
 type BodyOperator struct {
 	// The content type of the request body which is one of the following values
@@ -167,6 +391,14 @@ type BodyOperator struct {
 	// KeyPath is json or xml path
 	// Refer to this document for json path syntax https://goessner.net/articles/JsonPath/
 	KeyPath string `json:"key_path" yaml:"key_path"`
+
+	// Namespaces maps prefixes which are used in xml path to namespace uris
+	// This is optional, prefixes which are declared in the request body can be used directly
+	Namespaces map[string]string `json:"namespaces,omitempty" yaml:"namespaces"`
+
+	// Raw applies operator for the whole request body as string instead of the value at key path
+	// Content type is optional for raw body operator, empty content type matches any request content type
+	Raw bool `json:"raw,omitempty" yaml:"raw"`
 }
 
 // CreateBodyOperator is alias function for creating a body operator
@@ -184,6 +416,48 @@ func BodyJSONPath(jsonPath string, createOperator CreateOperator) CreateBodyOper
 	}
 }
 
+// BodyJSONEqual matches the whole json request body with the expected document
+// Use BodyJSONPath with JSONEqual operator to compare a sub tree
+func BodyJSONEqual(expected interface{}, options ...JSONEqualOption) CreateBodyOperator {
+	return BodyJSONPath("$", JSONEqual(expected, options...))
+}
+
+// BodyRaw matches the raw request body of any content type. For example: plain text, csv or binary
+// The raw body is passed to operator as string
+func BodyRaw(createOperator CreateOperator) CreateBodyOperator {
+	return BodyRawWithContentType("", createOperator)
+}
+
+// BodyRawWithContentType matches the raw request body if the request content type starts with the given content type
+func BodyRawWithContentType(contentType string, createOperator CreateOperator) CreateBodyOperator {
+	return func() BodyOperator {
+		return BodyOperator{
+			Operator:    createOperator(),
+			ContentType: contentType,
+			Raw:         true,
+		}
+	}
+}
+
+// BodyXMLPath matches xml request body by the xpath expression
+// Refer to this document for xpath syntax https://www.w3.org/TR/xpath/
+func BodyXMLPath(xmlPath string, createOperator CreateOperator) CreateBodyOperator {
+	return BodyXMLPathWithNamespaces(xmlPath, nil, createOperator)
+}
+
+// BodyXMLPathWithNamespaces matches xml request body by the xpath expression
+// The namespaces maps prefixes which are used in the expression to namespace uris
+func BodyXMLPathWithNamespaces(xmlPath string, namespaces map[string]string, createOperator CreateOperator) CreateBodyOperator {
+	return func() BodyOperator {
+		return BodyOperator{
+			Operator:    createOperator(),
+			ContentType: ContentTypeXML,
+			KeyPath:     xmlPath,
+			Namespaces:  namespaces,
+		}
+	}
+}
+
 // MultiPartForm to verify form value in multiple parts request
 func MultiPartForm(key string, createOperator CreateOperator) CreateBodyOperator {
 	return func() BodyOperator {
@@ -206,6 +480,73 @@ func URLEncodedBody(key string, createOperator CreateOperator) CreateBodyOperato
 	}
 }
 
+// Defines scopes to select messages from a grpc client stream
+const (
+	StreamScopeFirst StreamScope = "first"
+	StreamScopeLast  StreamScope = "last"
+	StreamScopeAny   StreamScope = "any"
+	StreamScopeAll   StreamScope = "all"
+	StreamScopeCount StreamScope = "count"
+)
+
+// StreamScope is alias for the scope of stream operator
+type StreamScope string
+
+// StreamOperator defines operator for matching messages of a grpc client stream
+type StreamOperator struct {
+	// Scope defines which messages are matched, it is one of the following values
+	//  - "first": the first message
+	//  - "last": the last message
+	//  - "any": at least one message must be matched
+	//  - "all": all messages must be matched
+	//  - "count": the operator is applied for the number of messages, key path is ignored
+	Scope StreamScope `json:"scope" yaml:"scope"`
+
+	Operator Operator `json:"operator" yaml:"operator"`
+
+	// KeyPath is json path which is applied for each selected message
+	// Refer to this document for json path syntax https://goessner.net/articles/JsonPath/
+	KeyPath string `json:"key_path,omitempty" yaml:"key_path"`
+}
+
+// CreateStreamOperator is alias function for creating a stream operator
+type CreateStreamOperator func() StreamOperator
+
+// StreamFirst matches the first message of client stream by the json path
+func StreamFirst(jsonPath string, createOperator CreateOperator) CreateStreamOperator {
+	return newStreamOperator(StreamScopeFirst, jsonPath, createOperator)
+}
+
+// StreamLast matches the last message of client stream by the json path
+func StreamLast(jsonPath string, createOperator CreateOperator) CreateStreamOperator {
+	return newStreamOperator(StreamScopeLast, jsonPath, createOperator)
+}
+
+// StreamAny matches if at least one message of client stream is matched by the json path
+func StreamAny(jsonPath string, createOperator CreateOperator) CreateStreamOperator {
+	return newStreamOperator(StreamScopeAny, jsonPath, createOperator)
+}
+
+// StreamAll matches if all messages of client stream are matched by the json path
+func StreamAll(jsonPath string, createOperator CreateOperator) CreateStreamOperator {
+	return newStreamOperator(StreamScopeAll, jsonPath, createOperator)
+}
+
+// StreamCount matches the number of messages of client stream
+func StreamCount(createOperator CreateOperator) CreateStreamOperator {
+	return newStreamOperator(StreamScopeCount, "", createOperator)
+}
+
+func newStreamOperator(scope StreamScope, jsonPath string, createOperator CreateOperator) CreateStreamOperator {
+	return func() StreamOperator {
+		return StreamOperator{
+			Scope:    scope,
+			Operator: createOperator(),
+			KeyPath:  jsonPath,
+		}
+	}
+}
+
 func validateOp(ctx context.Context, ops ...Operator) error {
 	for _, o := range ops {
 		if !o.IsValid() {
@@ -213,6 +554,98 @@ func validateOp(ctx context.Context, ops ...Operator) error {
 			log.Error(ctx, err)
 			return err
 		}
+
+		if err := validateOpValue(ctx, o); err != nil {
+			log.Error(ctx, err)
+			return err
+		}
+
+		if o.IsComposite() {
+			if err := validateCompositeOp(ctx, o); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCompositeOp validates nested operators recursively
+func validateCompositeOp(ctx context.Context, o Operator) error {
+	if len(o.Operators) == 0 {
+		err := fmt.Errorf("operator %s requires nested operators", o.Name)
+		log.Error(ctx, err)
+		return err
+	}
+
+	if o.Name == OpNot && len(o.Operators) != 1 {
+		err := fmt.Errorf("operator %s requires exactly one nested operator", o.Name)
+		log.Error(ctx, err)
+		return err
+	}
+
+	return validateOp(ctx, o.Operators...)
+}
+
+// validateOpValue validates expected value of the operators which require a specific data type
+func validateOpValue(ctx context.Context, o Operator) error {
+	if custom, ok := customOperators.get(o.Name); ok {
+		if custom.validate == nil {
+			return nil
+		}
+
+		if err := custom.validate(o.Value); err != nil {
+			return fmt.Errorf("operator %s has invalid value: %w", o.Name, err)
+		}
+
+		return nil
+	}
+
+	switch o.Name {
+	case OpGreaterThan, OpGreaterThanOrEqual, OpLessThan, OpLessThanOrEqual:
+		if _, ok := getFloat64(o.Value); !ok {
+			return fmt.Errorf("operator %s requires a number, got %T", o.Name, o.Value)
+		}
+
+	case OpBetween:
+		lower, upper, ok := getRange(o.Value)
+		if !ok {
+			return fmt.Errorf("operator %s requires an array of two numbers [min, max]", o.Name)
+		}
+
+		if c, _ := compareNumbers(lower, upper); c > 0 {
+			return fmt.Errorf("operator %s requires min is not greater than max", o.Name)
+		}
+
+	case OpBefore, OpAfter:
+		s, ok := o.Value.(string)
+		if !ok {
+			return fmt.Errorf("operator %s requires a date string, got %T", o.Name, o.Value)
+		}
+
+		if _, err := parseTimeExpression(s, time.Now()); err != nil {
+			return fmt.Errorf("operator %s has invalid date: %w", o.Name, err)
+		}
+
+	case OpJSONEqual:
+		if err := validateJSONEqualValue(o.Value); err != nil {
+			return fmt.Errorf("operator %s has invalid value: %w", o.Name, err)
+		}
+
+	case OpJSONSchema:
+		if err := validateJSONSchemaValue(ctx, o.Value); err != nil {
+			return fmt.Errorf("operator %s has invalid value: %w", o.Name, err)
+		}
+
+	case OpContainsAll:
+		if _, ok := getSlice(o.Value); !ok {
+			return fmt.Errorf("operator %s requires an array, got %T", o.Name, o.Value)
+		}
+
+	case OpHash:
+		if _, _, err := parseHashValue(o.Value); err != nil {
+			return fmt.Errorf("operator %s has invalid value: %w", o.Name, err)
+		}
 	}
 
 	return nil
@@ -229,6 +662,14 @@ func validateFieldOps(ctx context.Context, ops ...FieldOperator) error {
 			log.Error(ctx, err)
 			return err
 		}
+
+		switch o.Quantifier {
+		case "", QuantifierFirst, QuantifierAny, QuantifierAll, QuantifierCount, QuantifierValues:
+		default:
+			err := fmt.Errorf("unsupported quantifier %s", o.Quantifier)
+			log.Error(ctx, err)
+			return err
+		}
 	}
 
 	return nil
@@ -240,6 +681,11 @@ func validateBodyOps(ctx context.Context, ops ...BodyOperator) error {
 			return err
 		}
 
+		// Raw body operator is applied for the whole body of any content type
+		if o.Raw {
+			continue
+		}
+
 		if len(o.KeyPath) == 0 {
 			err := fmt.Errorf("missing key path %s", o.KeyPath)
 			log.Error(ctx, err)
@@ -255,3 +701,28 @@ func validateBodyOps(ctx context.Context, ops ...BodyOperator) error {
 
 	return nil
 }
+
+func validateStreamOps(ctx context.Context, ops ...StreamOperator) error {
+	for _, o := range ops {
+		if err := validateOp(ctx, o.Operator); err != nil {
+			return err
+		}
+
+		switch o.Scope {
+		case StreamScopeCount:
+			continue
+		case StreamScopeFirst, StreamScopeLast, StreamScopeAny, StreamScopeAll:
+			if len(o.KeyPath) == 0 {
+				err := fmt.Errorf("missing key path for scope %s", o.Scope)
+				log.Error(ctx, err)
+				return err
+			}
+		default:
+			err := fmt.Errorf("unsupported stream scope %s", o.Scope)
+			log.Error(ctx, err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package rio
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateOp(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	testCases := []struct {
+		Name             string
+		Operator         Operator
+		ExpectedHasError bool
+	}{
+		{"equal_to", EqualTo("abc")(), false},
+		{"unsupported_name", Operator{Name: "unknown"}, true},
+		{"gt", GreaterThan(10)(), false},
+		{"gt_numeric_string", GreaterThan("10.5")(), false},
+		{"gt_not_number", GreaterThan("abc")(), true},
+		{"between", Between(1, 10)(), false},
+		{"between_decoded_json", Operator{Name: OpBetween, Value: []interface{}{float64(1), float64(10)}}, false},
+		{"between_min_greater_than_max", Between(10, 1)(), true},
+		{"between_not_array", Operator{Name: OpBetween, Value: 10}, true},
+		{"before_relative", Before("now-1h")(), false},
+		{"after_absolute", After("2023-01-01T00:00:00Z")(), false},
+		{"after_invalid_date", After("2023/01/01")(), true},
+		{"before_not_string", Operator{Name: OpBefore, Value: 10}, true},
+		{"any_of", AnyOf(EqualTo("A"), EqualTo("B"))(), false},
+		{"any_of_empty", AnyOf()(), true},
+		{"all_of_nested_invalid", AllOf(EqualTo("A"), AnyOf(GreaterThan("abc")))(), true},
+		{"not", Not(StartWith("/internal"))(), false},
+		{"not_multiple_operators", Operator{Name: OpNot, Operators: []Operator{EqualTo("A")(), EqualTo("B")()}}, true},
+		{"json_equal", JSONEqual(map[string]interface{}{"id": 1}, IgnorePaths("$.id"))(), false},
+		{"json_equal_invalid_ignore_path", JSONEqual(map[string]interface{}{"id": 1}, IgnorePaths("id"))(), true},
+		{"json_equal_invalid_value", Operator{Name: OpJSONEqual, Value: "abc"}, true},
+		{"json_schema", JSONSchema(map[string]interface{}{"type": "object"})(), false},
+		{"json_schema_file", JSONSchemaFile("schema.json")(), false},
+		{"json_schema_invalid_schema", JSONSchema(map[string]interface{}{"type": 10})(), true},
+		{"json_schema_missing_schema", Operator{Name: OpJSONSchema, Value: map[string]interface{}{}}, true},
+		{"hash", Hash("md5", "d41d8cd98f00b204e9800998ecf8427e")(), false},
+		{"hash_unsupported_algorithm", Hash("crc32", "00000000")(), true},
+		{"hash_invalid_digest", Hash("sha256", "abc")(), true},
+		{"hash_missing_algorithm", Operator{Name: OpHash, Value: "d41d8cd98f00b204e9800998ecf8427e"}, true},
+	}
+
+	for _, tc := range testCases {
+		err := validateOp(ctx, tc.Operator)
+		if tc.ExpectedHasError {
+			require.Error(t, err, tc.Name)
+		} else {
+			require.NoError(t, err, tc.Name)
+		}
+	}
+}
+
+func TestCompositeOperator_Decode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	op := AnyOf(EqualTo("A"), Not(StartWith("/internal")))()
+
+	data, err := json.Marshal(op)
+	require.NoError(t, err)
+
+	fromJSON := Operator{}
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	require.Equal(t, op, fromJSON)
+
+	yamlData := `
+name: any_of
+operators:
+  - name: equal_to
+    value: A
+  - name: not
+    operators:
+      - name: start_with
+        value: /internal
+`
+	fromYAML := Operator{}
+	require.NoError(t, yaml.Unmarshal([]byte(yamlData), &fromYAML))
+	require.Equal(t, op, fromYAML)
+	require.NoError(t, validateOp(ctx, fromYAML))
+
+	matched, err := Match(ctx, fromYAML, "/internal/animal")
+	require.NoError(t, err)
+	require.False(t, matched)
+	require.Equal(t, "(equal_to A, not (start_with /internal))", op.expected())
+}
@@ -0,0 +1,270 @@
+package rio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Defines types of path parameters which can be used in path template. For example: /users/{id:int}
+const (
+	PathParamString = "string"
+	PathParamInt    = "int"
+	PathParamNumber = "number"
+	PathParamUUID   = "uuid"
+	PathParamAlpha  = "alpha"
+)
+
+// opPathTemplate is the name which is reported in diagnostics when path template is not matched
+const opPathTemplate OperatorName = "path_template"
+
+var (
+	pathParamNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	alphaPattern         = regexp.MustCompile(`^[A-Za-z]+$`)
+)
+
+type requestPathContextKey struct{}
+
+// contextWithRequestPath saves the escaped request path which is removed the base path of mock server
+func contextWithRequestPath(ctx context.Context, requestPath string) context.Context {
+	return context.WithValue(ctx, requestPathContextKey{}, requestPath)
+}
+
+// getRequestPath returns the escaped request path without base path if it is available, otherwise escaped url path is returned
+// The segments are unescaped when matching with path template, so that an escaped slash is kept in a segment
+func getRequestPath(ctx context.Context, u *url.URL) string {
+	if p, ok := ctx.Value(requestPathContextKey{}).(string); ok {
+		return p
+	}
+
+	return u.EscapedPath()
+}
+
+// trimBasePath removes the base path (and namespace) of mock server from the url path
+// The url path is not changed if base path is empty, which is used for virtual hosts
+func trimBasePath(urlPath string, namespace string, basePath string) string {
+	if len(basePath) == 0 {
+		return path.Join("/", urlPath)
+	}
+
+	if len(namespace) > 0 {
+		return path.Join("/", strings.TrimPrefix(urlPath, "/"+namespace+basePath))
+	}
+
+	return path.Join("/", strings.TrimPrefix(urlPath, basePath))
+}
+
+type pathSegmentKind int
+
+const (
+	pathSegmentLiteral pathSegmentKind = iota
+	pathSegmentParam
+	pathSegmentWildcard
+	pathSegmentMultiWildcard
+)
+
+type pathSegment struct {
+	kind      pathSegmentKind
+	value     string
+	paramType string
+}
+
+// pathTemplate is the parsed path template. For example: /users/{id:int}/orders/{order_id}, /static/**
+type pathTemplate struct {
+	segments []pathSegment
+}
+
+var defaultPathTemplateParser = &pathTemplateParser{}
+
+// pathTemplateParser caches the parsed path templates, so that a template is not parsed for every request
+type pathTemplateParser struct {
+	templates map[string]*pathTemplate
+	l         sync.RWMutex
+}
+
+func (p *pathTemplateParser) parse(template string) (*pathTemplate, error) {
+	if t := p.getFromCache(template); t != nil {
+		return t, nil
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	t, err := parsePathTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.templates == nil {
+		p.templates = map[string]*pathTemplate{}
+	}
+
+	p.templates[template] = t
+	return t, nil
+}
+
+func (p *pathTemplateParser) getFromCache(template string) *pathTemplate {
+	p.l.RLock()
+	defer p.l.RUnlock()
+
+	if t, ok := p.templates[template]; ok {
+		return t
+	}
+
+	return nil
+}
+
+// parsePathTemplate parses the path template
+// Supported segments are literal, parameter {name} or {name:type}, wildcard * for one segment and ** for any segments
+func parsePathTemplate(template string) (*pathTemplate, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("path template must start with /: %s", template)
+	}
+
+	t := &pathTemplate{}
+	names := map[string]bool{}
+	for _, s := range splitPath(template) {
+		switch {
+		case s == "*":
+			t.segments = append(t.segments, pathSegment{kind: pathSegmentWildcard})
+
+		case s == "**":
+			t.segments = append(t.segments, pathSegment{kind: pathSegmentMultiWildcard})
+
+		case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			name, paramType, _ := strings.Cut(s[1:len(s)-1], ":")
+			if len(paramType) == 0 {
+				paramType = PathParamString
+			}
+
+			if !pathParamNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid path parameter name %s in %s", name, template)
+			}
+
+			if names[name] {
+				return nil, fmt.Errorf("duplicated path parameter %s in %s", name, template)
+			}
+
+			if !isValidPathParamType(paramType) {
+				return nil, fmt.Errorf("unsupported path parameter type %s in %s", paramType, template)
+			}
+
+			names[name] = true
+			t.segments = append(t.segments, pathSegment{kind: pathSegmentParam, value: name, paramType: paramType})
+
+		case strings.ContainsAny(s, "{}"):
+			return nil, fmt.Errorf("path parameter must be a whole segment: %s", template)
+
+		default:
+			t.segments = append(t.segments, pathSegment{kind: pathSegmentLiteral, value: s})
+		}
+	}
+
+	return t, nil
+}
+
+// match matches the escaped path with template and returns the captured parameters
+func (t *pathTemplate) match(requestPath string) (map[string]string, bool) {
+	params := map[string]string{}
+	if !t.matchSegments(t.segments, splitPath(requestPath), params) {
+		return nil, false
+	}
+
+	return params, true
+}
+
+func (t *pathTemplate) matchSegments(segments []pathSegment, parts []string, params map[string]string) bool {
+	if len(segments) == 0 {
+		return len(parts) == 0
+	}
+
+	segment := segments[0]
+	if segment.kind == pathSegmentMultiWildcard {
+		// Try to consume from zero to all remaining parts
+		for i := 0; i <= len(parts); i++ {
+			if t.matchSegments(segments[1:], parts[i:], params) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+
+	part, err := url.PathUnescape(parts[0])
+	if err != nil {
+		part = parts[0]
+	}
+
+	switch segment.kind {
+	case pathSegmentLiteral:
+		if part != segment.value {
+			return false
+		}
+
+	case pathSegmentParam:
+		if !matchPathParamType(segment.paramType, part) {
+			return false
+		}
+
+		params[segment.value] = part
+		if t.matchSegments(segments[1:], parts[1:], params) {
+			return true
+		}
+
+		delete(params, segment.value)
+		return false
+	}
+
+	return t.matchSegments(segments[1:], parts[1:], params)
+}
+
+func splitPath(p string) []string {
+	parts := []string{}
+	for _, part := range strings.Split(p, "/") {
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+func isValidPathParamType(paramType string) bool {
+	switch paramType {
+	case PathParamString, PathParamInt, PathParamNumber, PathParamUUID, PathParamAlpha:
+		return true
+	}
+
+	return false
+}
+
+func matchPathParamType(paramType string, value string) bool {
+	switch paramType {
+	case PathParamString:
+		return true
+	case PathParamInt:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case PathParamNumber:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case PathParamUUID:
+		_, err := uuid.Parse(value)
+		return err == nil
+	case PathParamAlpha:
+		return alphaPattern.MatchString(value)
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package rio
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathTemplate_Match(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.NewString()
+	testCases := []struct {
+		Name           string
+		Template       string
+		Path           string
+		ExpectedResult bool
+		ExpectedParams map[string]string
+	}{
+		{"literal", "/animals", "/animals", true, map[string]string{}},
+		{"trailing_slash", "/animals/", "/animals", true, map[string]string{}},
+		{"literal_unmatched", "/animals", "/animals/1", false, nil},
+		{"params", "/users/{id:int}/orders/{order_id}", "/users/10/orders/abc", true, map[string]string{"id": "10", "order_id": "abc"}},
+		{"int_unmatched", "/users/{id:int}", "/users/abc", false, nil},
+		{"number", "/prices/{value:number}", "/prices/10.5", true, map[string]string{"value": "10.5"}},
+		{"uuid", "/animals/{id:uuid}", "/animals/" + id, true, map[string]string{"id": id}},
+		{"uuid_unmatched", "/animals/{id:uuid}", "/animals/10", false, nil},
+		{"alpha", "/animals/{name:alpha}", "/animals/cat", true, map[string]string{"name": "cat"}},
+		{"alpha_unmatched", "/animals/{name:alpha}", "/animals/cat1", false, nil},
+		{"escaped", "/animals/{name}", "/animals/black%20cat", true, map[string]string{"name": "black cat"}},
+		{"escaped_once", "/animals/{name}", "/animals/100%2525", true, map[string]string{"name": "100%25"}},
+		{"escaped_slash", "/files/{name}", "/files/a%2Fb", true, map[string]string{"name": "a/b"}},
+		{"wildcard", "/animals/*/image", "/animals/10/image", true, map[string]string{}},
+		{"wildcard_unmatched", "/animals/*/image", "/animals/image", false, nil},
+		{"multi_wildcard", "/static/**", "/static/images/cat.png", true, map[string]string{}},
+		{"multi_wildcard_empty", "/static/**", "/static", true, map[string]string{}},
+		{"multi_wildcard_middle", "/files/**/{name}", "/files/a/b/cat.png", true, map[string]string{"name": "cat.png"}},
+	}
+
+	for _, tc := range testCases {
+		template, err := parsePathTemplate(tc.Template)
+		require.NoError(t, err, tc.Name)
+
+		params, matched := template.match(tc.Path)
+		require.Equal(t, tc.ExpectedResult, matched, tc.Name)
+		require.Equal(t, tc.ExpectedParams, params, tc.Name)
+	}
+}
+
+func TestPathTemplateParser(t *testing.T) {
+	t.Parallel()
+
+	parser := &pathTemplateParser{}
+	template, err := parser.parse("/users/{id:int}")
+	require.NoError(t, err)
+
+	cached, err := parser.parse("/users/{id:int}")
+	require.NoError(t, err)
+	require.Same(t, template, cached)
+
+	_, err = parser.parse("/users/{id:date}")
+	require.Error(t, err)
+}
+
+func TestParsePathTemplate_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, template := range []string{
+		"animals/{id}",
+		"/animals/{id:date}",
+		"/animals/{1id}",
+		"/animals/{id}/owners/{id}",
+		"/animals/id-{id}",
+	} {
+		_, err := parsePathTemplate(template)
+		require.Error(t, err, template)
+	}
+}
+
+func TestTrimBasePath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/users/1", trimBasePath("/echo/users/1", "", defaultBasePath))
+	require.Equal(t, "/users/1", trimBasePath("/ns/echo/users/1", "ns", defaultBasePath))
+	require.Equal(t, "/users/1", trimBasePath("/users/1", "", defaultBasePath))
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,6 +25,20 @@ type RequestMatching struct {
 	// Rules to match the url
 	URL []Operator `json:"url,omitempty" yaml:"url"`
 
+	// Rules to match the host without port. The forwarded host is used if rio is behind a proxy
+	Host []Operator `json:"host,omitempty" yaml:"host"`
+
+	// Scheme to match the request scheme http or https. The forwarded proto is used if rio is behind a proxy
+	Scheme string `json:"scheme,omitempty" yaml:"scheme"`
+
+	// Rules to match the port as string. The default port of scheme is used if the host does not contain port
+	Port []Operator `json:"port,omitempty" yaml:"port"`
+
+	// Path is the template to match the url path which is removed the base path of mock server
+	// For example: /users/{id:int}/orders/{order_id}, /static/** or /animals/*/image
+	// Supported parameter types: string (default), int, number, uuid and alpha
+	Path string `json:"path,omitempty" yaml:"path"`
+
 	// Rules to match header name
 	Header []FieldOperator `json:"header,omitempty" yaml:"header"`
 
@@ -35,13 +50,47 @@ type RequestMatching struct {
 
 	// Rules to match request body by xml or json path
 	Body []BodyOperator `json:"body,omitempty" yaml:"body"`
+
+	// Rules to match the parsed GraphQL request by operation name, operation type, root fields or variables
+	GraphQL []GraphQLOperator `json:"graphql,omitempty" yaml:"graphql"`
+
+	// Rules to match the messages of a grpc client stream
+	// This is only applied for client streaming or bidirectional streaming methods
+	Stream []StreamOperator `json:"stream,omitempty" yaml:"stream"`
 }
 
 func (r *RequestMatching) Validate(ctx context.Context) error {
+	if r == nil {
+		err := errors.New("request matching must be defined")
+		log.Error(ctx, err)
+		return err
+	}
+
 	if err := validateOp(ctx, r.URL...); err != nil {
 		return err
 	}
 
+	if err := validateOp(ctx, r.Host...); err != nil {
+		return err
+	}
+
+	if len(r.Scheme) > 0 && !isValidScheme(r.Scheme) {
+		err := fmt.Errorf("unsupported scheme %s", r.Scheme)
+		log.Error(ctx, err)
+		return err
+	}
+
+	if err := validateOp(ctx, r.Port...); err != nil {
+		return err
+	}
+
+	if len(r.Path) > 0 {
+		if _, err := parsePathTemplate(r.Path); err != nil {
+			log.Error(ctx, err)
+			return err
+		}
+	}
+
 	if err := validateFieldOps(ctx, r.Header...); err != nil {
 		return err
 	}
@@ -58,19 +107,51 @@ func (r *RequestMatching) Validate(ctx context.Context) error {
 		return err
 	}
 
+	if err := validateGraphQLOps(ctx, r.GraphQL...); err != nil {
+		return err
+	}
+
+	if err := validateStreamOps(ctx, r.Stream...); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// matchPath matches the escaped request path with path template and returns the captured path parameters
+func (r *RequestMatching) matchPath(ctx context.Context, requestPath string) (map[string]string, bool, error) {
+	if len(r.Path) == 0 {
+		return nil, true, nil
+	}
+
+	t, err := defaultPathTemplateParser.parse(r.Path)
+	if err != nil {
+		log.Error(ctx, err)
+		return nil, false, err
+	}
+
+	params, matched := t.match(requestPath)
+	return params, matched, nil
+}
+
 // Scan implements sqlx JSON scan method
 func (r *RequestMatching) Scan(val interface{}) error {
+	var data []byte
 	switch v := val.(type) {
 	case []byte:
-		return json.Unmarshal(v, &r)
+		data = v
 	case string:
-		return json.Unmarshal([]byte(v), &r)
+		data = []byte(v)
 	default:
 		return fmt.Errorf("unsupported type: %T", v)
 	}
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+
+	*r = *loadJSONSchemas(r)
+	return nil
 }
 
 // Value implements sqlx JSON value method
@@ -80,8 +161,14 @@ func (r RequestMatching) Value() (driver.Value, error) {
 
 // GrpcRequest defines grpc request
 type GrpcRequest struct {
-	FullMethod string    `json:"full_method" yaml:"full_method"`
-	InputData  types.Map `json:"input_data" yaml:"input_data"`
+	FullMethod string `json:"full_method" yaml:"full_method"`
+
+	// InputData is the first message of a client stream, or the latest received message of a bidirectional stream
+	InputData types.Map `json:"input_data" yaml:"input_data"`
+
+	// Messages holds all received messages of a client stream
+	// For unary and server streaming methods, it contains only one message which is the same as InputData
+	Messages []types.Map `json:"messages" yaml:"messages"`
 }
 
 type IncomingRequests struct {
@@ -99,6 +186,16 @@ type IncomingRequest struct {
 	Body      []byte    `json:"body" yaml:"body"`
 	CURL      string    `json:"curl" gorm:"column:curl" yaml:"curl"`
 	StubID    int64     `json:"stub_id" yaml:"stub_id"`
+
+	// Host is the host (with port if it is not default) which the client used to send the request
+	Host   string `json:"host,omitempty" yaml:"host"`
+	Scheme string `json:"scheme,omitempty" yaml:"scheme"`
+
+	// PathParams are the parameters which are captured by the path template of matched stub
+	PathParams map[string]string `json:"path_params,omitempty" yaml:"path_params" gorm:"serializer:json"`
+
+	// Diagnostics lists the closest stubs if the request is not matched with any stub
+	Diagnostics Diagnostics `json:"diagnostics,omitempty" yaml:"diagnostics" gorm:"serializer:json"`
 }
 
 // WithNamespace sets namespace
@@ -109,11 +206,15 @@ func (i *IncomingRequest) WithNamespace(v string) *IncomingRequest {
 
 // Capture capture the request from http request
 // Ignore body if the given request is multiparts or its body exceeds the threshold
+// The origin is taken from the request context if it is resolved by handler
 func Capture(r *http.Request, bodyThreshold int) *IncomingRequest {
+	origin := getRequestOrigin(r.Context(), r)
 	incomingRequest := &IncomingRequest{
 		Method: r.Method,
 		URL:    r.URL.String(),
 		Header: types.Map{},
+		Host:   origin.HostPort(),
+		Scheme: origin.Scheme,
 	}
 
 	for name := range r.Header {
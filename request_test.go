@@ -85,3 +85,56 @@ func TestRemoveBodyFromCurl(t *testing.T) {
 	got := removeBodyFromCurl(&curl)
 	require.Equal(t, &expectedCurl, got)
 }
+
+func TestRequestMatching_ValidateStream(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validStub := NewStub().
+		ForGRPC(EqualTo("/events.v1.EventService/Upload")).
+		WithRequestStream(StreamFirst("$.id", EqualTo("1"))).
+		WithRequestStream(StreamCount(EqualTo(2)))
+	require.NoError(t, validStub.Request.Validate(ctx))
+
+	missingKeyPath := NewStub().
+		ForGRPC(EqualTo("/events.v1.EventService/Upload")).
+		WithRequestStream(StreamAll("", EqualTo("1")))
+	require.Error(t, missingKeyPath.Request.Validate(ctx))
+
+	unsupportedScope := NewStub().ForGRPC(EqualTo("/events.v1.EventService/Upload"))
+	unsupportedScope.Request.Stream = []StreamOperator{{Scope: "middle", Operator: EqualTo("1")(), KeyPath: "$.id"}}
+	require.Error(t, unsupportedScope.Request.Validate(ctx))
+}
+
+func TestRequestMatching_ValidateQuantifier(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validStub := NewStub().
+		For("GET", Contains("animal")).
+		WithQueryValues("tag", QuantifierValues, ContainsAll("cat", "dog")).
+		WithHeaderValues("Accept", QuantifierAny, Contains("json"))
+	require.NoError(t, validStub.Request.Validate(ctx))
+
+	unsupportedQuantifier := NewStub().For("GET", Contains("animal")).WithQueryValues("tag", "some", EqualTo("cat"))
+	require.Error(t, unsupportedQuantifier.Request.Validate(ctx))
+
+	invalidValue := NewStub().For("GET", Contains("animal")).WithQueryValues("tag", QuantifierValues, func() Operator {
+		return Operator{Name: OpContainsAll, Value: "cat"}
+	})
+	require.Error(t, invalidValue.Request.Validate(ctx))
+}
+
+func TestRequestMatching_ValidateOrigin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validStub := NewStub().For("GET", Contains("animal")).WithHost(EndWith("partner.com")).WithScheme("HTTPS").WithPort(EqualTo("8443"))
+	require.NoError(t, validStub.Request.Validate(ctx))
+
+	unsupportedScheme := NewStub().For("GET", Contains("animal")).WithScheme("ftp")
+	require.Error(t, unsupportedScheme.Request.Validate(ctx))
+
+	invalidHostOperator := NewStub().For("GET", Contains("animal")).WithHost(GreaterThan("abc"))
+	require.Error(t, invalidHostOperator.Request.Validate(ctx))
+}
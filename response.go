@@ -139,6 +139,29 @@ type ErrorDetail struct {
 	Value types.Map `json:"value,omitempty" yaml:"value"`
 }
 
+// StreamMessage defines a single message of a stream response
+// For HTTP, it is a chunk of body or an event if the content type is text/event-stream
+type StreamMessage struct {
+	// Body is the message payload. For grpc, it is the JSON format of the output message
+	// For server-sent events, it is the data of event
+	Body Body `json:"body,omitempty" yaml:"body"`
+
+	// Delay is the waiting duration before sending this message
+	Delay time.Duration `json:"delay,omitempty" swaggertype:"primitive,integer" yaml:"delay"`
+
+	// ID, Event and Retry are the fields of server-sent event. They are not applied for other content types
+	ID    string        `json:"id,omitempty" yaml:"id"`
+	Event string        `json:"event,omitempty" yaml:"event"`
+	Retry time.Duration `json:"retry,omitempty" swaggertype:"primitive,integer" yaml:"retry"`
+}
+
+// JSONStreamMessage is convenient constructor to initialize stream message with JSON body
+// The input parameter will be decoded to JSON
+func JSONStreamMessage(body interface{}, delay time.Duration) *StreamMessage {
+	_, b := MustToJSON(body)
+	return &StreamMessage{Body: b, Delay: delay}
+}
+
 // Response defines a response
 type Response struct {
 	// Required. Define the response status code
@@ -169,6 +192,16 @@ type Response struct {
 
 	// Optional. If defined, then executed template will override response data
 	Template *Template `json:"template,omitempty" yaml:"template"`
+
+	// Optional. Defines a sequence of messages for grpc server streaming methods or http streaming response
+	// Messages are sent in order, each message can be delayed. If not defined, body is sent as a single message
+	// For HTTP, each message is flushed as a chunk. If content type is text/event-stream, messages are written as server-sent events
+	Stream []*StreamMessage `json:"stream,omitempty" yaml:"stream"`
+
+	// Optional. Simulates a network-level failure instead of writing a well-formed response
+	// HTTP: connection_reset, empty_response, malformed_chunk, random_data, close_mid_body
+	// GRPC: close_mid_stream, unavailable
+	Fault FaultType `json:"fault,omitempty" yaml:"fault"`
 }
 
 // NewResponse creates new response
@@ -223,6 +256,7 @@ func (r *Response) Clone() *Response {
 		Error:      r.Error.Clone(),
 		Template:   r.Template,
 		Header:     cloneStringMap(r.Header),
+		Fault:      r.Fault,
 	}
 
 	if r.Body != nil {
@@ -235,6 +269,11 @@ func (r *Response) Clone() *Response {
 		copy(nr.Cookies, r.Cookies)
 	}
 
+	if r.Stream != nil {
+		nr.Stream = make([]*StreamMessage, len(r.Stream))
+		copy(nr.Stream, r.Stream)
+	}
+
 	return nr
 }
 
@@ -254,6 +293,17 @@ func (r *Response) Validate(ctx context.Context) error {
 		}
 	}
 
+	for _, m := range r.Stream {
+		if err := m.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := validateFault(r.Fault); err != nil {
+		log.Error(ctx, err)
+		return err
+	}
+
 	return nil
 }
 
@@ -279,6 +329,18 @@ func (r *Response) WithError(msg string, details ...*ErrorDetail) *Response {
 	return r
 }
 
+// WithStreamMessages appends messages to the stream response
+func (r *Response) WithStreamMessages(messages ...*StreamMessage) *Response {
+	r.Stream = append(r.Stream, messages...)
+	return r
+}
+
+// WithFault sets the fault to simulate a network-level failure
+func (r *Response) WithFault(f FaultType) *Response {
+	r.Fault = f
+	return r
+}
+
 // WithRedirect sets redirect url
 // Use WithStatusCode if want to customize the redirect code
 func (r *Response) WithRedirect(url string) *Response {
@@ -340,6 +402,26 @@ func (r *Response) LoadBodyFromTemplate(ctx context.Context, data *TemplateData)
 
 // WriteTo writes response
 func (r *Response) WriteTo(ctx context.Context, w http.ResponseWriter) error {
+	if len(r.Fault) > 0 {
+		return r.writeFault(ctx, w)
+	}
+
+	if len(r.Stream) > 0 {
+		return r.writeStream(ctx, w)
+	}
+
+	r.writeHeader(w)
+	if _, err := w.Write(r.Body); err != nil {
+		log.Error(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+
+	return nil
+}
+
+// writeHeader writes headers, cookies and status code
+func (r *Response) writeHeader(w http.ResponseWriter) {
 	for k, v := range r.Header {
 		w.Header().Set(k, v)
 	}
@@ -353,14 +435,6 @@ func (r *Response) WriteTo(ctx context.Context, w http.ResponseWriter) error {
 	} else {
 		w.WriteHeader(r.StatusCode)
 	}
-
-	if _, err := w.Write(r.Body); err != nil {
-		log.Error(ctx, err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return err
-	}
-
-	return nil
 }
 
 // Scan implements sqlx JSON scan method
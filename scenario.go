@@ -0,0 +1,224 @@
+package rio
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// ScenarioStateStarted is the initial state of all scenarios
+const ScenarioStateStarted = "started"
+
+// The maximum number of attempts to select a stub if scenario state is changed by a concurrent request
+const maxScenarioAttempts = 3
+
+// Scenario holds the current state of a named scenario in a namespace
+type Scenario struct {
+	ID        int64     `json:"id" yaml:"id"`
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	Name      string    `json:"name" yaml:"name"`
+	State     string    `json:"state" yaml:"state"`
+	CreatedAt time.Time `json:"created_at,omitempty" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" yaml:"updated_at"`
+}
+
+// Validate returns an non-nil error if scenario is invalid
+func (s *Scenario) Validate(ctx context.Context) error {
+	if len(s.Name) == 0 {
+		err := errors.New("missing scenario name")
+		log.Error(ctx, err)
+		return err
+	}
+
+	if len(s.State) == 0 {
+		err := errors.New("missing scenario state")
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// ScenarioQueryOption defines query option for scenarios
+type ScenarioQueryOption struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+
+	// Name is optional, all scenarios in namespace are selected if it is empty
+	Name string `json:"name" yaml:"name"`
+}
+
+// StubScenario defines the scenario settings of a stub
+// The stub is only matched if the current state of scenario is equal to the required state
+// Then the scenario is moved to the new state if it is defined
+type StubScenario struct {
+	// Name is the name of scenario. Stubs with the same name share the same state
+	Name string `json:"name" yaml:"name"`
+
+	// RequiredState is the state which is required to match the stub
+	// If it is empty, the stub is matched with any state
+	RequiredState string `json:"required_state,omitempty" yaml:"required_state"`
+
+	// NewState is the state which the scenario is moved to when the stub is matched
+	// This is optional, the state is not changed if it is empty
+	NewState string `json:"new_state,omitempty" yaml:"new_state"`
+}
+
+// Validate returns an non-nil error if stub scenario is invalid
+func (r *StubScenario) Validate(ctx context.Context) error {
+	if len(r.Name) == 0 {
+		err := errors.New("missing scenario name")
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// Scan implements sqlx JSON scan method
+func (r *StubScenario) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, &r)
+	case string:
+		return json.Unmarshal([]byte(v), &r)
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements sqlx JSON value method
+func (r StubScenario) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// SelectStubWithScenario selects the stub with the highest priority from the matched stubs
+// Stubs which require another state of their scenario are ignored
+// If the selected stub defines a new state, the scenario is atomically moved to the new state
+// Returns nil if there is no stub for the current state of scenarios
+func SelectStubWithScenario(ctx context.Context, store StubStore, namespace string, stubs []*Stub) (*Stub, error) {
+	if !hasScenario(stubs) {
+		return SelectStubs(stubs), nil
+	}
+
+	for i := 0; i < maxScenarioAttempts; i++ {
+		states, err := getScenarioStates(ctx, store, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates := make([]*Stub, 0, len(stubs))
+		for _, stub := range stubs {
+			if stub.Scenario == nil || len(stub.Scenario.RequiredState) == 0 || stub.Scenario.RequiredState == states.get(stub.Scenario.Name) {
+				candidates = append(candidates, stub)
+			}
+		}
+
+		stub := SelectStubs(candidates)
+		if stub == nil || stub.Scenario == nil || len(stub.Scenario.NewState) == 0 {
+			return stub, nil
+		}
+
+		currentState := states.get(stub.Scenario.Name)
+		if currentState == stub.Scenario.NewState {
+			return stub, nil
+		}
+
+		transited, err := store.TransitScenarioState(ctx, namespace, stub.Scenario.Name, currentState, stub.Scenario.NewState)
+		if err != nil {
+			return nil, err
+		}
+
+		if transited {
+			log.Info(ctx, "scenario", stub.Scenario.Name, "moved from", currentState, "to", stub.Scenario.NewState)
+			return stub, nil
+		}
+
+		log.Info(ctx, "scenario state has been changed by another request, retry", stub.Scenario.Name)
+	}
+
+	err := fmt.Errorf("cannot select stub due to concurrent scenario state changes in namespace %s", namespace)
+	log.Error(ctx, err)
+	return nil, err
+}
+
+// DiagnoseScenario explains why the matched stubs are filtered out by the current scenario states
+func DiagnoseScenario(ctx context.Context, store StubStore, namespace string, stubs []*Stub) (Diagnostics, error) {
+	states, err := getScenarioStates(ctx, store, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := make(Diagnostics, 0, len(stubs))
+	for _, stub := range stubs {
+		if stub.Scenario == nil || len(stub.Scenario.RequiredState) == 0 {
+			continue
+		}
+
+		currentState := states.get(stub.Scenario.Name)
+		if currentState == stub.Scenario.RequiredState {
+			continue
+		}
+
+		totalRules := countRules(stub.Request) + 1
+		diagnostics = append(diagnostics, &StubDiagnostic{
+			StubID:       stub.ID,
+			Description:  stub.Description,
+			MatchedRules: totalRules - 1,
+			TotalRules:   totalRules,
+			Mismatches: []*Mismatch{{
+				Field:    "scenario",
+				Key:      stub.Scenario.Name,
+				Operator: OpEqualTo,
+				Expected: stub.Scenario.RequiredState,
+				Actual:   currentState,
+				Reason:   fmt.Sprintf("scenario %s is in state %s", stub.Scenario.Name, currentState),
+			}},
+		})
+	}
+
+	if len(diagnostics) > maxDiagnostics {
+		diagnostics = diagnostics[:maxDiagnostics]
+	}
+
+	return diagnostics, nil
+}
+
+func hasScenario(stubs []*Stub) bool {
+	for _, stub := range stubs {
+		if stub.Scenario != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+type scenarioStates map[string]string
+
+// get returns the current state, the initial state is returned if scenario has not been started
+func (s scenarioStates) get(name string) string {
+	if state, ok := s[name]; ok {
+		return state
+	}
+
+	return ScenarioStateStarted
+}
+
+func getScenarioStates(ctx context.Context, store StubStore, namespace string) (scenarioStates, error) {
+	scenarios, err := store.GetScenarios(ctx, &ScenarioQueryOption{Namespace: namespace})
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(scenarioStates, len(scenarios))
+	for _, scenario := range scenarios {
+		states[scenario.Name] = scenario.State
+	}
+
+	return states, nil
+}
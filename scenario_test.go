@@ -0,0 +1,143 @@
+package rio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio/internal/netkit"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectStubWithScenario(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	namespace := uuid.NewString()
+	name := uuid.NewString()
+
+	pending := NewStub().WithID(2).InScenario(name).WhenScenarioStateIs(ScenarioStateStarted).WillSetStateTo("pending")
+	done := NewStub().WithID(3).InScenario(name).WhenScenarioStateIs("pending").WillSetStateTo("done")
+	fallback := NewStub().WithID(1)
+	stubs := []*Stub{pending, done, fallback}
+
+	stub, err := SelectStubWithScenario(ctx, store, namespace, stubs)
+	require.NoError(t, err)
+	require.Equal(t, pending.ID, stub.ID)
+
+	stub, err = SelectStubWithScenario(ctx, store, namespace, stubs)
+	require.NoError(t, err)
+	require.Equal(t, done.ID, stub.ID)
+
+	stub, err = SelectStubWithScenario(ctx, store, namespace, stubs)
+	require.NoError(t, err)
+	require.Equal(t, fallback.ID, stub.ID)
+
+	stub, err = SelectStubWithScenario(ctx, store, namespace, []*Stub{pending, done})
+	require.NoError(t, err)
+	require.Nil(t, stub)
+
+	scenarios, err := store.GetScenarios(ctx, &ScenarioQueryOption{Namespace: namespace, Name: name})
+	require.NoError(t, err)
+	require.Len(t, scenarios, 1)
+	require.Equal(t, "done", scenarios[0].State)
+
+	require.NoError(t, store.ResetScenarios(ctx, &ScenarioQueryOption{Namespace: namespace}))
+
+	stub, err = SelectStubWithScenario(ctx, store, namespace, stubs)
+	require.NoError(t, err)
+	require.Equal(t, pending.ID, stub.ID)
+}
+
+func TestSelectStubWithScenario_NoScenario(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stubs := []*Stub{NewStub().WithID(1), NewStub().WithID(2).WithWeight(10)}
+
+	stub, err := SelectStubWithScenario(ctx, NewStubMemory(), "", stubs)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), stub.ID)
+}
+
+func TestStubMemory_TransitScenarioState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	namespace := uuid.NewString()
+	name := uuid.NewString()
+
+	transited, err := store.TransitScenarioState(ctx, namespace, name, "pending", "done")
+	require.NoError(t, err)
+	require.False(t, transited)
+
+	transited, err = store.TransitScenarioState(ctx, namespace, name, ScenarioStateStarted, "pending")
+	require.NoError(t, err)
+	require.True(t, transited)
+
+	transited, err = store.TransitScenarioState(ctx, namespace, name, ScenarioStateStarted, "done")
+	require.NoError(t, err)
+	require.False(t, transited)
+
+	require.NoError(t, store.SetScenarioState(ctx, &Scenario{Namespace: namespace, Name: name, State: "failed"}))
+
+	transited, err = store.TransitScenarioState(ctx, namespace, name, "failed", "done")
+	require.NoError(t, err)
+	require.True(t, transited)
+}
+
+func TestLocalServer_Scenario(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t)
+	name := uuid.NewString()
+
+	require.NoError(t, NewStub().For("GET", Contains("job/status")).
+		InScenario(name).
+		WhenScenarioStateIs(ScenarioStateStarted).
+		WillSetStateTo("pending").
+		WillReturn(NewResponse().WithBody(MustToJSON(types.Map{"status": "PENDING"}))).
+		Send(ctx, server))
+
+	require.NoError(t, NewStub().For("GET", Contains("job/status")).
+		InScenario(name).
+		WhenScenarioStateIs("pending").
+		WillSetStateTo("done").
+		WillReturn(NewResponse().WithBody(MustToJSON(types.Map{"status": "DONE"}))).
+		Send(ctx, server))
+
+	requestURL := server.GetURL(ctx) + "/job/status"
+	for _, expected := range []string{"PENDING", "DONE"} {
+		res, err := netkit.Get[types.Map](ctx, requestURL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, expected, res.Body.ForceString("status"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	require.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// Both stubs are matched by request, but filtered out by the scenario state
+	body := struct {
+		Message     string      `json:"message"`
+		Diagnostics Diagnostics `json:"diagnostics"`
+	}{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	require.Equal(t, "no matched stub found for current scenario state", body.Message)
+	require.Len(t, body.Diagnostics, 2)
+	require.Equal(t, "scenario", body.Diagnostics[0].Mismatches[0].Field)
+	require.Equal(t, name, body.Diagnostics[0].Mismatches[0].Key)
+	require.Equal(t, "done", body.Diagnostics[0].Mismatches[0].Actual)
+	require.Contains(t, body.Diagnostics.String(), "scenario "+name+" is in state done")
+}
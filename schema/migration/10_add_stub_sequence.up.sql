@@ -0,0 +1,16 @@
+ALTER TABLE `rio_services`.`stubs`
+ADD COLUMN `sequence` JSON DEFAULT NULL;
+
+-- -----------------------------------------------------
+-- Table `rio_services`.`stub_counters`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `rio_services`.`stub_counters` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(255) NOT NULL DEFAULT '',
+  `stub_id` BIGINT(20) NOT NULL DEFAULT 0,
+  `counter` BIGINT(20) NOT NULL DEFAULT 0,
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_namespace_stub_id` (`namespace`, `stub_id`))
+ENGINE = InnoDB;
@@ -0,0 +1,27 @@
+ALTER TABLE `rio_services`.`stubs`
+ADD COLUMN `callbacks` JSON DEFAULT NULL;
+
+-- -----------------------------------------------------
+-- Table `rio_services`.`callback_results`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `rio_services`.`callback_results` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(255) NOT NULL DEFAULT '',
+  `tag` VARCHAR(127) DEFAULT '',
+  `stub_id` BIGINT(20) NOT NULL DEFAULT 0,
+  `incoming_request_id` BIGINT(20) NOT NULL DEFAULT 0,
+  `url` LONGTEXT NOT NULL,
+  `method` VARCHAR(31) NOT NULL DEFAULT '',
+  `header` JSON NULL,
+  `body` BLOB NULL,
+  `status_code` INT NOT NULL DEFAULT 0,
+  `attempts` INT NOT NULL DEFAULT 0,
+  `delivered` TINYINT(1) NOT NULL DEFAULT 0,
+  `error` TEXT NULL,
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX `idx_tag` (`tag`),
+  INDEX `idx_namespace_stub_id` (`namespace`, `stub_id`),
+  INDEX `idx_incoming_request_id` (`incoming_request_id`))
+ENGINE = InnoDB;
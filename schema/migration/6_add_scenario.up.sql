@@ -0,0 +1,16 @@
+ALTER TABLE `rio_services`.`stubs`
+ADD COLUMN `scenario` JSON DEFAULT NULL;
+
+-- -----------------------------------------------------
+-- Table `rio_services`.`scenarios`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `rio_services`.`scenarios` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(255) NOT NULL DEFAULT '',
+  `name` VARCHAR(255) NOT NULL DEFAULT '',
+  `state` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_namespace_name` (`namespace`, `name`))
+ENGINE = InnoDB;
@@ -0,0 +1,2 @@
+ALTER TABLE `rio_services`.`incoming_requests`
+ADD COLUMN `diagnostics` JSON DEFAULT NULL;
\ No newline at end of file
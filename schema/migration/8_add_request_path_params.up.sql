@@ -0,0 +1,2 @@
+ALTER TABLE `rio_services`.`incoming_requests`
+ADD COLUMN `path_params` JSON DEFAULT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE `rio_services`.`incoming_requests`
+ADD COLUMN `host` VARCHAR(255) NOT NULL DEFAULT '',
+ADD COLUMN `scheme` VARCHAR(15) NOT NULL DEFAULT '';
@@ -0,0 +1,75 @@
+CREATE TABLE IF NOT EXISTS stubs (
+  id BIGSERIAL PRIMARY KEY,
+  namespace VARCHAR(255) NOT NULL DEFAULT '',
+  request JSONB NULL,
+  response JSONB NULL,
+  weight INT NOT NULL DEFAULT 0,
+  active BOOLEAN NOT NULL DEFAULT FALSE,
+  proxy JSONB NULL,
+  settings JSONB NULL,
+  scenario JSONB NULL,
+  description VARCHAR(511) NOT NULL DEFAULT '',
+  tag VARCHAR(127) NOT NULL DEFAULT '',
+  protocol VARCHAR(31) NOT NULL DEFAULT 'http',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stubs_tag ON stubs (tag);
+CREATE INDEX IF NOT EXISTS idx_stubs_namespace ON stubs (namespace);
+CREATE INDEX IF NOT EXISTS idx_stubs_protocol ON stubs (protocol);
+CREATE INDEX IF NOT EXISTS idx_stubs_updated_at ON stubs (updated_at);
+
+CREATE TABLE IF NOT EXISTS incoming_requests (
+  id BIGSERIAL PRIMARY KEY,
+  namespace VARCHAR(255) NOT NULL DEFAULT '',
+  tag VARCHAR(127) NOT NULL DEFAULT '',
+  url TEXT NOT NULL DEFAULT '',
+  method VARCHAR(31) NOT NULL DEFAULT '',
+  header JSONB NULL,
+  body BYTEA NULL,
+  stub_id BIGINT NOT NULL DEFAULT 0,
+  curl TEXT NOT NULL DEFAULT '',
+  diagnostics JSONB NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_requests_tag ON incoming_requests (tag);
+CREATE INDEX IF NOT EXISTS idx_incoming_requests_namespace ON incoming_requests (namespace);
+
+CREATE TABLE IF NOT EXISTS protos (
+  id BIGSERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  file_id VARCHAR(63) NOT NULL DEFAULT '',
+  methods JSONB NULL,
+  types JSONB NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_protos_updated_at ON protos (updated_at);
+
+CREATE TABLE IF NOT EXISTS scenarios (
+  id BIGSERIAL PRIMARY KEY,
+  namespace VARCHAR(255) NOT NULL DEFAULT '',
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  state VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scenarios_namespace_name ON scenarios (namespace, name);
+
+-- Postgres does not support ON UPDATE CURRENT_TIMESTAMP, the cache relies on updated_at to detect changes
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_stubs_updated_at BEFORE UPDATE ON stubs FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+CREATE TRIGGER trg_incoming_requests_updated_at BEFORE UPDATE ON incoming_requests FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+CREATE TRIGGER trg_protos_updated_at BEFORE UPDATE ON protos FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+CREATE TRIGGER trg_scenarios_updated_at BEFORE UPDATE ON scenarios FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
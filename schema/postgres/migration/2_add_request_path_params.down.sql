@@ -0,0 +1 @@
+-- Not required
\ No newline at end of file
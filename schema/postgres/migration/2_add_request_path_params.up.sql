@@ -0,0 +1 @@
+ALTER TABLE incoming_requests ADD COLUMN IF NOT EXISTS path_params JSONB NULL;
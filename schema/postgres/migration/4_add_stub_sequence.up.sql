@@ -0,0 +1,14 @@
+ALTER TABLE stubs ADD COLUMN IF NOT EXISTS sequence JSONB NULL;
+
+CREATE TABLE IF NOT EXISTS stub_counters (
+  id BIGSERIAL PRIMARY KEY,
+  namespace VARCHAR(255) NOT NULL DEFAULT '',
+  stub_id BIGINT NOT NULL DEFAULT 0,
+  counter BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stub_counters_namespace_stub_id ON stub_counters (namespace, stub_id);
+
+CREATE TRIGGER trg_stub_counters_updated_at BEFORE UPDATE ON stub_counters FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
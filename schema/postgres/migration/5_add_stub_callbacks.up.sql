@@ -0,0 +1,25 @@
+ALTER TABLE stubs ADD COLUMN IF NOT EXISTS callbacks JSONB NULL;
+
+CREATE TABLE IF NOT EXISTS callback_results (
+  id BIGSERIAL PRIMARY KEY,
+  namespace VARCHAR(255) NOT NULL DEFAULT '',
+  tag VARCHAR(127) NOT NULL DEFAULT '',
+  stub_id BIGINT NOT NULL DEFAULT 0,
+  incoming_request_id BIGINT NOT NULL DEFAULT 0,
+  url TEXT NOT NULL DEFAULT '',
+  method VARCHAR(31) NOT NULL DEFAULT '',
+  header JSONB NULL,
+  body BYTEA NULL,
+  status_code INT NOT NULL DEFAULT 0,
+  attempts INT NOT NULL DEFAULT 0,
+  delivered BOOLEAN NOT NULL DEFAULT FALSE,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_callback_results_tag ON callback_results (tag);
+CREATE INDEX IF NOT EXISTS idx_callback_results_namespace_stub_id ON callback_results (namespace, stub_id);
+CREATE INDEX IF NOT EXISTS idx_callback_results_incoming_request_id ON callback_results (incoming_request_id);
+
+CREATE TRIGGER trg_callback_results_updated_at BEFORE UPDATE ON callback_results FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
@@ -32,6 +32,9 @@ CREATE TABLE IF NOT EXISTS `rio_services`.`stubs` (
   `description` VARCHAR(511) NOT NULL DEFAULT '',
   `tag` VARCHAR(127) DEFAULT '',
   `protocol` VARCHAR(31) DEFAULT 'http',
+  `scenario` JSON NULL,
+  `sequence` JSON NULL,
+  `callbacks` JSON NULL,
   `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`id`),
@@ -55,6 +58,10 @@ CREATE TABLE IF NOT EXISTS `rio_services`.`incoming_requests` (
   `body` BLOB NULL,
   `stub_id` BIGINT(20) NOT NULL DEFAULT 0,
   `curl` LONGTEXT NOT NULL,
+  `diagnostics` JSON NULL,
+  `path_params` JSON NULL,
+  `host` VARCHAR(255) NOT NULL DEFAULT '',
+  `scheme` VARCHAR(15) NOT NULL DEFAULT '',
   `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`id`),
@@ -77,6 +84,59 @@ CREATE TABLE IF NOT EXISTS `rio_services`.`protos` (
   INDEX `idx_updated_at` (`updated_at`))
 ENGINE = InnoDB;
 
+-- -----------------------------------------------------
+-- Table `rio_services`.`scenarios`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `rio_services`.`scenarios` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(255) NOT NULL DEFAULT '',
+  `name` VARCHAR(255) NOT NULL DEFAULT '',
+  `state` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_namespace_name` (`namespace`, `name`))
+ENGINE = InnoDB;
+
+-- -----------------------------------------------------
+-- Table `rio_services`.`stub_counters`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `rio_services`.`stub_counters` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(255) NOT NULL DEFAULT '',
+  `stub_id` BIGINT(20) NOT NULL DEFAULT 0,
+  `counter` BIGINT(20) NOT NULL DEFAULT 0,
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_namespace_stub_id` (`namespace`, `stub_id`))
+ENGINE = InnoDB;
+
+-- -----------------------------------------------------
+-- Table `rio_services`.`callback_results`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `rio_services`.`callback_results` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(255) NOT NULL DEFAULT '',
+  `tag` VARCHAR(127) DEFAULT '',
+  `stub_id` BIGINT(20) NOT NULL DEFAULT 0,
+  `incoming_request_id` BIGINT(20) NOT NULL DEFAULT 0,
+  `url` LONGTEXT NOT NULL,
+  `method` VARCHAR(31) NOT NULL DEFAULT '',
+  `header` JSON NULL,
+  `body` BLOB NULL,
+  `status_code` INT NOT NULL DEFAULT 0,
+  `attempts` INT NOT NULL DEFAULT 0,
+  `delivered` TINYINT(1) NOT NULL DEFAULT 0,
+  `error` TEXT NULL,
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX `idx_tag` (`tag`),
+  INDEX `idx_namespace_stub_id` (`namespace`, `stub_id`),
+  INDEX `idx_incoming_request_id` (`incoming_request_id`))
+ENGINE = InnoDB;
+
 SET SQL_MODE=@OLD_SQL_MODE;
 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS;
 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS;
@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS stubs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  namespace TEXT NOT NULL DEFAULT '',
+  request TEXT NULL,
+  response TEXT NULL,
+  weight INTEGER NOT NULL DEFAULT 0,
+  active INTEGER NOT NULL DEFAULT 0,
+  proxy TEXT NULL,
+  settings TEXT NULL,
+  scenario TEXT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  tag TEXT NOT NULL DEFAULT '',
+  protocol TEXT NOT NULL DEFAULT 'http',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stubs_tag ON stubs (tag);
+CREATE INDEX IF NOT EXISTS idx_stubs_namespace ON stubs (namespace);
+CREATE INDEX IF NOT EXISTS idx_stubs_protocol ON stubs (protocol);
+CREATE INDEX IF NOT EXISTS idx_stubs_updated_at ON stubs (updated_at);
+
+CREATE TABLE IF NOT EXISTS incoming_requests (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  namespace TEXT NOT NULL DEFAULT '',
+  tag TEXT NOT NULL DEFAULT '',
+  url TEXT NOT NULL DEFAULT '',
+  method TEXT NOT NULL DEFAULT '',
+  header TEXT NULL,
+  body BLOB NULL,
+  stub_id INTEGER NOT NULL DEFAULT 0,
+  curl TEXT NOT NULL DEFAULT '',
+  diagnostics TEXT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_requests_tag ON incoming_requests (tag);
+CREATE INDEX IF NOT EXISTS idx_incoming_requests_namespace ON incoming_requests (namespace);
+
+CREATE TABLE IF NOT EXISTS protos (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL DEFAULT '',
+  file_id TEXT NOT NULL DEFAULT '',
+  methods TEXT NULL,
+  types TEXT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_protos_updated_at ON protos (updated_at);
+
+CREATE TABLE IF NOT EXISTS scenarios (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  namespace TEXT NOT NULL DEFAULT '',
+  name TEXT NOT NULL DEFAULT '',
+  state TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scenarios_namespace_name ON scenarios (namespace, name);
@@ -0,0 +1 @@
+ALTER TABLE incoming_requests ADD COLUMN path_params TEXT NULL;
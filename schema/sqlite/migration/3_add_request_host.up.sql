@@ -0,0 +1,2 @@
+ALTER TABLE incoming_requests ADD COLUMN host VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE incoming_requests ADD COLUMN scheme VARCHAR(15) NOT NULL DEFAULT '';
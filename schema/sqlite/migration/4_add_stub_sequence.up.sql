@@ -0,0 +1,12 @@
+ALTER TABLE stubs ADD COLUMN sequence TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS stub_counters (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  namespace TEXT NOT NULL DEFAULT '',
+  stub_id INTEGER NOT NULL DEFAULT 0,
+  counter INTEGER NOT NULL DEFAULT 0,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stub_counters_namespace_stub_id ON stub_counters (namespace, stub_id);
@@ -0,0 +1,23 @@
+ALTER TABLE stubs ADD COLUMN callbacks TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS callback_results (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  namespace TEXT NOT NULL DEFAULT '',
+  tag TEXT NOT NULL DEFAULT '',
+  stub_id INTEGER NOT NULL DEFAULT 0,
+  incoming_request_id INTEGER NOT NULL DEFAULT 0,
+  url TEXT NOT NULL DEFAULT '',
+  method TEXT NOT NULL DEFAULT '',
+  header TEXT NULL,
+  body BLOB NULL,
+  status_code INTEGER NOT NULL DEFAULT 0,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  delivered BOOLEAN NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_callback_results_tag ON callback_results (tag);
+CREATE INDEX IF NOT EXISTS idx_callback_results_namespace_stub_id ON callback_results (namespace, stub_id);
+CREATE INDEX IF NOT EXISTS idx_callback_results_incoming_request_id ON callback_results (incoming_request_id);
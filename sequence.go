@@ -0,0 +1,229 @@
+package rio
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2" // nolint:gosec
+	"time"
+
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// Defines the policies to select the response of a sequence
+const (
+	// SequencePolicySequential returns the responses in order, then sticks to the last one
+	SequencePolicySequential SequencePolicy = "sequential"
+
+	// SequencePolicyRoundRobin returns the responses in order, then restarts from the first one
+	SequencePolicyRoundRobin SequencePolicy = "round_robin"
+
+	// SequencePolicyRandom returns a random response by weights
+	SequencePolicyRandom SequencePolicy = "random"
+)
+
+// SequencePolicy is alias for the policy of response sequence
+type SequencePolicy string
+
+// StubCounter holds the number of times a stub has returned a response from its sequence in a namespace
+type StubCounter struct {
+	ID        int64     `json:"id" yaml:"id"`
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	StubID    int64     `json:"stub_id" yaml:"stub_id"`
+	Counter   int64     `json:"counter" yaml:"counter"`
+	CreatedAt time.Time `json:"created_at,omitempty" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" yaml:"updated_at"`
+}
+
+// ResponseSequence defines an ordered list of responses of a stub
+// This is to emulate polling APIs without creating many stubs. For example: the first call returns PENDING, the next calls return DONE
+type ResponseSequence struct {
+	// Policy is optional, default is sequential
+	Policy SequencePolicy `json:"policy,omitempty" yaml:"policy"`
+
+	Responses []*Response `json:"responses" yaml:"responses"`
+
+	// Weights are the relative weights of responses, which are only applied for random policy
+	// This is optional, all responses have the same weight if it is empty
+	Weights []int `json:"weights,omitempty" yaml:"weights"`
+}
+
+// SequentialResponses returns the responses in order, then sticks to the last one
+func SequentialResponses(responses ...*Response) *ResponseSequence {
+	return &ResponseSequence{Policy: SequencePolicySequential, Responses: responses}
+}
+
+// RoundRobinResponses returns the responses in order, then restarts from the first one
+func RoundRobinResponses(responses ...*Response) *ResponseSequence {
+	return &ResponseSequence{Policy: SequencePolicyRoundRobin, Responses: responses}
+}
+
+// RandomResponses returns a random response. Use WithWeights to define the weights of responses
+func RandomResponses(responses ...*Response) *ResponseSequence {
+	return &ResponseSequence{Policy: SequencePolicyRandom, Responses: responses}
+}
+
+// WithWeights sets the weights of responses for random policy
+func (q *ResponseSequence) WithWeights(weights ...int) *ResponseSequence {
+	q.Weights = weights
+	return q
+}
+
+// Validate returns a non-nil error if invalid
+func (q *ResponseSequence) Validate(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+
+	if err := q.validate(); err != nil {
+		log.Error(ctx, err)
+		return err
+	}
+
+	for _, r := range q.Responses {
+		if err := r.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *ResponseSequence) validate() error {
+	switch q.Policy {
+	case "", SequencePolicySequential, SequencePolicyRoundRobin, SequencePolicyRandom:
+	default:
+		return fmt.Errorf("unsupported sequence policy %s", q.Policy)
+	}
+
+	if len(q.Responses) == 0 {
+		return errors.New("missing responses in sequence")
+	}
+
+	for _, r := range q.Responses {
+		if r == nil {
+			return errors.New("response in sequence must not be nil")
+		}
+	}
+
+	if len(q.Weights) == 0 {
+		return nil
+	}
+
+	if len(q.Weights) != len(q.Responses) {
+		return errors.New("the number of weights must be equal to the number of responses")
+	}
+
+	total := 0
+	for _, w := range q.Weights {
+		if w < 0 {
+			return errors.New("weight must not be negative")
+		}
+
+		total += w
+	}
+
+	if total == 0 {
+		return errors.New("total weight must be positive")
+	}
+
+	return nil
+}
+
+// Clone clones sequence and its responses
+func (q *ResponseSequence) Clone() *ResponseSequence {
+	if q == nil {
+		return nil
+	}
+
+	nq := &ResponseSequence{
+		Policy:    q.Policy,
+		Responses: make([]*Response, len(q.Responses)),
+		Weights:   append([]int(nil), q.Weights...),
+	}
+
+	for i, r := range q.Responses {
+		nq.Responses[i] = r.Clone()
+	}
+
+	return nq
+}
+
+// index returns the index of response for the counter which starts from 1
+func (q *ResponseSequence) index(counter int64) int {
+	n := int64(len(q.Responses))
+	if q.Policy == SequencePolicyRoundRobin {
+		return int((counter - 1) % n)
+	}
+
+	return int(min(counter, n) - 1)
+}
+
+// randomIndex returns a random index by weights
+func (q *ResponseSequence) randomIndex() int {
+	if len(q.Weights) == 0 {
+		return rand.IntN(len(q.Responses))
+	}
+
+	total := 0
+	for _, w := range q.Weights {
+		total += w
+	}
+
+	v := rand.IntN(total)
+	for i, w := range q.Weights {
+		if v < w {
+			return i
+		}
+
+		v -= w
+	}
+
+	return len(q.Weights) - 1
+}
+
+// Scan implements sqlx JSON scan method
+func (q *ResponseSequence) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, &q)
+	case string:
+		return json.Unmarshal([]byte(v), &q)
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements sqlx JSON value method
+func (q ResponseSequence) Value() (driver.Value, error) {
+	return json.Marshal(q)
+}
+
+// SelectSequenceResponse returns a copy of stub whose response is selected from its sequence
+// The counter is tracked per stub and namespace by the store, so it is consistent across concurrent requests and servers
+// The stub is returned as is if it does not define a sequence
+func SelectSequenceResponse(ctx context.Context, store StubStore, namespace string, stub *Stub) (*Stub, error) {
+	if stub.Sequence == nil || len(stub.Sequence.Responses) == 0 {
+		return stub, nil
+	}
+
+	var index int
+	if stub.Sequence.Policy == SequencePolicyRandom {
+		index = stub.Sequence.randomIndex()
+	} else {
+		counter, err := store.IncreaseStubCounter(ctx, namespace, stub.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		index = stub.Sequence.index(counter)
+	}
+
+	log.Info(ctx, "selected response", index, "in sequence of stub", stub.ID)
+
+	selected := *stub
+	selected.Response = stub.Sequence.Responses[index].Clone()
+	return &selected, nil
+}
@@ -0,0 +1,146 @@
+package rio
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseSequence_Index(t *testing.T) {
+	t.Parallel()
+
+	responses := []*Response{NewResponse(), NewResponse(), NewResponse()}
+
+	sequential := SequentialResponses(responses...)
+	roundRobin := RoundRobinResponses(responses...)
+	defaultPolicy := &ResponseSequence{Responses: responses}
+
+	expectedSequential := []int{0, 1, 2, 2, 2}
+	expectedRoundRobin := []int{0, 1, 2, 0, 1}
+	for i := range expectedSequential {
+		counter := int64(i + 1)
+		require.Equal(t, expectedSequential[i], sequential.index(counter))
+		require.Equal(t, expectedSequential[i], defaultPolicy.index(counter))
+		require.Equal(t, expectedRoundRobin[i], roundRobin.index(counter))
+	}
+}
+
+func TestResponseSequence_RandomIndex(t *testing.T) {
+	t.Parallel()
+
+	q := RandomResponses(NewResponse(), NewResponse(), NewResponse()).WithWeights(3, 0, 1)
+	counts := make([]int, 3)
+	for i := 0; i < 1000; i++ {
+		counts[q.randomIndex()]++
+	}
+
+	require.Zero(t, counts[1])
+	require.Greater(t, counts[0], counts[2])
+	require.Positive(t, counts[2])
+
+	q = RandomResponses(NewResponse(), NewResponse())
+	for i := 0; i < 100; i++ {
+		require.Contains(t, []int{0, 1}, q.randomIndex())
+	}
+}
+
+func TestResponseSequence_Validate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.NoError(t, SequentialResponses(NewResponse()).Validate(ctx))
+	require.NoError(t, RandomResponses(NewResponse(), NewResponse()).WithWeights(1, 0).Validate(ctx))
+
+	invalidSequences := []*ResponseSequence{
+		SequentialResponses(),
+		SequentialResponses(nil),
+		{Policy: "unknown", Responses: []*Response{NewResponse()}},
+		RandomResponses(NewResponse()).WithWeights(1, 2),
+		RandomResponses(NewResponse(), NewResponse()).WithWeights(0, 0),
+		RandomResponses(NewResponse(), NewResponse()).WithWeights(-1, 2),
+		SequentialResponses(NewResponse().WithFault("unknown")),
+	}
+
+	for _, q := range invalidSequences {
+		require.Error(t, q.Validate(ctx))
+	}
+
+	// Sequence can replace response
+	stub := NewStub().For(http.MethodGet, EqualTo("/order")).WillReturnSequence(SequentialResponses(NewResponse()))
+	stub.Response = nil
+	require.NoError(t, stub.Validate(ctx))
+
+	stub = NewStub().ForGRPC(EqualTo("/offers.v1.OfferService/ValidateOffer")).
+		WillReturnSequence(SequentialResponses(NewResponse(), NewResponse().WithFault(FaultConnectionReset)))
+	require.Error(t, stub.Validate(ctx))
+}
+
+func TestSelectSequenceResponse(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+
+	stub := NewStub().For(http.MethodGet, EqualTo("/order")).WillReturn(NewResponse().WithStatusCode(http.StatusTeapot))
+	require.NoError(t, store.Create(ctx, stub))
+
+	selected, err := SelectSequenceResponse(ctx, store, "", stub)
+	require.NoError(t, err)
+	require.Same(t, stub, selected)
+
+	t.Run("sequential", func(t *testing.T) {
+		t.Parallel()
+
+		stub := NewStub().For(http.MethodGet, EqualTo("/order")).WillReturnSequence(SequentialResponses(
+			NewResponse().WithStatusCode(http.StatusAccepted),
+			NewResponse().WithStatusCode(http.StatusOK),
+		))
+		require.NoError(t, store.Create(ctx, stub))
+
+		for _, expected := range []int{http.StatusAccepted, http.StatusOK, http.StatusOK} {
+			selected, err := SelectSequenceResponse(ctx, store, "", stub)
+			require.NoError(t, err)
+			require.Equal(t, expected, selected.Response.StatusCode)
+		}
+
+		// The stub and its sequence are not changed
+		require.Zero(t, stub.Response.StatusCode)
+		require.Equal(t, http.StatusAccepted, stub.Sequence.Responses[0].StatusCode)
+	})
+
+	t.Run("round_robin_concurrent", func(t *testing.T) {
+		t.Parallel()
+
+		stub := NewStub().For(http.MethodGet, EqualTo("/order")).WillReturnSequence(RoundRobinResponses(
+			NewResponse().WithStatusCode(http.StatusOK),
+			NewResponse().WithStatusCode(http.StatusServiceUnavailable),
+		))
+		require.NoError(t, store.Create(ctx, stub))
+
+		var (
+			wg     sync.WaitGroup
+			l      sync.Mutex
+			counts = map[int]int{}
+		)
+
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				selected, err := SelectSequenceResponse(ctx, store, "", stub)
+				require.NoError(t, err)
+
+				l.Lock()
+				counts[selected.Response.StatusCode]++
+				l.Unlock()
+			}()
+		}
+
+		wg.Wait()
+		require.Equal(t, map[int]int{http.StatusOK: 50, http.StatusServiceUnavailable: 50}, counts)
+	})
+}
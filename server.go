@@ -20,8 +20,14 @@ const (
 	createStubsPath       = "/stub/create_many"
 	uploadFilePath        = "/stub/upload"
 	createListRequestPath = "/incoming_request/list"
+	verifyRequestPath     = "/incoming_request/verify"
+	listCallbackPath      = "/callback/list"
+	stubPath              = "/stub/%d"
 )
 
+// ErrStubNotFound is returned when the stub with the given id does not exist
+var ErrStubNotFound = errors.New("stub not found")
+
 var (
 	_ Server = (*LocalServer)(nil)
 	_ Server = (*RemoteServer)(nil)
@@ -49,38 +55,56 @@ type ReplayOption struct {
 	queryOption *IncomingQueryOption
 }
 
+type verificationResponse struct {
+	Result *VerificationResult `json:"result"`
+}
+
+type stubResponse struct {
+	Stub *Stub `json:"stub"`
+}
+
 // Server defines server interface
 type Server interface {
 	SetNamespace(v string)
 	GetURL(ctx context.Context) string
 	Create(ctx context.Context, stubs ...*Stub) error
+	GetStub(ctx context.Context, id int64) (*Stub, error)
+	UpdateStub(ctx context.Context, stub *Stub) error
+	DeleteStub(ctx context.Context, id int64) error
+	ActivateStub(ctx context.Context, id int64) error
+	DeactivateStub(ctx context.Context, id int64) error
 	UploadFile(ctx context.Context, fileID string, file []byte) (string, error)
+	Verify(ctx context.Context, request *RequestMatching, count Count) (*VerificationResult, error)
+	GetCallbacks(ctx context.Context, option *CallbackQueryOption) ([]*CallbackResult, error)
 	Close(ctx context.Context)
 }
 
 // LocalServer is local server for unit test
 type LocalServer struct {
-	server      *httptest.Server
-	stubStore   StubStore
-	handler     *Handler
-	fileStorage fs.FileStorage
-	namespace   string
+	server         *httptest.Server
+	stubStore      StubStore
+	handler        *Handler
+	callbackSender *CallbackSender
+	fileStorage    fs.FileStorage
+	namespace      string
 }
 
 // NewLocalServer returns a new instance
 func NewLocalServer() *LocalServer {
 	stubStore := NewStubMemory()
 	fileStorage := fs.NewLocalStorage(fs.LocalStorageConfig{UseTempDir: true, StoragePath: "uploaded_files"})
-	handler := NewHandler(stubStore, fileStorage)
+	callbackSender := NewCallbackSender(stubStore)
+	handler := NewHandler(stubStore, fileStorage).WithCallbackSender(callbackSender)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handler.Handle)
 
 	return &LocalServer{
-		stubStore:   stubStore,
-		fileStorage: fileStorage,
-		handler:     handler,
-		server:      httptest.NewServer(mux),
+		stubStore:      stubStore,
+		fileStorage:    fileStorage,
+		handler:        handler,
+		callbackSender: callbackSender,
+		server:         httptest.NewServer(mux),
 	}
 }
 
@@ -119,6 +143,64 @@ func (s *LocalServer) Create(ctx context.Context, stubs ...*Stub) error {
 	return s.stubStore.Create(ctx, stubs...)
 }
 
+// GetStub gets a stub by id including inactive stub
+func (s *LocalServer) GetStub(ctx context.Context, id int64) (*Stub, error) {
+	stub, err := s.stubStore.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if stub == nil {
+		log.Error(ctx, ErrStubNotFound, id)
+		return nil, ErrStubNotFound
+	}
+
+	return stub, nil
+}
+
+// UpdateStub replaces the stub which has the same id
+// Namespace, active status and created time are kept unchanged
+func (s *LocalServer) UpdateStub(ctx context.Context, stub *Stub) error {
+	current, err := s.GetStub(ctx, stub.ID)
+	if err != nil {
+		return err
+	}
+
+	stub.Namespace = current.Namespace
+	stub.Active = current.Active
+	stub.CreatedAt = current.CreatedAt
+	return s.stubStore.Update(ctx, stub)
+}
+
+// DeleteStub deletes a stub by id
+func (s *LocalServer) DeleteStub(ctx context.Context, id int64) error {
+	if _, err := s.GetStub(ctx, id); err != nil {
+		return err
+	}
+
+	return s.stubStore.Delete(ctx, id)
+}
+
+// ActivateStub activates a stub by id
+func (s *LocalServer) ActivateStub(ctx context.Context, id int64) error {
+	return s.setStubActive(ctx, id, true)
+}
+
+// DeactivateStub deactivates a stub by id
+func (s *LocalServer) DeactivateStub(ctx context.Context, id int64) error {
+	return s.setStubActive(ctx, id, false)
+}
+
+func (s *LocalServer) setStubActive(ctx context.Context, id int64, active bool) error {
+	stub, err := s.GetStub(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	stub.Active = active
+	return s.stubStore.Update(ctx, stub)
+}
+
 // UploadFile upload file to server
 func (s *LocalServer) UploadFile(ctx context.Context, fileID string, file []byte) (string, error) {
 	_, err := s.fileStorage.UploadFile(ctx, fileID, bytes.NewReader(file))
@@ -135,9 +217,28 @@ func (s *LocalServer) GetIncomingRequests(ctx context.Context, option *IncomingQ
 	return s.stubStore.GetIncomingRequests(ctx, option)
 }
 
+// GetCallbacks gets the results of callbacks. Callbacks are sent in background, so the result might not be available right after the response
+func (s *LocalServer) GetCallbacks(ctx context.Context, option *CallbackQueryOption) ([]*CallbackResult, error) {
+	option.Namespace = s.namespace
+	return s.stubStore.GetCallbackResults(ctx, option)
+}
+
+// Verify verifies the number of captured requests which are matched with the request matching
+// The request matching can be built with the same DSL as stub. For example: NewStub().For("GET", Contains("animal")).Request
+func (s *LocalServer) Verify(ctx context.Context, request *RequestMatching, count Count) (*VerificationResult, error) {
+	requests, err := s.stubStore.GetIncomingRequests(ctx, &IncomingQueryOption{Namespace: s.namespace})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = ContextWithFileStorage(ctx, s.fileStorage)
+	return VerifyRequests(ctx, requests, &Verification{Namespace: s.namespace, Request: request, Count: count})
+}
+
 // Close clean up
 func (s *LocalServer) Close(ctx context.Context) {
 	s.server.Close()
+	s.callbackSender.Close()
 	_ = s.fileStorage.Reset(ctx)
 }
 
@@ -214,6 +315,70 @@ func (s *RemoteServer) Create(ctx context.Context, stubs ...*Stub) error {
 	return nil
 }
 
+// GetStub gets a stub by id including inactive stub
+func (s *RemoteServer) GetStub(ctx context.Context, id int64) (*Stub, error) {
+	return s.sendStubRequest(ctx, http.MethodGet, fmt.Sprintf(stubPath, id), nil)
+}
+
+// UpdateStub replaces the stub which has the same id
+// Active status is not changed, use ActivateStub or DeactivateStub instead
+func (s *RemoteServer) UpdateStub(ctx context.Context, stub *Stub) error {
+	_, err := s.sendStubRequest(ctx, http.MethodPut, fmt.Sprintf(stubPath, stub.ID), stub)
+	return err
+}
+
+// DeleteStub deletes a stub by id
+func (s *RemoteServer) DeleteStub(ctx context.Context, id int64) error {
+	res, err := netkit.SendJSON[netkit.InternalBody[types.Map]](ctx, http.MethodDelete, s.rootURL+fmt.Sprintf(stubPath, id), nil)
+	if err != nil {
+		log.Error(ctx, err)
+		return err
+	}
+
+	return parseStubStatus(ctx, res.StatusCode, res.Body.Message)
+}
+
+// ActivateStub activates a stub by id
+func (s *RemoteServer) ActivateStub(ctx context.Context, id int64) error {
+	_, err := s.sendStubRequest(ctx, http.MethodPost, fmt.Sprintf(stubPath+"/activate", id), nil)
+	return err
+}
+
+// DeactivateStub deactivates a stub by id
+func (s *RemoteServer) DeactivateStub(ctx context.Context, id int64) error {
+	_, err := s.sendStubRequest(ctx, http.MethodPost, fmt.Sprintf(stubPath+"/deactivate", id), nil)
+	return err
+}
+
+func (s *RemoteServer) sendStubRequest(ctx context.Context, method string, path string, body interface{}) (*Stub, error) {
+	requestURL := s.rootURL + path + "?return_encoded=true"
+	res, err := netkit.SendJSON[netkit.InternalBody[stubResponse]](ctx, method, requestURL, body)
+	if err != nil {
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	if err := parseStubStatus(ctx, res.StatusCode, res.Body.Message); err != nil {
+		return nil, err
+	}
+
+	return res.Body.Data.Stub, nil
+}
+
+func parseStubStatus(ctx context.Context, statusCode int, message string) error {
+	switch statusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		log.Error(ctx, ErrStubNotFound, message)
+		return ErrStubNotFound
+	default:
+		err := fmt.Errorf("cannot process stub request: %s", message)
+		log.Error(ctx, err)
+		return err
+	}
+}
+
 // UploadFile upload file to server
 func (s *RemoteServer) UploadFile(ctx context.Context, fileID string, fileBody []byte) (string, error) {
 	request, err := netkit.NewUploadRequest(ctx, s.rootURL+uploadFilePath, fileBody, map[string]string{"file_id": fileID})
@@ -268,6 +433,43 @@ func (s *RemoteServer) GetIncomingRequests(ctx context.Context, option *Incoming
 	return res.Body.Data.Requests, nil
 }
 
+// GetCallbacks gets the results of callbacks. Callbacks are sent in background, so the result might not be available right after the response
+func (s *RemoteServer) GetCallbacks(ctx context.Context, option *CallbackQueryOption) ([]*CallbackResult, error) {
+	option.Namespace = s.namespace
+	res, err := netkit.PostJSON[netkit.InternalBody[CallbackResults]](ctx, s.rootURL+listCallbackPath, option)
+	if err != nil {
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		err := errors.New("cannot get callbacks")
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	return res.Body.Data.Callbacks, nil
+}
+
+// Verify verifies the number of captured requests which are matched with the request matching
+// The request matching can be built with the same DSL as stub. For example: NewStub().For("GET", Contains("animal")).Request
+func (s *RemoteServer) Verify(ctx context.Context, request *RequestMatching, count Count) (*VerificationResult, error) {
+	verification := &Verification{Namespace: s.namespace, Request: request, Count: count}
+	res, err := netkit.PostJSON[netkit.InternalBody[verificationResponse]](ctx, s.rootURL+verifyRequestPath, verification)
+	if err != nil {
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK || res.Body.Data.Result == nil {
+		err := fmt.Errorf("cannot verify requests: %s", res.Body.Message)
+		log.Error(ctx, err)
+		return nil, err
+	}
+
+	return res.Body.Data.Result, nil
+}
+
 // ReplayOnShadowServer replays incoming requests (from remote server) to a shadow server (local server)
 // By default, only the last request will be replayed. Use option to change replay option
 // This is to debug the stub on a remote server using IDE
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hungdv136/rio/internal/netkit"
@@ -95,6 +96,63 @@ func TestLocalServer_Deactivate(t *testing.T) {
 	require.NoError(t, res.Body.Close())
 }
 
+func TestLocalServer_Delay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t)
+
+	require.NoError(t, NewStub().For("GET", Contains("animal/slow")).
+		ShouldDelayWith(UniformDelay(time.Hour, 2*time.Hour)).
+		Send(ctx, server))
+
+	require.NoError(t, NewStub().For("GET", Contains("animal/trickle")).
+		ShouldDelayWith(FixedDelay(10*time.Millisecond, 0)).
+		ShouldTrickleBody(50*time.Millisecond, 5).
+		WillReturn(NewResponse().WithBody(MustToJSON(types.Map{"name": "trickle"}))).
+		Send(ctx, server))
+
+	// The cancelled client does not wait for the delay
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(timeoutCtx, http.MethodGet, server.GetURL(ctx)+"/animal/slow", nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = netkit.SendRequest(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	start = time.Now()
+	res, err := netkit.Get[types.Map](ctx, server.GetURL(ctx)+"/animal/trickle")
+	require.NoError(t, err)
+	require.Equal(t, "trickle", res.Body.ForceString("name"))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestLocalServer_Sequence(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t)
+
+	require.NoError(t, NewStub().For("GET", Contains("order/status")).
+		WillReturnSequence(SequentialResponses(
+			JSONResponse(types.Map{"status": "pending"}),
+			JSONResponse(types.Map{"status": "processing"}),
+			JSONResponse(types.Map{"status": "done"}),
+		)).
+		Send(ctx, server))
+
+	requestURL := server.GetURL(ctx) + "/order/status"
+	for _, expected := range []string{"pending", "processing", "done", "done"} {
+		res, err := netkit.Get[types.Map](ctx, requestURL)
+		require.NoError(t, err)
+		require.Equal(t, expected, res.Body.ForceString("status"))
+	}
+}
+
 func TestLocalServer_ReserveProxy(t *testing.T) {
 	t.Parallel()
 
@@ -198,3 +256,187 @@ func TestRemoteServer_ReplayOnShadowServer(t *testing.T) {
 	err = remoteServer.ReplayOnShadowServer(ctx)
 	require.NoError(t, err)
 }
+
+func TestLocalServer_Verify(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t).WithNamespace(uuid.NewString())
+	animalName := uuid.NewString()
+
+	require.NoError(t, NewStub().For("POST", Contains("animal/create")).
+		WillReturn(NewResponse().WithBody(MustToJSON(types.Map{"id": uuid.NewString()}))).
+		Send(ctx, server))
+
+	requestURL := server.GetURL(ctx) + "/animal/create"
+	for _, name := range []string{animalName, animalName, uuid.NewString()} {
+		res, err := netkit.PostJSON[types.Map](ctx, requestURL, types.Map{"name": name})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	request := NewStub().For("POST", Contains("animal/create")).WithRequestBody(BodyJSONPath("$.name", EqualTo(animalName))).Request
+	result, err := server.Verify(ctx, request, Exactly(2))
+	require.NoError(t, err)
+	require.True(t, result.Verified, result.String())
+
+	result, err = server.Verify(ctx, request, AtLeast(3))
+	require.NoError(t, err)
+	require.False(t, result.Verified)
+	require.Len(t, result.NearMisses, 1)
+	require.Equal(t, "body", result.NearMisses[0].Mismatches[0].Field)
+}
+
+func TestLocalServer_PathTemplate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t).WithNamespace(uuid.NewString())
+
+	script := `
+status_code: 200
+body: >
+  {"user_id": "{{ .PathParams.user_id }}", "order_id": "{{ .PathParams.order_id }}"}
+`
+	stub := NewStub().For("GET").WithPath("/users/{user_id:int}/orders/{order_id}").
+		WillReturn(&Response{Template: &Template{Script: script}})
+	require.NoError(t, stub.Send(ctx, server))
+
+	res, err := netkit.Get[types.Map](ctx, server.GetURL(ctx)+"/users/10/orders/abc?expand=true")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, types.Map{"user_id": "10", "order_id": "abc"}, res.Body)
+
+	// The escaped segment is unescaped only once
+	res, err = netkit.Get[types.Map](ctx, server.GetURL(ctx)+"/users/10/orders/a%2Fb%2520")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, types.Map{"user_id": "10", "order_id": "a/b%20"}, res.Body)
+
+	res, err = netkit.Get[types.Map](ctx, server.GetURL(ctx)+"/users/abc/orders/abc")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	requests, err := server.GetIncomingRequests(ctx, &IncomingQueryOption{})
+	require.NoError(t, err)
+	require.Len(t, requests, 3)
+	require.Equal(t, "path", requests[0].Diagnostics[0].Mismatches[0].Field)
+	require.Equal(t, map[string]string{"user_id": "10", "order_id": "abc"}, requests[2].PathParams)
+
+	request := NewStub().For("GET").WithPath("/users/{user_id:int}/**").Request
+	result, err := server.Verify(ctx, request, Exactly(2))
+	require.NoError(t, err)
+	require.True(t, result.Verified, result.String())
+}
+
+func TestRemoteServer_Verify(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	expectedNamespace := uuid.NewString()
+	mockingServer := NewLocalServerWithReporter(t)
+
+	result := &VerificationResult{Verified: true, Expected: Exactly(1), Actual: 1, MatchedIDs: []int64{1}}
+	resData := types.Map{"verdict": "success", "data": types.Map{"result": result}}
+	require.NoError(t, NewStub().
+		For("POST", Contains("/incoming_request/verify")).
+		WithRequestBody(BodyJSONPath("$.namespace", EqualTo(expectedNamespace))).
+		WithRequestBody(BodyJSONPath("$.count.condition", EqualTo(string(CountExactly)))).
+		WillReturn(NewResponse().WithBody(MustToJSON(resData))).
+		Send(ctx, mockingServer))
+
+	remoteServer := NewRemoteServerWithReporter(t, mockingServer.GetURL(ctx)).WithNamespace(expectedNamespace)
+	request := NewStub().For("GET", Contains("animal")).Request
+	actualResult, err := remoteServer.Verify(ctx, request, Exactly(1))
+	require.NoError(t, err)
+	require.Equal(t, result, actualResult)
+}
+
+func TestLocalServer_StubCRUD(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := NewLocalServerWithReporter(t)
+
+	stub := NewStub().For("GET", Contains("animal/get")).WillReturn(NewResponse().WithStatusCode(http.StatusAccepted))
+	require.NoError(t, stub.Send(ctx, server))
+
+	requestURL := server.GetURL(ctx) + "/animal/get"
+	sendRequest := func() int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		require.NoError(t, err)
+
+		res, err := netkit.SendRequest(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusAccepted, sendRequest())
+
+	foundStub, err := server.GetStub(ctx, stub.ID)
+	require.NoError(t, err)
+
+	foundStub.Response = NewResponse().WithStatusCode(http.StatusCreated)
+	require.NoError(t, server.UpdateStub(ctx, foundStub))
+	require.Equal(t, http.StatusCreated, sendRequest())
+
+	require.NoError(t, server.DeactivateStub(ctx, stub.ID))
+	require.Equal(t, http.StatusNotFound, sendRequest())
+
+	require.NoError(t, server.ActivateStub(ctx, stub.ID))
+	require.Equal(t, http.StatusCreated, sendRequest())
+
+	require.NoError(t, server.DeleteStub(ctx, stub.ID))
+	require.Equal(t, http.StatusNotFound, sendRequest())
+
+	_, err = server.GetStub(ctx, stub.ID+1)
+	require.ErrorIs(t, err, ErrStubNotFound)
+}
+
+func TestRemoteServer_StubCRUD(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mockingServer := NewLocalServerWithReporter(t)
+	stub := NewStub().WithID(10).WithDescription(uuid.NewString())
+
+	resData := types.Map{"verdict": "success", "data": types.Map{"stub": stub}}
+	require.NoError(t, NewStub().
+		For("GET", Contains("/stub/10?")).
+		WithQuery("return_encoded", EqualTo("true")).
+		WillReturn(NewResponse().WithBody(MustToJSON(resData))).
+		Send(ctx, mockingServer))
+
+	require.NoError(t, NewStub().
+		For("PUT", Contains("/stub/10?")).
+		WithRequestBody(BodyJSONPath("$.description", EqualTo(stub.Description))).
+		WillReturn(NewResponse().WithBody(MustToJSON(resData))).
+		Send(ctx, mockingServer))
+
+	require.NoError(t, NewStub().
+		For("POST", Contains("/stub/10/deactivate")).
+		WillReturn(NewResponse().WithBody(MustToJSON(resData))).
+		Send(ctx, mockingServer))
+
+	require.NoError(t, NewStub().
+		For("DELETE", EndWith("/stub/10")).
+		WillReturn(NewResponse().WithBody(MustToJSON(types.Map{"verdict": "success", "data": types.Map{}}))).
+		Send(ctx, mockingServer))
+
+	notFoundData := types.Map{"verdict": "not_found", "message": "stub 11 not found", "data": types.Map{}}
+	require.NoError(t, NewStub().
+		For("POST", Contains("/stub/11/activate")).
+		WillReturn(NewResponse().WithStatusCode(http.StatusNotFound).WithBody(MustToJSON(notFoundData))).
+		Send(ctx, mockingServer))
+
+	remoteServer := NewRemoteServerWithReporter(t, mockingServer.GetURL(ctx))
+	foundStub, err := remoteServer.GetStub(ctx, stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, stub.Description, foundStub.Description)
+
+	require.NoError(t, remoteServer.UpdateStub(ctx, stub))
+	require.NoError(t, remoteServer.DeactivateStub(ctx, stub.ID))
+	require.NoError(t, remoteServer.DeleteStub(ctx, stub.ID))
+	require.ErrorIs(t, remoteServer.ActivateStub(ctx, 11), ErrStubNotFound)
+}
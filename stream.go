@@ -0,0 +1,139 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hungdv136/rio/internal/log"
+)
+
+// TextStreamMessage is convenient constructor to initialize stream message with text body
+func TextStreamMessage(body string, delay time.Duration) *StreamMessage {
+	return &StreamMessage{Body: []byte(body), Delay: delay}
+}
+
+// SSEMessage is convenient constructor to initialize a server-sent event
+// Event is optional, the client uses "message" as the default event type
+func SSEMessage(event string, data string, delay time.Duration) *StreamMessage {
+	return &StreamMessage{Event: event, Body: []byte(data), Delay: delay}
+}
+
+// WithID sets id of server-sent event
+func (m *StreamMessage) WithID(id string) *StreamMessage {
+	m.ID = id
+	return m
+}
+
+// WithRetry sets reconnection time of server-sent event
+func (m *StreamMessage) WithRetry(d time.Duration) *StreamMessage {
+	m.Retry = d
+	return m
+}
+
+// Validate returns a non-nil error if invalid
+func (m *StreamMessage) Validate(ctx context.Context) error {
+	var err error
+	switch {
+	case m == nil:
+		err = errors.New("stream message must not be nil")
+	case m.Delay < 0 || m.Retry < 0:
+		err = errors.New("delay and retry of stream message must not be negative")
+	case strings.ContainsAny(m.ID, "\r\n") || strings.ContainsAny(m.Event, "\r\n"):
+		err = errors.New("id and event of stream message must not contain line breaks")
+	}
+
+	if err != nil {
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// encodeEvent encodes message to the format of server-sent event
+// Each line of body is written as a data field
+func (m *StreamMessage) encodeEvent() []byte {
+	buf := bytes.Buffer{}
+	if len(m.ID) > 0 {
+		buf.WriteString("id: " + m.ID + "\n")
+	}
+
+	if len(m.Event) > 0 {
+		buf.WriteString("event: " + m.Event + "\n")
+	}
+
+	if m.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(m.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	if len(m.Body) > 0 {
+		data := strings.ReplaceAll(string(m.Body), "\r\n", "\n")
+		for _, line := range strings.Split(data, "\n") {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// StreamResponse is convenient constructor to initialize a http streaming response
+// Each message is flushed to client as a chunk
+func StreamResponse(contentType string, messages ...*StreamMessage) *Response {
+	return NewResponse().WithHeader(HeaderContentType, contentType).WithStreamMessages(messages...)
+}
+
+// SSEResponse is convenient constructor to initialize a server-sent events response
+func SSEResponse(messages ...*StreamMessage) *Response {
+	return StreamResponse(ContentTypeSSE, messages...).WithHeader(HeaderCacheControl, "no-cache")
+}
+
+// writeStream writes status and headers, then writes and flushes messages one by one
+// Streaming is stopped without error if the client disconnects
+func (r *Response) writeStream(ctx context.Context, w http.ResponseWriter) error {
+	r.writeHeader(w)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	flush()
+
+	contentType := w.Header().Get(HeaderContentType)
+	for i, m := range r.Stream {
+		if err := Sleep(ctx, m.Delay); err != nil {
+			log.Info(ctx, "client disconnected, stop streaming at message", i)
+			return nil
+		}
+
+		data := []byte(m.Body)
+		switch {
+		case strings.HasPrefix(contentType, ContentTypeSSE):
+			data = m.encodeEvent()
+		case strings.HasPrefix(contentType, ContentTypeNDJSON) && !bytes.HasSuffix(data, []byte("\n")):
+			data = append(data[:len(data):len(data)], '\n')
+		}
+
+		if _, err := w.Write(data); err != nil {
+			if ctx.Err() != nil {
+				log.Info(ctx, "client disconnected, stop streaming at message", i)
+				return nil
+			}
+
+			log.Error(ctx, "cannot write stream message", err)
+			return err
+		}
+
+		flush()
+	}
+
+	return nil
+}
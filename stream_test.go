@@ -0,0 +1,163 @@
+package rio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamMessage_EncodeEvent(t *testing.T) {
+	t.Parallel()
+
+	m := SSEMessage("update", "line 1\nline 2", 0).WithID("10").WithRetry(3 * time.Second)
+	require.Equal(t, "id: 10\nevent: update\nretry: 3000\ndata: line 1\ndata: line 2\n\n", string(m.encodeEvent()))
+	require.Equal(t, "data: {\"id\":1}\n\n", string(SSEMessage("", `{"id":1}`, 0).encodeEvent()))
+}
+
+func TestStreamMessage_Validate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.NoError(t, SSEMessage("update", "data", time.Second).WithID("1").Validate(ctx))
+	require.Error(t, SSEMessage("update\n", "data", 0).Validate(ctx))
+	require.Error(t, SSEMessage("update", "data", 0).WithID("1\r").Validate(ctx))
+	require.Error(t, SSEMessage("update", "data", -time.Second).Validate(ctx))
+	require.Error(t, NewResponse().WithStreamMessages(nil).Validate(ctx))
+}
+
+func TestResponse_WriteStream(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newServer := func(t *testing.T, res *Response, done chan<- error) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := res.WriteTo(r.Context(), w)
+			if done != nil {
+				done <- err
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	get := func(t *testing.T, ctx context.Context, url string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		require.NoError(t, err)
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+
+	t.Run("sse", func(t *testing.T) {
+		t.Parallel()
+
+		res := SSEResponse(
+			SSEMessage("created", "1", 0).WithID("1"),
+			SSEMessage("updated", "2", 300*time.Millisecond).WithID("2"),
+		).WithStatusCode(http.StatusAccepted)
+
+		start := time.Now()
+		httpRes := get(t, ctx, newServer(t, res, nil))
+		require.Equal(t, http.StatusAccepted, httpRes.StatusCode)
+		require.Equal(t, ContentTypeSSE, httpRes.Header.Get(HeaderContentType))
+		require.Equal(t, "no-cache", httpRes.Header.Get(HeaderCacheControl))
+
+		// The first event is flushed before the delay of the second event
+		reader := bufio.NewReader(httpRes.Body)
+		var firstEvent string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			firstEvent += line
+			if line == "\n" {
+				break
+			}
+		}
+
+		require.Equal(t, "id: 1\nevent: created\ndata: 1\n\n", firstEvent)
+		require.Less(t, time.Since(start), 300*time.Millisecond)
+
+		remaining, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "id: 2\nevent: updated\ndata: 2\n\n", string(remaining))
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		t.Parallel()
+
+		res := StreamResponse(ContentTypeNDJSON,
+			JSONStreamMessage(types.Map{"id": 1}, 0),
+			TextStreamMessage("{\"id\":2}\n", 10*time.Millisecond),
+		)
+
+		body, err := io.ReadAll(get(t, ctx, newServer(t, res, nil)).Body)
+		require.NoError(t, err)
+		require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", string(body))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+
+		res := StreamResponse(ContentTypeText, TextStreamMessage("Hello", 0), TextStreamMessage(" world", 10*time.Millisecond))
+		body, err := io.ReadAll(get(t, ctx, newServer(t, res, nil)).Body)
+		require.NoError(t, err)
+		require.Equal(t, "Hello world", string(body))
+	})
+
+	t.Run("client_disconnected", func(t *testing.T) {
+		t.Parallel()
+
+		done := make(chan error, 1)
+		res := SSEResponse(SSEMessage("", "1", 0), SSEMessage("", "2", time.Hour))
+
+		clientCtx, cancel := context.WithCancel(ctx)
+		httpRes := get(t, clientCtx, newServer(t, res, done))
+
+		line, err := bufio.NewReader(httpRes.Body).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "data: 1\n", line)
+		cancel()
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "streaming is not stopped when client disconnected")
+		}
+	})
+}
+
+func TestResponse_StreamTemplate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/chat", strings.NewReader(`{"tokens": ["Hello", "world"]}`))
+	require.NoError(t, err)
+
+	res := NewResponse()
+	res.Template = &Template{Script: `
+stream:
+{{- range $i, $token := .JSONBody.tokens }}
+  - id: "{{ $i }}"
+    event: token
+    body: {{ $token }}
+    delay: 10ms
+{{- end }}
+`}
+
+	require.NoError(t, res.LoadBodyFromTemplate(ctx, &TemplateData{Request: request}))
+	require.Equal(t, []*StreamMessage{
+		{ID: "0", Event: "token", Body: []byte("Hello"), Delay: 10 * time.Millisecond},
+		{ID: "1", Event: "token", Body: []byte("world"), Delay: 10 * time.Millisecond},
+	}, res.Stream)
+}
@@ -16,10 +16,14 @@ import (
 const (
 	ContentTypeJSON      = "application/json"
 	ContentTypeXML       = "text/xml"
+	ContentTypeAppXML    = "application/xml"
 	ContentTypeHTML      = "text/html"
 	ContentTypeText      = "text/plain"
 	ContentTypeMultipart = "multipart/form-data"
 	ContentTypeForm      = "application/x-www-form-urlencoded"
+	ContentTypeGraphQL   = "application/graphql"
+	ContentTypeSSE       = "text/event-stream"
+	ContentTypeNDJSON    = "application/x-ndjson"
 )
 
 // Defines request header
@@ -27,6 +31,7 @@ const (
 	HeaderContentType   = "Content-Type"
 	HeaderContentLength = "Content-Length"
 	HeaderLocation      = "Location"
+	HeaderCacheControl  = "Cache-Control"
 	HeaderXRequestID    = "X-Request-Id"
 )
 
@@ -69,6 +74,10 @@ type Stub struct {
 	// The expected response which includes the body, header and cookies
 	Response *Response `json:"response,omitempty" yaml:"response"`
 
+	// Sequence defines an ordered list of responses which overrides the response
+	// The response is selected by the policy of sequence and the number of times the stub has been matched
+	Sequence *ResponseSequence `json:"sequence,omitempty" yaml:"sequence"`
+
 	// The mock server will act as reserved proxy if this settings are provided
 	Proxy *Proxy `json:"proxy,omitempty" yaml:"proxy"`
 
@@ -83,6 +92,13 @@ type Stub struct {
 
 	Settings StubSettings `json:"settings,omitempty" yaml:"settings"`
 
+	// Scenario defines the state machine settings of the stub
+	// This is to return different responses for the same request. For example: the first call returns PENDING, the second returns DONE
+	Scenario *StubScenario `json:"scenario,omitempty" yaml:"scenario"`
+
+	// Callbacks are the outbound http requests which are sent in background after the stub is matched
+	Callbacks []*Callback `json:"callbacks,omitempty" yaml:"callbacks" gorm:"serializer:json"`
+
 	CreatedAt time.Time `json:"created_at,omitempty" yaml:"created_at"`
 	UpdatedAt time.Time `json:"updated_at,omitempty" yaml:"updated_at"`
 }
@@ -93,8 +109,8 @@ func (s *Stub) Validate(ctx context.Context) error {
 		return err
 	}
 
-	if s.Proxy == nil && s.Response == nil {
-		err := errors.New("proxy or response must be defined")
+	if s.Proxy == nil && s.Response == nil && s.Sequence == nil {
+		err := errors.New("proxy, response or sequence must be defined")
 		log.Error(ctx, err)
 		return err
 	}
@@ -103,6 +119,61 @@ func (s *Stub) Validate(ctx context.Context) error {
 		return err
 	}
 
+	if err := s.Sequence.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := s.validateFault(ctx); err != nil {
+		return err
+	}
+
+	if err := s.Settings.Validate(ctx); err != nil {
+		return err
+	}
+
+	if s.Protocol == ProtocolGrpc && s.Settings.Trickle != nil {
+		err := errors.New("trickle is not supported for grpc")
+		log.Error(ctx, err)
+		return err
+	}
+
+	if s.Scenario != nil {
+		if err := s.Scenario.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.validateCallbacks(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateFault returns an error if the fault of responses is not applied for the protocol of stub
+func (s *Stub) validateFault(ctx context.Context) error {
+	responses := []*Response{s.Response}
+	if s.Sequence != nil {
+		responses = append(responses, s.Sequence.Responses...)
+	}
+
+	for _, r := range responses {
+		if r == nil || len(r.Fault) == 0 {
+			continue
+		}
+
+		supported := r.Fault.IsHTTP()
+		if s.Protocol == ProtocolGrpc {
+			supported = r.Fault.IsGrpc()
+		}
+
+		if !supported {
+			err := fmt.Errorf("fault %s is not supported for protocol %s", r.Fault, s.Protocol)
+			log.Error(ctx, err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -124,12 +195,15 @@ func (s *Stub) Clone() *Stub {
 		Namespace:   s.Namespace,
 		Request:     s.Request,
 		Response:    s.Response.Clone(),
+		Sequence:    s.Sequence.Clone(),
 		Proxy:       s.Proxy,
 		Active:      s.Active,
 		Settings:    s.Settings,
 		Tag:         s.Tag,
 		Protocol:    s.Protocol,
 		Weight:      s.Weight,
+		Scenario:    s.Scenario,
+		Callbacks:   cloneCallbacks(s.Callbacks),
 	}
 }
 
@@ -176,6 +250,14 @@ type StubSettings struct {
 	// Rio supports this feature by set delay duration
 	DelayDuration time.Duration `json:"delay_duration,omitempty" swaggertype:"primitive,integer" yaml:"delay_duration"`
 
+	// Optional. Defines a random distribution of delay to simulate jittered latency
+	// If defined, then it overrides delay duration
+	Delay *Delay `json:"delay,omitempty" yaml:"delay"`
+
+	// Optional. Paces the body over a duration after the status and headers are sent
+	// Delay is the time to first byte, trickle is the time to transfer body. This is not applied for GRPC
+	Trickle *Trickle `json:"trickle,omitempty" yaml:"trickle"`
+
 	// StoreVersion is a system field to control data structure version for stub
 	// Value will be overrided by system
 	StoreVersion int `json:"store_version,omitempty" yaml:"store_version"`
@@ -203,10 +285,30 @@ func (r *StubSettings) Clone() *StubSettings {
 	return &StubSettings{
 		DeactivateWhenMatched: r.DeactivateWhenMatched,
 		DelayDuration:         r.DelayDuration,
+		Delay:                 r.Delay.Clone(),
+		Trickle:               r.Trickle.Clone(),
 		StoreVersion:          r.StoreVersion,
 	}
 }
 
+// SampleDelay returns the delay duration before responding
+func (r *StubSettings) SampleDelay() time.Duration {
+	if r.Delay != nil {
+		return r.Delay.Sample()
+	}
+
+	return r.DelayDuration
+}
+
+// Validate returns a non-nil error if invalid
+func (r *StubSettings) Validate(ctx context.Context) error {
+	if err := r.Delay.Validate(ctx); err != nil {
+		return err
+	}
+
+	return r.Trickle.Validate(ctx)
+}
+
 // NewStub returns a new stub
 func NewStub() *Stub {
 	return &Stub{
@@ -258,6 +360,31 @@ func (s *Stub) For(method string, urlMatchingFuncs ...CreateOperator) *Stub {
 	return s
 }
 
+// WithPath sets the path template to match the url path which is removed the base path of mock server
+// For example: /users/{id:int}/orders/{order_id}. Captured parameters can be used in template as {{ .PathParams.id }}
+func (s *Stub) WithPath(pathTemplate string) *Stub {
+	s.Request.Path = pathTemplate
+	return s
+}
+
+// WithHost sets host matching operator. The host is matched without port
+func (s *Stub) WithHost(createFunc CreateOperator) *Stub {
+	s.Request.Host = append(s.Request.Host, createFunc())
+	return s
+}
+
+// WithScheme sets scheme (http or https) to match
+func (s *Stub) WithScheme(scheme string) *Stub {
+	s.Request.Scheme = scheme
+	return s
+}
+
+// WithPort sets port matching operator. The port is matched as string
+func (s *Stub) WithPort(createFunc CreateOperator) *Stub {
+	s.Request.Port = append(s.Request.Port, createFunc())
+	return s
+}
+
 // ForAny for matching request with any method
 func (s *Stub) ForAny(urlMatchingFuncs ...CreateOperator) *Stub {
 	return s.For("", urlMatchingFuncs...)
@@ -282,6 +409,13 @@ func (s *Stub) WithHeader(name string, createFunc CreateOperator) *Stub {
 	return s
 }
 
+// WithHeaderValues sets matching operator for a header which has multiple values by the given quantifier
+func (s *Stub) WithHeaderValues(name string, quantifier Quantifier, createFunc CreateOperator) *Stub {
+	op := FieldOperator{FieldName: name, Operator: createFunc(), Quantifier: quantifier}
+	s.Request.Header = append(s.Request.Header, op)
+	return s
+}
+
 // WithCookie sets cookie matching operator
 func (s *Stub) WithCookie(name string, createFunc CreateOperator) *Stub {
 	op := FieldOperator{FieldName: name, Operator: createFunc()}
@@ -296,12 +430,32 @@ func (s *Stub) WithQuery(name string, createFunc CreateOperator) *Stub {
 	return s
 }
 
+// WithQueryValues sets matching operator for a query parameter which has multiple values by the given quantifier
+// For example: ?tag=a&tag=b
+func (s *Stub) WithQueryValues(name string, quantifier Quantifier, createFunc CreateOperator) *Stub {
+	op := FieldOperator{FieldName: name, Operator: createFunc(), Quantifier: quantifier}
+	s.Request.Query = append(s.Request.Query, op)
+	return s
+}
+
 // WithRequestBody sets body matching operator
 func (s *Stub) WithRequestBody(createFunc CreateBodyOperator) *Stub {
 	s.Request.Body = append(s.Request.Body, createFunc())
 	return s
 }
 
+// WithGraphQL sets matching operator for the parsed GraphQL request
+func (s *Stub) WithGraphQL(createFunc CreateGraphQLOperator) *Stub {
+	s.Request.GraphQL = append(s.Request.GraphQL, createFunc())
+	return s
+}
+
+// WithRequestStream sets matching operator for messages of grpc client stream
+func (s *Stub) WithRequestStream(createFunc CreateStreamOperator) *Stub {
+	s.Request.Stream = append(s.Request.Stream, createFunc())
+	return s
+}
+
 // WillReturn sets the response
 func (s *Stub) WillReturn(resp *Response) *Stub {
 	s.Response = resp
@@ -337,6 +491,63 @@ func (s *Stub) ShouldDelay(d time.Duration) *Stub {
 	return s
 }
 
+// WillReturnSequence sets the sequence of responses. For example: SequentialResponses(pendingResponse, doneResponse)
+func (s *Stub) WillReturnSequence(q *ResponseSequence) *Stub {
+	s.Sequence = q
+	return s
+}
+
+// WithCallback adds callbacks which are sent after the stub is matched. For example: NewCallback(webhookURL).WithDelay(time.Second)
+func (s *Stub) WithCallback(callbacks ...*Callback) *Stub {
+	s.Callbacks = append(s.Callbacks, callbacks...)
+	return s
+}
+
+// ShouldDelayWith sets the random distribution of delay
+// Use this to simulate jittered latency. For example: UniformDelay(100*time.Millisecond, 500*time.Millisecond)
+func (s *Stub) ShouldDelayWith(d *Delay) *Stub {
+	s.Settings.Delay = d
+	return s
+}
+
+// ShouldTrickleBody paces the body over a duration. The body is written in chunks with the same interval
+// If chunks is zero, then the default number of chunks is used
+func (s *Stub) ShouldTrickleBody(d time.Duration, chunks int) *Stub {
+	s.Settings.Trickle = &Trickle{Duration: d, Chunks: chunks}
+	return s
+}
+
+// InScenario sets the scenario name of stub. Stubs in the same scenario share the same state
+// The initial state of scenario is "started"
+func (s *Stub) InScenario(name string) *Stub {
+	if s.Scenario == nil {
+		s.Scenario = &StubScenario{}
+	}
+
+	s.Scenario.Name = name
+	return s
+}
+
+// WhenScenarioStateIs sets the required state. The stub is only matched if the scenario is in this state
+func (s *Stub) WhenScenarioStateIs(state string) *Stub {
+	if s.Scenario == nil {
+		s.Scenario = &StubScenario{}
+	}
+
+	s.Scenario.RequiredState = state
+	return s
+}
+
+// WillSetStateTo sets the new state. The scenario will be moved to this state when the stub is matched
+func (s *Stub) WillSetStateTo(state string) *Stub {
+	if s.Scenario == nil {
+		s.Scenario = &StubScenario{}
+	}
+
+	s.Scenario.NewState = state
+	return s
+}
+
 // WithTargetURL sets base target url, request will be forwarded to the given url
 func (s *Stub) WithTargetURL(url string) *Stub {
 	if s.Proxy == nil {
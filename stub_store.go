@@ -19,6 +19,9 @@ type IncomingQueryOption struct {
 	Namespace string  `json:"namespace" yaml:"namespace"`
 	Ids       []int64 `json:"ids" yaml:"ids"`
 	Limit     int     `json:"limit" yaml:"limit"`
+
+	// Unmatched selects only requests which are not matched with any stub
+	Unmatched bool `json:"unmatched" yaml:"unmatched"`
 }
 
 type ResetQueryOption struct {
@@ -30,12 +33,21 @@ type ResetQueryOption struct {
 type StubStore interface {
 	Create(ctx context.Context, stubs ...*Stub) error
 	Delete(ctx context.Context, id int64) error
+	Find(ctx context.Context, id int64) (*Stub, error)
+	Update(ctx context.Context, stub *Stub) error
 	GetAll(ctx context.Context, namespace string) ([]*Stub, error)
 	CreateProto(ctx context.Context, protos ...*Proto) error
 	GetProtos(ctx context.Context) ([]*Proto, error)
 	CreateIncomingRequest(ctx context.Context, r *IncomingRequest) error
 	GetIncomingRequests(ctx context.Context, option *IncomingQueryOption) ([]*IncomingRequest, error)
 	Reset(ctx context.Context, option *ResetQueryOption) error
+	GetScenarios(ctx context.Context, option *ScenarioQueryOption) ([]*Scenario, error)
+	SetScenarioState(ctx context.Context, scenario *Scenario) error
+	TransitScenarioState(ctx context.Context, namespace string, name string, currentState string, newState string) (bool, error)
+	ResetScenarios(ctx context.Context, option *ScenarioQueryOption) error
+	IncreaseStubCounter(ctx context.Context, namespace string, stubID int64) (int64, error)
+	CreateCallbackResult(ctx context.Context, r *CallbackResult) error
+	GetCallbackResults(ctx context.Context, option *CallbackQueryOption) ([]*CallbackResult, error)
 }
 
 // LastUpdatedRecord holds the id and updated at
@@ -50,11 +62,14 @@ type StatusStore interface {
 	GetLastUpdatedProto(ctx context.Context) (*LastUpdatedRecord, error)
 }
 
-// StubMemory implements in memory store which using for unit test
+// StubMemory implements in memory store which is used for unit test and the in-memory mode of standalone servers
 type StubMemory struct {
 	stubs          []*Stub
 	protos         []*Proto
 	incomeRequests []*IncomingRequest
+	scenarios      []*Scenario
+	counters       []*StubCounter
+	callbacks      []*CallbackResult
 	id             int64
 	l              sync.RWMutex
 }
@@ -75,29 +90,69 @@ func (db *StubMemory) Create(ctx context.Context, stubs ...*Stub) error {
 			stub.ID = db.id
 		}
 
+		stub.Request = loadJSONSchemas(stub.Request)
 		db.stubs = append(db.stubs, stub)
 	}
 
 	return nil
 }
 
-// Delete deletes a stub
+// Delete marks stub as inactive
 func (db *StubMemory) Delete(ctx context.Context, id int64) error {
 	db.l.Lock()
 	defer db.l.Unlock()
 
-	records := make([]*Stub, 0, len(db.stubs))
-	for _, r := range db.stubs {
-		if r.ID != id {
-			records = append(records, r)
+	for i, r := range db.stubs {
+		if r.ID == id {
+			// Replace by a copy since the stored one might be being read by other goroutines
+			record := *r
+			record.Active = false
+			db.stubs[i] = &record
 		}
 	}
 
-	db.stubs = records
 	return nil
 }
 
-// GetAll gets all records
+// Find finds by id, returns nil if not found
+func (db *StubMemory) Find(ctx context.Context, id int64) (*Stub, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	for _, r := range db.stubs {
+		if r.ID == id {
+			record := *r
+			return &record, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Update replaces the stub which has the same id, returns ErrStubNotFound if it does not exist
+func (db *StubMemory) Update(ctx context.Context, stub *Stub) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	for i, r := range db.stubs {
+		if r.ID == stub.ID {
+			stub.CreatedAt = r.CreatedAt
+			stub.UpdatedAt = time.Now()
+
+			// Store a copy, so that the caller can modify the given stub without racing with other goroutines
+			record := stub.Clone()
+			record.Request = loadJSONSchemas(stub.Request)
+			record.CreatedAt = stub.CreatedAt
+			record.UpdatedAt = stub.UpdatedAt
+			db.stubs[i] = record
+			return nil
+		}
+	}
+
+	return ErrStubNotFound
+}
+
+// GetAll gets all active records
 func (db *StubMemory) GetAll(_ context.Context, namespace string) ([]*Stub, error) {
 	db.l.RLock()
 	defer db.l.RUnlock()
@@ -106,7 +161,7 @@ func (db *StubMemory) GetAll(_ context.Context, namespace string) ([]*Stub, erro
 
 	for i := len(db.stubs) - 1; i >= 0; i-- {
 		r := db.stubs[i]
-		if r.Namespace == namespace {
+		if r.Namespace == namespace && r.Active {
 			records = append(records, r)
 		}
 	}
@@ -148,6 +203,10 @@ func (db *StubMemory) GetIncomingRequests(ctx context.Context, option *IncomingQ
 			continue
 		}
 
+		if option.Unmatched && r.StubID > 0 {
+			continue
+		}
+
 		incomeRequests = append(incomeRequests, r)
 	}
 
@@ -171,7 +230,220 @@ func (db *StubMemory) GetProtos(ctx context.Context) ([]*Proto, error) {
 
 // Reset clear data
 func (db *StubMemory) Reset(ctx context.Context, option *ResetQueryOption) error {
-	db.stubs = []*Stub{}
-	db.incomeRequests = []*IncomingRequest{}
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	shouldReset := func(namespace string, tag string) bool {
+		if option.Namespace != ResetAll && namespace != option.Namespace {
+			return false
+		}
+
+		return len(option.Tag) == 0 || tag == option.Tag
+	}
+
+	stubs := make([]*Stub, 0, len(db.stubs))
+	for _, stub := range db.stubs {
+		if !shouldReset(stub.Namespace, stub.Tag) {
+			stubs = append(stubs, stub)
+		}
+	}
+
+	incomeRequests := make([]*IncomingRequest, 0, len(db.incomeRequests))
+	for _, r := range db.incomeRequests {
+		if !shouldReset(r.Namespace, r.Tag) {
+			incomeRequests = append(incomeRequests, r)
+		}
+	}
+
+	callbacks := make([]*CallbackResult, 0, len(db.callbacks))
+	for _, r := range db.callbacks {
+		if !shouldReset(r.Namespace, r.Tag) {
+			callbacks = append(callbacks, r)
+		}
+	}
+
+	db.stubs = stubs
+	db.incomeRequests = incomeRequests
+	db.callbacks = callbacks
+
+	// Scenarios and counters are not tagged, so they are only reset with the whole namespace
+	if len(option.Tag) == 0 {
+		scenarios := make([]*Scenario, 0, len(db.scenarios))
+		for _, s := range db.scenarios {
+			if !shouldReset(s.Namespace, "") {
+				scenarios = append(scenarios, s)
+			}
+		}
+
+		db.scenarios = scenarios
+
+		counters := make([]*StubCounter, 0, len(db.counters))
+		for _, c := range db.counters {
+			if !shouldReset(c.Namespace, "") {
+				counters = append(counters, c)
+			}
+		}
+
+		db.counters = counters
+	}
+
+	return nil
+}
+
+// GetScenarios returns started scenarios
+func (db *StubMemory) GetScenarios(ctx context.Context, option *ScenarioQueryOption) ([]*Scenario, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	scenarios := make([]*Scenario, 0, len(db.scenarios))
+	for _, s := range db.scenarios {
+		if s.Namespace == option.Namespace && (len(option.Name) == 0 || s.Name == option.Name) {
+			cloned := *s
+			scenarios = append(scenarios, &cloned)
+		}
+	}
+
+	return scenarios, nil
+}
+
+// SetScenarioState sets state of a scenario
+func (db *StubMemory) SetScenarioState(ctx context.Context, scenario *Scenario) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	if s := db.findScenario(scenario.Namespace, scenario.Name); s != nil {
+		s.State = scenario.State
+		s.UpdatedAt = time.Now()
+		return nil
+	}
+
+	db.id++
+	scenario.ID = db.id
+	scenario.CreatedAt = time.Now()
+	scenario.UpdatedAt = scenario.CreatedAt
+
+	cloned := *scenario
+	db.scenarios = append(db.scenarios, &cloned)
+	return nil
+}
+
+// TransitScenarioState moves scenario to the new state if its state is the current state
+func (db *StubMemory) TransitScenarioState(ctx context.Context, namespace string, name string, currentState string, newState string) (bool, error) {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	s := db.findScenario(namespace, name)
+	if s == nil {
+		if currentState != ScenarioStateStarted {
+			return false, nil
+		}
+
+		db.id++
+		now := time.Now()
+		db.scenarios = append(db.scenarios, &Scenario{ID: db.id, Namespace: namespace, Name: name, State: newState, CreatedAt: now, UpdatedAt: now})
+		return true, nil
+	}
+
+	if s.State != currentState {
+		return false, nil
+	}
+
+	s.State = newState
+	s.UpdatedAt = time.Now()
+	return true, nil
+}
+
+// ResetScenarios resets scenarios to the initial state
+func (db *StubMemory) ResetScenarios(ctx context.Context, option *ScenarioQueryOption) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	scenarios := make([]*Scenario, 0, len(db.scenarios))
+	for _, s := range db.scenarios {
+		if s.Namespace != option.Namespace || (len(option.Name) > 0 && s.Name != option.Name) {
+			scenarios = append(scenarios, s)
+		}
+	}
+
+	db.scenarios = scenarios
+	return nil
+}
+
+func (db *StubMemory) findScenario(namespace string, name string) *Scenario {
+	for _, s := range db.scenarios {
+		if s.Namespace == namespace && s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// IncreaseStubCounter increases the counter of stub in namespace by one and returns the new value
+func (db *StubMemory) IncreaseStubCounter(ctx context.Context, namespace string, stubID int64) (int64, error) {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	for _, c := range db.counters {
+		if c.Namespace == namespace && c.StubID == stubID {
+			c.Counter++
+			c.UpdatedAt = time.Now()
+			return c.Counter, nil
+		}
+	}
+
+	db.id++
+	now := time.Now()
+	db.counters = append(db.counters, &StubCounter{ID: db.id, Namespace: namespace, StubID: stubID, Counter: 1, CreatedAt: now, UpdatedAt: now})
+	return 1, nil
+}
+
+// CreateCallbackResult saves the result of a callback
+func (db *StubMemory) CreateCallbackResult(ctx context.Context, r *CallbackResult) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	if r.ID == 0 {
+		db.id++
+		r.ID = db.id
+	}
+
+	now := time.Now()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	cloned := *r
+	db.callbacks = append(db.callbacks, &cloned)
 	return nil
 }
+
+// GetCallbackResults returns the latest callback results
+func (db *StubMemory) GetCallbackResults(ctx context.Context, option *CallbackQueryOption) ([]*CallbackResult, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	results := make([]*CallbackResult, 0, len(db.callbacks))
+	for i := len(db.callbacks) - 1; i >= 0; i-- {
+		if option.Limit > 0 && len(results) >= option.Limit {
+			break
+		}
+
+		r := db.callbacks[i]
+		if r.Namespace != option.Namespace {
+			continue
+		}
+
+		if option.StubID > 0 && r.StubID != option.StubID {
+			continue
+		}
+
+		if option.IncomingRequestID > 0 && r.IncomingRequestID != option.IncomingRequestID {
+			continue
+		}
+
+		cloned := *r
+		results = append(results, &cloned)
+	}
+
+	return results, nil
+}
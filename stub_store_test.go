@@ -39,3 +39,176 @@ func TestStubMemory_CreateIncomingRequest(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, requests, 1)
 }
+
+func TestStubMemory_Reset(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	namespace := uuid.NewString()
+	tag := uuid.NewString()
+
+	require.NoError(t, store.Create(ctx,
+		NewStub().For("GET", Contains("animal/create")).WithNamespace(namespace).WithTag(tag),
+		NewStub().For("GET", Contains("animal/create")).WithNamespace(namespace),
+		NewStub().For("GET", Contains("animal/create")),
+	))
+	require.NoError(t, store.CreateIncomingRequest(ctx, &IncomingRequest{Namespace: namespace}))
+	require.NoError(t, store.SetScenarioState(ctx, &Scenario{Namespace: namespace, Name: uuid.NewString(), State: "done"}))
+
+	require.NoError(t, store.Reset(ctx, &ResetQueryOption{Namespace: namespace, Tag: tag}))
+	stubs, err := store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+
+	scenarios, err := store.GetScenarios(ctx, &ScenarioQueryOption{Namespace: namespace})
+	require.NoError(t, err)
+	require.Len(t, scenarios, 1)
+
+	require.NoError(t, store.Reset(ctx, &ResetQueryOption{Namespace: namespace}))
+	stubs, err = store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Empty(t, stubs)
+
+	requests, err := store.GetIncomingRequests(ctx, &IncomingQueryOption{Namespace: namespace})
+	require.NoError(t, err)
+	require.Empty(t, requests)
+
+	scenarios, err = store.GetScenarios(ctx, &ScenarioQueryOption{Namespace: namespace})
+	require.NoError(t, err)
+	require.Empty(t, scenarios)
+
+	stubs, err = store.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+
+	require.NoError(t, store.Reset(ctx, &ResetQueryOption{Namespace: ResetAll}))
+	stubs, err = store.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, stubs)
+}
+
+func TestStubMemory_FindUpdate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	namespace := uuid.NewString()
+
+	stub := NewStub().For("GET", Contains("animal/create")).WithNamespace(namespace)
+	require.NoError(t, store.Create(ctx, stub))
+
+	foundStub, err := store.Find(ctx, stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, stub.ID, foundStub.ID)
+
+	notFound, err := store.Find(ctx, stub.ID+1)
+	require.NoError(t, err)
+	require.Nil(t, notFound)
+
+	foundStub.Description = uuid.NewString()
+	require.NoError(t, store.Update(ctx, foundStub))
+	require.ErrorIs(t, store.Update(ctx, NewStub().WithID(stub.ID+1)), ErrStubNotFound)
+
+	stubs, err := store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+	require.Equal(t, foundStub.Description, stubs[0].Description)
+
+	// The stored stub is a copy which is not changed by the caller
+	updatedDescription := foundStub.Description
+	foundStub.Description = uuid.NewString()
+	stubs, err = store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Equal(t, updatedDescription, stubs[0].Description)
+
+	// Deleted stub is kept as inactive, so that it can be activated again
+	require.NoError(t, store.Delete(ctx, stub.ID))
+	stubs, err = store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Empty(t, stubs)
+
+	foundStub, err = store.Find(ctx, stub.ID)
+	require.NoError(t, err)
+	require.False(t, foundStub.Active)
+
+	foundStub.Active = true
+	require.NoError(t, store.Update(ctx, foundStub))
+	stubs, err = store.GetAll(ctx, namespace)
+	require.NoError(t, err)
+	require.Len(t, stubs, 1)
+}
+
+func TestStubMemory_IncreaseStubCounter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	namespace := uuid.NewString()
+
+	for i := int64(1); i <= 3; i++ {
+		counter, err := store.IncreaseStubCounter(ctx, namespace, 1)
+		require.NoError(t, err)
+		require.Equal(t, i, counter)
+	}
+
+	counter, err := store.IncreaseStubCounter(ctx, namespace, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter)
+
+	counter, err = store.IncreaseStubCounter(ctx, "", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter)
+
+	// Counters are not reset by tag
+	require.NoError(t, store.Reset(ctx, &ResetQueryOption{Namespace: namespace, Tag: uuid.NewString()}))
+	counter, err = store.IncreaseStubCounter(ctx, namespace, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), counter)
+
+	require.NoError(t, store.Reset(ctx, &ResetQueryOption{Namespace: namespace}))
+	counter, err = store.IncreaseStubCounter(ctx, namespace, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter)
+}
+
+func TestStubMemory_CallbackResults(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewStubMemory()
+	namespace := uuid.NewString()
+	tag := uuid.NewString()
+
+	results := []*CallbackResult{
+		{Namespace: namespace, StubID: 1, IncomingRequestID: 10, Delivered: true},
+		{Namespace: namespace, Tag: tag, StubID: 2, IncomingRequestID: 11},
+		{Namespace: uuid.NewString(), StubID: 1, IncomingRequestID: 12},
+	}
+
+	for _, r := range results {
+		require.NoError(t, store.CreateCallbackResult(ctx, r))
+		require.NotZero(t, r.ID)
+	}
+
+	found, err := store.GetCallbackResults(ctx, &CallbackQueryOption{Namespace: namespace})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, results[1].ID, found[0].ID)
+
+	found, err = store.GetCallbackResults(ctx, &CallbackQueryOption{Namespace: namespace, StubID: 1})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.True(t, found[0].Delivered)
+
+	found, err = store.GetCallbackResults(ctx, &CallbackQueryOption{Namespace: namespace, IncomingRequestID: 11})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, int64(2), found[0].StubID)
+
+	require.NoError(t, store.Reset(ctx, &ResetQueryOption{Namespace: namespace, Tag: tag}))
+	found, err = store.GetCallbackResults(ctx, &CallbackQueryOption{Namespace: namespace})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, results[0].ID, found[0].ID)
+}
@@ -7,7 +7,9 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/hungdv136/rio/internal/log"
@@ -38,6 +40,19 @@ type ResponseScript struct {
 	Cookies    []Cookie          `json:"cookies,omitempty" yaml:"cookies"`
 	Headers    map[string]string `json:"headers,omitempty" yaml:"headers"`
 	Error      *ResponseError    `json:"error,omitempty" yaml:"error"`
+
+	// Stream overrides the stream messages of response if defined
+	Stream []*StreamMessageScript `json:"stream,omitempty" yaml:"stream"`
+}
+
+// StreamMessageScript represents for a stream message in response script
+// Body is a raw string, delay and retry are in nanoseconds for JSON or duration string (such as 100ms) for YAML
+type StreamMessageScript struct {
+	Body  string        `json:"body,omitempty" yaml:"body"`
+	Delay time.Duration `json:"delay,omitempty" yaml:"delay"`
+	ID    string        `json:"id,omitempty" yaml:"id"`
+	Event string        `json:"event,omitempty" yaml:"event"`
+	Retry time.Duration `json:"retry,omitempty" yaml:"retry"`
 }
 
 func (s *ResponseScript) AssignTo(r *Response) {
@@ -58,6 +73,13 @@ func (s *ResponseScript) AssignTo(r *Response) {
 	for k, v := range s.Headers {
 		r.Header[k] = v
 	}
+
+	if len(s.Stream) > 0 {
+		r.Stream = make([]*StreamMessage, len(s.Stream))
+		for i, m := range s.Stream {
+			r.Stream[i] = &StreamMessage{Body: []byte(m.Body), Delay: m.Delay, ID: m.ID, Event: m.Event, Retry: m.Retry}
+		}
+	}
 }
 
 // TemplateData holds all available data for feeding to template
@@ -71,6 +93,10 @@ type TemplateData struct {
 	// Which can be accessed from template as {{ .Grpc.<FielName> }}
 	Grpc *GrpcRequest `json:"grpc,omitempty" yaml:"grpc"`
 
+	// PathParams are the parameters which are captured by the path template of stub
+	// Which can be accessed from template as {{ .PathParams.<name> }}
+	PathParams map[string]string `json:"path_params,omitempty" yaml:"path_params"`
+
 	parsedBody types.Map
 }
 
@@ -99,10 +125,28 @@ func (t *TemplateData) JSONBody() map[string]interface{} {
 // Execute executes the template. Only go-template is supported at the moment
 // For supported function in Go template, see http://masterminds.github.io/sprig/
 func (t *Template) Execute(ctx context.Context, data *TemplateData) (*ResponseScript, error) {
-	script := template.New("Response Template").Funcs(sprig.TxtFuncMap())
-	script, err := script.Parse(t.Script)
+	doc, err := executeText(ctx, "Response Template", t.Script, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.ScriptSchemaType == SchemaTypeJSON {
+		return parseResponseScriptFromJSON(ctx, doc)
+	}
+
+	return parseResponseScriptFromYaml(ctx, doc)
+}
+
+// executeText executes a go template with sprig functions
+// The text is returned as is if it does not contain any action
+func executeText(ctx context.Context, name string, text string, data *TemplateData) ([]byte, error) {
+	if !strings.Contains(text, "{{") {
+		return []byte(text), nil
+	}
+
+	script, err := template.New(name).Funcs(sprig.TxtFuncMap()).Parse(text)
 	if err != nil {
-		log.Error(ctx, "cannot parse script", err, t.Script)
+		log.Error(ctx, "cannot parse script", err, text)
 		return nil, err
 	}
 
@@ -112,11 +156,7 @@ func (t *Template) Execute(ctx context.Context, data *TemplateData) (*ResponseSc
 		return nil, err
 	}
 
-	if t.ScriptSchemaType == SchemaTypeJSON {
-		return parseResponseScriptFromJSON(ctx, doc.Bytes())
-	}
-
-	return parseResponseScriptFromYaml(ctx, doc.Bytes())
+	return doc.Bytes(), nil
 }
 
 func parseResponseScriptFromJSON(ctx context.Context, data []byte) (*ResponseScript, error) {
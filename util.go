@@ -2,12 +2,15 @@ package rio
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Try to get length of object
@@ -183,6 +186,120 @@ func getInt64(value interface{}) (int64, bool) {
 	return 0, false
 }
 
+// getExactInt64 converts to int64 only if value is an integer without losing precision
+func getExactInt64(value interface{}) (int64, bool) {
+	switch value.(type) {
+	case float64, float32:
+		return 0, false
+	}
+
+	return getInt64(value)
+}
+
+// compareNumbers returns -1, 0 or +1 if actual is less than, equal to or greater than expected
+// Integers are compared as int64 to avoid losing precision of large numbers
+func compareNumbers(actual interface{}, expected interface{}) (int, bool) {
+	if a, ok := getExactInt64(actual); ok {
+		if e, ok := getExactInt64(expected); ok {
+			return cmp.Compare(a, e), true
+		}
+	}
+
+	a, ok := getFloat64(actual)
+	if !ok {
+		return 0, false
+	}
+
+	e, ok := getFloat64(expected)
+	if !ok {
+		return 0, false
+	}
+
+	return cmp.Compare(a, e), true
+}
+
+// getSlice converts an array or slice to a slice of interface
+func getSlice(value interface{}) ([]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+
+	return values, true
+}
+
+// getRange gets min and max from an array of two numbers
+func getRange(value interface{}) (interface{}, interface{}, bool) {
+	values, ok := value.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, nil, false
+	}
+
+	for _, v := range values {
+		if _, ok := getFloat64(v); !ok {
+			return nil, nil, false
+		}
+	}
+
+	return values[0], values[1], true
+}
+
+// parseTimeExpression parses RFC3339 date or relative expression to the given current time
+// Relative expression is "now" with an optional offset. For example: now-1h, now+30m, now-7d
+func parseTimeExpression(s string, now time.Time) (time.Time, error) {
+	offset, ok := strings.CutPrefix(strings.TrimSpace(s), "now")
+	if !ok {
+		return time.Parse(time.RFC3339, s)
+	}
+
+	if len(offset) == 0 {
+		return now, nil
+	}
+
+	if offset[0] != '+' && offset[0] != '-' {
+		return time.Time{}, fmt.Errorf("invalid relative date %s", s)
+	}
+
+	// Day is not supported by time.ParseDuration
+	if days, ok := strings.CutSuffix(offset, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative date %s", s)
+		}
+
+		return now.AddDate(0, 0, n), nil
+	}
+
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid relative date %s", s)
+	}
+
+	return now.Add(d), nil
+}
+
+// getTime converts RFC3339 date string to time
+func getTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
+
 func cloneStringMap(m map[string]string) map[string]string {
 	if m == nil {
 		return nil
@@ -0,0 +1,623 @@
+package rio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/hungdv136/rio/internal/log"
+	"github.com/hungdv136/rio/internal/types"
+)
+
+// The maximum number of near misses are returned when verification is failed
+const maxNearMisses = 3
+
+// Defines conditions to verify the number of matched requests
+const (
+	CountExactly CountCondition = "exactly"
+	CountAtLeast CountCondition = "at_least"
+	CountAtMost  CountCondition = "at_most"
+	CountNever   CountCondition = "never"
+)
+
+// CountCondition is alias for the condition of count constraint
+type CountCondition string
+
+// Count defines the constraint for the number of matched requests
+type Count struct {
+	Condition CountCondition `json:"condition" yaml:"condition"`
+	Value     int            `json:"value" yaml:"value"`
+}
+
+// Exactly expects the number of matched requests is equal to n
+func Exactly(n int) Count {
+	return Count{Condition: CountExactly, Value: n}
+}
+
+// AtLeast expects the number of matched requests is greater than or equal to n
+func AtLeast(n int) Count {
+	return Count{Condition: CountAtLeast, Value: n}
+}
+
+// AtMost expects the number of matched requests is less than or equal to n
+func AtMost(n int) Count {
+	return Count{Condition: CountAtMost, Value: n}
+}
+
+// Never expects there is no matched request
+func Never() Count {
+	return Count{Condition: CountNever}
+}
+
+// Validate validates count constraint
+func (c Count) Validate(ctx context.Context) error {
+	switch c.Condition {
+	case CountExactly, CountAtLeast, CountAtMost, CountNever:
+	default:
+		err := fmt.Errorf("unsupported count condition %s", c.Condition)
+		log.Error(ctx, err)
+		return err
+	}
+
+	if c.Value < 0 {
+		err := fmt.Errorf("invalid count value %d", c.Value)
+		log.Error(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// IsSatisfied checks whether the number of matched requests satisfies the constraint
+func (c Count) IsSatisfied(n int) bool {
+	switch c.Condition {
+	case CountExactly:
+		return n == c.Value
+	case CountAtLeast:
+		return n >= c.Value
+	case CountAtMost:
+		return n <= c.Value
+	case CountNever:
+		return n == 0
+	}
+
+	return false
+}
+
+func (c Count) String() string {
+	if c.Condition == CountNever {
+		return string(c.Condition)
+	}
+
+	return fmt.Sprintf("%s %d", c.Condition, c.Value)
+}
+
+// Verification defines criteria to verify captured requests
+type Verification struct {
+	Namespace string           `json:"namespace" yaml:"namespace"`
+	Request   *RequestMatching `json:"request" yaml:"request"`
+	Count     Count            `json:"count" yaml:"count"`
+}
+
+// Validate validates verification criteria
+func (v *Verification) Validate(ctx context.Context) error {
+	if v.Request == nil {
+		err := errors.New("missing request matching")
+		log.Error(ctx, err)
+		return err
+	}
+
+	if err := v.Request.Validate(ctx); err != nil {
+		return err
+	}
+
+	return v.Count.Validate(ctx)
+}
+
+// Mismatch describes a matching rule which is not satisfied by a request
+type Mismatch struct {
+	// Field is one of the following values: method, url, path, header, query, cookie, body, stream, scenario
+	Field string `json:"field" yaml:"field"`
+
+	// Key is header name, cookie name, query name, key path of body, stream scope or scenario name
+	Key string `json:"key,omitempty" yaml:"key"`
+
+	Operator OperatorName `json:"operator" yaml:"operator"`
+	Expected interface{}  `json:"expected" yaml:"expected"`
+	Actual   interface{}  `json:"actual" yaml:"actual"`
+
+	// Reason is provided if the rule cannot be evaluated. For example: content type is mismatched
+	Reason string `json:"reason,omitempty" yaml:"reason"`
+}
+
+func (m *Mismatch) String() string {
+	field := m.Field
+	if len(m.Key) > 0 {
+		field = fmt.Sprintf("%s[%s]", m.Field, m.Key)
+	}
+
+	s := fmt.Sprintf("%s: expected %s %v, actual %v", field, m.Operator, m.Expected, m.Actual)
+	if len(m.Reason) > 0 {
+		s += " (" + m.Reason + ")"
+	}
+
+	return s
+}
+
+// NearMiss is a request which is closest to the matching rules
+type NearMiss struct {
+	Request    *IncomingRequest `json:"request" yaml:"request"`
+	Mismatches []*Mismatch      `json:"mismatches" yaml:"mismatches"`
+}
+
+// VerificationResult is the result of verification
+type VerificationResult struct {
+	Verified   bool        `json:"verified" yaml:"verified"`
+	Expected   Count       `json:"expected" yaml:"expected"`
+	Actual     int         `json:"actual" yaml:"actual"`
+	MatchedIDs []int64     `json:"matched_ids" yaml:"matched_ids"`
+	NearMisses []*NearMiss `json:"near_misses" yaml:"near_misses"`
+}
+
+// String returns the human readable result which can be used as the failure message in unit test
+func (r *VerificationResult) String() string {
+	if r.Verified {
+		return fmt.Sprintf("verified: expected %s, actual %d", r.Expected, r.Actual)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("verification failed: expected %s, actual %d", r.Expected, r.Actual))
+	if len(r.MatchedIDs) > 0 {
+		sb.WriteString(fmt.Sprintf("\nmatched requests: %v", r.MatchedIDs))
+	}
+
+	for _, nearMiss := range r.NearMisses {
+		sb.WriteString(fmt.Sprintf("\nnear miss request %d %s %s", nearMiss.Request.ID, nearMiss.Request.Method, nearMiss.Request.URL))
+		for _, m := range nearMiss.Mismatches {
+			sb.WriteString("\n  - " + m.String())
+		}
+	}
+
+	return sb.String()
+}
+
+// VerifyRequests verifies captured requests with the verification criteria
+// If verification is failed, the closest near misses are returned
+func VerifyRequests(ctx context.Context, requests []*IncomingRequest, v *Verification) (*VerificationResult, error) {
+	if err := v.Validate(ctx); err != nil {
+		return nil, err
+	}
+
+	result := &VerificationResult{Expected: v.Count, MatchedIDs: []int64{}, NearMisses: []*NearMiss{}}
+	nearMisses := make([]*NearMiss, 0, len(requests))
+
+	for _, r := range requests {
+		mismatches, err := r.Evaluate(ctx, v.Request)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(mismatches) == 0 {
+			result.MatchedIDs = append(result.MatchedIDs, r.ID)
+			continue
+		}
+
+		nearMisses = append(nearMisses, &NearMiss{Request: r, Mismatches: mismatches})
+	}
+
+	result.Actual = len(result.MatchedIDs)
+	result.Verified = v.Count.IsSatisfied(result.Actual)
+	if result.Verified {
+		return result, nil
+	}
+
+	// The fewer mismatches, the closer. Keep the original order (latest first) for the same number of mismatches
+	sort.SliceStable(nearMisses, func(i, j int) bool {
+		return len(nearMisses[i].Mismatches) < len(nearMisses[j].Mismatches)
+	})
+
+	if len(nearMisses) > maxNearMisses {
+		nearMisses = nearMisses[:maxNearMisses]
+	}
+
+	result.NearMisses = nearMisses
+	return result, nil
+}
+
+// Evaluate evaluates all matching rules against the captured request
+// Unlike matching, it does not stop at the first unsatisfied rule, all mismatches are returned
+func (i *IncomingRequest) Evaluate(ctx context.Context, rm *RequestMatching) ([]*Mismatch, error) {
+	if rm.Method == MethodGrpc || i.Method == MethodGrpc {
+		return evaluateIncomingGrpcRequest(ctx, rm, i), nil
+	}
+
+	r, err := i.ReserveRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = contextWithRequestPath(ctx, trimBasePath(r.URL.EscapedPath(), i.Namespace, defaultBasePath))
+	if len(i.Host) > 0 {
+		ctx = contextWithRequestOrigin(ctx, newRequestOrigin(i.Scheme, i.Host))
+	}
+
+	return evaluateHTTPRequest(ctx, rm, r), nil
+}
+
+type evaluator struct {
+	mismatches []*Mismatch
+}
+
+func (e *evaluator) evaluate(ctx context.Context, field string, key string, op Operator, actual interface{}) {
+	matched, err := Match(ctx, op, actual)
+	if err != nil {
+		e.add(field, key, op, actual, err.Error())
+		return
+	}
+
+	if !matched {
+		e.add(field, key, op, actual, explain(ctx, op, actual))
+	}
+}
+
+// explain returns the reason why the value is not matched with the operator if it is available
+func explain(ctx context.Context, op Operator, actual interface{}) string {
+	if op.Name == OpJSONSchema {
+		return explainJSONSchema(ctx, op, actual)
+	}
+
+	return ""
+}
+
+// evaluateValues evaluates a field which may have multiple values by the quantifier of field operator
+func (e *evaluator) evaluateValues(ctx context.Context, field string, op FieldOperator, defaultQuantifier Quantifier, values []string) {
+	quantifier := op.GetQuantifier(defaultQuantifier)
+	actual := quantifiedValue(quantifier, values)
+
+	matched, err := MatchValues(ctx, quantifier, op.Operator, values)
+	if err != nil {
+		e.add(field, op.FieldName, op.Operator, actual, err.Error())
+		return
+	}
+
+	if !matched {
+		e.add(field, op.FieldName, op.Operator, actual, explain(ctx, op.Operator, actual))
+	}
+}
+
+func (e *evaluator) add(field string, key string, op Operator, actual interface{}, reason string) {
+	e.mismatches = append(e.mismatches, &Mismatch{
+		Field:    field,
+		Key:      key,
+		Operator: op.Name,
+		Expected: op.expected(),
+		Actual:   actual,
+		Reason:   reason,
+	})
+}
+
+func evaluateHTTPRequest(ctx context.Context, rm *RequestMatching, r *http.Request) []*Mismatch {
+	e := &evaluator{}
+
+	if len(rm.Method) > 0 && !strings.EqualFold(rm.Method, r.Method) {
+		e.add("method", "", Operator{Name: OpEqualTo, Value: rm.Method}, r.Method, "")
+	}
+
+	origin := getRequestOrigin(ctx, r)
+	if len(rm.Scheme) > 0 && !strings.EqualFold(rm.Scheme, origin.Scheme) {
+		e.add("scheme", "", Operator{Name: OpEqualTo, Value: rm.Scheme}, origin.Scheme, "")
+	}
+
+	for _, op := range rm.Host {
+		e.evaluate(ctx, "host", "", op, origin.Host)
+	}
+
+	for _, op := range rm.Port {
+		e.evaluate(ctx, "port", "", op, origin.Port)
+	}
+
+	for _, op := range rm.URL {
+		e.evaluate(ctx, "url", "", op, r.URL.String())
+	}
+
+	requestPath := getRequestPath(ctx, r.URL)
+	if _, matched, err := rm.matchPath(ctx, requestPath); err != nil {
+		e.add("path", "", Operator{Name: opPathTemplate, Value: rm.Path}, requestPath, err.Error())
+	} else if !matched {
+		e.add("path", "", Operator{Name: opPathTemplate, Value: rm.Path}, requestPath, "")
+	}
+
+	for _, op := range rm.Header {
+		e.evaluateValues(ctx, "header", op, QuantifierFirst, r.Header.Values(op.FieldName))
+	}
+
+	query := r.URL.Query()
+	for _, op := range rm.Query {
+		e.evaluateValues(ctx, "query", op, QuantifierFirst, query[op.FieldName])
+	}
+
+	for _, op := range rm.Cookie {
+		var val string
+		if cookie, err := r.Cookie(op.FieldName); err == nil {
+			val = cookie.Value
+		}
+
+		e.evaluate(ctx, "cookie", op.FieldName, op.Operator, val)
+	}
+
+	evaluateHTTPBody(ctx, e, rm, r)
+	evaluateGraphQL(ctx, e, rm, r)
+	return e.mismatches
+}
+
+func evaluateHTTPBody(ctx context.Context, e *evaluator, rm *RequestMatching, r *http.Request) {
+	if len(rm.Body) == 0 {
+		return
+	}
+
+	var body []byte
+	if r.Body != nil {
+		buf := bytes.Buffer{}
+		if _, err := buf.ReadFrom(readRequestBody(r)); err != nil {
+			log.Error(ctx, "cannot read body", err)
+		}
+
+		body = buf.Bytes()
+	}
+
+	contentType := r.Header.Get(HeaderContentType)
+	getValue := bodyValueGetter(ctx, r, contentType, body)
+
+	for _, op := range rm.Body {
+		if !isBodyContentTypeMatched(contentType, op) {
+			e.add("body", op.KeyPath, op.Operator, nil, fmt.Sprintf("mismatch request and operator content type %s - %s", contentType, op.ContentType))
+			continue
+		}
+
+		if op.Raw {
+			e.evaluate(ctx, "body", op.KeyPath, op.Operator, string(body))
+			continue
+		}
+
+		val, err := getValue(op)
+		if err != nil {
+			e.add("body", op.KeyPath, op.Operator, nil, err.Error())
+			continue
+		}
+
+		e.evaluate(ctx, "body", op.KeyPath, op.Operator, val)
+	}
+}
+
+func evaluateGraphQL(ctx context.Context, e *evaluator, rm *RequestMatching, r *http.Request) {
+	if len(rm.GraphQL) == 0 {
+		return
+	}
+
+	g, err := parseGraphQLRequest(r)
+	for _, op := range rm.GraphQL {
+		if err != nil {
+			e.add("graphql", graphQLKey(op), op.Operator, nil, err.Error())
+			continue
+		}
+
+		val, err := g.value(ctx, op)
+		if err != nil {
+			e.add("graphql", graphQLKey(op), op.Operator, nil, err.Error())
+			continue
+		}
+
+		e.evaluate(ctx, "graphql", graphQLKey(op), op.Operator, val)
+	}
+}
+
+// graphQLKey returns the key of GraphQL operator which is reported in diagnostics. For example: variables[$.id]
+func graphQLKey(op GraphQLOperator) string {
+	if len(op.KeyPath) == 0 {
+		return string(op.Scope)
+	}
+
+	return string(op.Scope) + "[" + op.KeyPath + "]"
+}
+
+// bodyValueGetter returns a function to get value from request body by a body operator
+// The body is decoded only once
+func bodyValueGetter(ctx context.Context, r *http.Request, contentType string, body []byte) func(op BodyOperator) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(contentType, ContentTypeJSON):
+		dataMap := map[string]interface{}{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&dataMap); err != nil && len(body) > 0 {
+			return func(BodyOperator) (interface{}, error) { return nil, fmt.Errorf("cannot decode json: %w", err) }
+		}
+
+		return func(op BodyOperator) (interface{}, error) {
+			return getJSONPath(ctx, op.KeyPath, dataMap)
+		}
+
+	case isXMLContentType(contentType):
+		doc, err := xmlquery.Parse(bytes.NewReader(body))
+		if err != nil {
+			return func(BodyOperator) (interface{}, error) { return nil, fmt.Errorf("cannot decode xml: %w", err) }
+		}
+
+		return func(op BodyOperator) (interface{}, error) {
+			return evaluateXMLPath(ctx, doc, op)
+		}
+
+	case strings.HasPrefix(contentType, ContentTypeMultipart):
+		if err := r.ParseMultipartForm(1024 * 1024 * 20 << 20); err != nil {
+			return func(BodyOperator) (interface{}, error) {
+				return nil, fmt.Errorf("cannot parse multipart body: %w", err)
+			}
+		}
+
+		return func(op BodyOperator) (interface{}, error) {
+			return r.FormValue(op.KeyPath), nil
+		}
+
+	case strings.HasPrefix(contentType, ContentTypeForm):
+		return func(op BodyOperator) (interface{}, error) {
+			return r.FormValue(op.KeyPath), nil
+		}
+	}
+
+	return func(BodyOperator) (interface{}, error) {
+		return nil, fmt.Errorf("unsupported content type %s", contentType)
+	}
+}
+
+// evaluateGrpcRequest evaluates all matching rules against the grpc request and its metadata
+func evaluateGrpcRequest(ctx context.Context, rm *RequestMatching, r *GrpcRequest, md map[string][]string) []*Mismatch {
+	e := &evaluator{}
+	if rm.Method != MethodGrpc {
+		e.add("method", "", Operator{Name: OpEqualTo, Value: rm.Method}, MethodGrpc, "")
+		return e.mismatches
+	}
+
+	e.evaluateGrpcMetadata(ctx, rm, r.FullMethod, md)
+	e.evaluateGrpcMessages(ctx, rm, r)
+	return e.mismatches
+}
+
+// evaluateIncomingGrpcRequest evaluates the captured grpc request whose body holds the received messages
+func evaluateIncomingGrpcRequest(ctx context.Context, rm *RequestMatching, i *IncomingRequest) []*Mismatch {
+	e := &evaluator{}
+	if rm.Method != i.Method {
+		e.add("method", "", Operator{Name: OpEqualTo, Value: rm.Method}, i.Method, "")
+		return e.mismatches
+	}
+
+	md := make(map[string][]string, len(i.Header))
+	for name := range i.Header {
+		md[name], _ = i.Header.GetArrayString(name)
+	}
+
+	e.evaluateGrpcMetadata(ctx, rm, i.URL, md)
+
+	messages, err := decodeGrpcMessages(i.Body)
+	if err != nil {
+		for _, op := range rm.Body {
+			e.add("body", op.KeyPath, op.Operator, nil, err.Error())
+		}
+
+		for _, op := range rm.Stream {
+			e.add("stream", string(op.Scope), op.Operator, nil, err.Error())
+		}
+
+		return e.mismatches
+	}
+
+	r := &GrpcRequest{FullMethod: i.URL, Messages: messages}
+	if len(messages) > 0 {
+		r.InputData = messages[0]
+	}
+
+	e.evaluateGrpcMessages(ctx, rm, r)
+	return e.mismatches
+}
+
+func (e *evaluator) evaluateGrpcMetadata(ctx context.Context, rm *RequestMatching, fullMethod string, md map[string][]string) {
+	for _, op := range rm.URL {
+		e.evaluate(ctx, "url", "", op, fullMethod)
+	}
+
+	for _, op := range rm.Header {
+		e.evaluateValues(ctx, "header", op, QuantifierAny, md[strings.ToLower(op.FieldName)])
+	}
+}
+
+func (e *evaluator) evaluateGrpcMessages(ctx context.Context, rm *RequestMatching, r *GrpcRequest) {
+	for _, op := range rm.Body {
+		val, err := getJSONPath(ctx, op.KeyPath, r.InputData)
+		if err != nil {
+			e.add("body", op.KeyPath, op.Operator, nil, err.Error())
+			continue
+		}
+
+		e.evaluate(ctx, "body", op.KeyPath, op.Operator, val)
+	}
+
+	for _, op := range rm.Stream {
+		matched, err := MatchStream(ctx, op, r.Messages)
+		if err == nil && matched {
+			continue
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		}
+
+		e.add("stream", streamKey(op), op.Operator, streamValues(ctx, op, r.Messages), reason)
+	}
+}
+
+// decodeGrpcMessages decodes the captured body of grpc request
+// The body is a JSON object for unary method or a JSON array for client streaming method
+func decodeGrpcMessages(body []byte) ([]types.Map, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	if body[0] == '[' {
+		messages := []types.Map{}
+		if err := decoder.Decode(&messages); err != nil {
+			return nil, fmt.Errorf("cannot decode messages: %w", err)
+		}
+
+		return messages, nil
+	}
+
+	message := types.Map{}
+	if err := decoder.Decode(&message); err != nil {
+		return nil, fmt.Errorf("cannot decode message: %w", err)
+	}
+
+	return []types.Map{message}, nil
+}
+
+func streamKey(op StreamOperator) string {
+	if len(op.KeyPath) == 0 {
+		return string(op.Scope)
+	}
+
+	return fmt.Sprintf("%s %s", op.Scope, op.KeyPath)
+}
+
+// streamValues returns the actual values of the selected messages which is used for reporting
+func streamValues(ctx context.Context, op StreamOperator, messages []types.Map) interface{} {
+	if op.Scope == StreamScopeCount {
+		return len(messages)
+	}
+
+	values := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		val, _ := getJSONPath(ctx, op.KeyPath, message)
+		values = append(values, val)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	switch op.Scope {
+	case StreamScopeFirst:
+		return values[0]
+	case StreamScopeLast:
+		return values[len(values)-1]
+	}
+
+	return values
+}
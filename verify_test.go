@@ -0,0 +1,213 @@
+package rio
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hungdv136/rio/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount_IsSatisfied(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, Exactly(2).IsSatisfied(2))
+	require.False(t, Exactly(2).IsSatisfied(3))
+	require.True(t, AtLeast(2).IsSatisfied(3))
+	require.False(t, AtLeast(2).IsSatisfied(1))
+	require.True(t, AtMost(2).IsSatisfied(0))
+	require.False(t, AtMost(2).IsSatisfied(3))
+	require.True(t, Never().IsSatisfied(0))
+	require.False(t, Never().IsSatisfied(1))
+
+	ctx := context.Background()
+	require.NoError(t, Never().Validate(ctx))
+	require.Error(t, Count{Condition: "between"}.Validate(ctx))
+	require.Error(t, AtLeast(-1).Validate(ctx))
+}
+
+func TestVerifyRequests(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	requestID := uuid.NewString()
+
+	newRequest := func(id int64, method string, url string, name string) *IncomingRequest {
+		return &IncomingRequest{
+			ID:     id,
+			Method: method,
+			URL:    url,
+			Header: types.Map{
+				"X-Request-Id":    []string{requestID},
+				HeaderContentType: []string{ContentTypeJSON},
+			},
+			Body: []byte(types.Map{"name": name}.ForceJSON()),
+		}
+	}
+
+	// Requests are sorted from the latest to the oldest as returned from store
+	requests := []*IncomingRequest{
+		newRequest(4, http.MethodGet, "/animal/get?id=2", "dog"),
+		newRequest(3, http.MethodPost, "/animal/create?id=1", "cat"),
+		newRequest(2, http.MethodPost, "/animal/create?id=1", "bird"),
+		newRequest(1, http.MethodPost, "/animal/create?id=1", "bird"),
+	}
+
+	request := NewStub().
+		For(http.MethodPost, Contains("animal/create")).
+		WithHeader("X-Request-Id", EqualTo(requestID)).
+		WithQuery("id", EqualTo("1")).
+		WithRequestBody(BodyJSONPath("$.name", EqualTo("bird"))).
+		Request
+
+	t.Run("verified", func(t *testing.T) {
+		t.Parallel()
+
+		for _, count := range []Count{Exactly(2), AtLeast(1), AtMost(2)} {
+			result, err := VerifyRequests(ctx, requests, &Verification{Request: request, Count: count})
+			require.NoError(t, err)
+			require.True(t, result.Verified, result.String())
+			require.Equal(t, 2, result.Actual)
+			require.Equal(t, []int64{2, 1}, result.MatchedIDs)
+			require.Empty(t, result.NearMisses)
+		}
+	})
+
+	t.Run("near_misses", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := VerifyRequests(ctx, requests, &Verification{Request: request, Count: Exactly(3)})
+		require.NoError(t, err)
+		require.False(t, result.Verified)
+		require.Equal(t, 2, result.Actual)
+		require.Len(t, result.NearMisses, 2)
+
+		// The closest request is the one with less mismatches
+		require.Equal(t, int64(3), result.NearMisses[0].Request.ID)
+		require.Len(t, result.NearMisses[0].Mismatches, 1)
+		require.Equal(t, &Mismatch{Field: "body", Key: "$.name", Operator: OpEqualTo, Expected: "bird", Actual: "cat"}, result.NearMisses[0].Mismatches[0])
+
+		require.Equal(t, int64(4), result.NearMisses[1].Request.ID)
+		require.Len(t, result.NearMisses[1].Mismatches, 4)
+		require.Equal(t, "method", result.NearMisses[1].Mismatches[0].Field)
+		require.Equal(t, "url", result.NearMisses[1].Mismatches[1].Field)
+		require.Equal(t, "query", result.NearMisses[1].Mismatches[2].Field)
+		require.Equal(t, "body", result.NearMisses[1].Mismatches[3].Field)
+
+		require.True(t, strings.Contains(result.String(), "body[$.name]: expected equal_to bird, actual cat"), result.String())
+	})
+
+	t.Run("never", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := VerifyRequests(ctx, requests, &Verification{Request: request, Count: Never()})
+		require.NoError(t, err)
+		require.False(t, result.Verified)
+		require.Equal(t, []int64{2, 1}, result.MatchedIDs)
+
+		notFoundRequest := NewStub().For(http.MethodDelete, Contains("animal")).Request
+		result, err = VerifyRequests(ctx, requests, &Verification{Request: notFoundRequest, Count: Never()})
+		require.NoError(t, err)
+		require.True(t, result.Verified)
+	})
+
+	t.Run("content_type_mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		xmlRequest := NewStub().For(http.MethodPost, Contains("animal/create")).WithRequestBody(BodyXMLPath("//name", EqualTo("bird"))).Request
+		result, err := VerifyRequests(ctx, requests, &Verification{Request: xmlRequest, Count: AtLeast(1)})
+		require.NoError(t, err)
+		require.False(t, result.Verified)
+		require.NotEmpty(t, result.NearMisses)
+		require.Contains(t, result.NearMisses[0].Mismatches[0].Reason, "mismatch request and operator content type")
+	})
+
+	t.Run("raw_body", func(t *testing.T) {
+		t.Parallel()
+
+		rawRequest := NewStub().For(http.MethodPost, Contains("animal/create")).WithRequestBody(BodyRaw(Contains(`"name":"bird"`))).Request
+		result, err := VerifyRequests(ctx, requests, &Verification{Request: rawRequest, Count: Exactly(2)})
+		require.NoError(t, err)
+		require.True(t, result.Verified, result.String())
+		require.Equal(t, []int64{2, 1}, result.MatchedIDs)
+	})
+
+	t.Run("origin", func(t *testing.T) {
+		t.Parallel()
+
+		hostRequests := []*IncomingRequest{
+			{ID: 2, Method: http.MethodGet, URL: "/animal", Host: "api.partner.com:8443", Scheme: SchemeHTTPS},
+			{ID: 1, Method: http.MethodGet, URL: "/animal", Host: "api.partner.com", Scheme: SchemeHTTP},
+		}
+
+		hostRequest := NewStub().For(http.MethodGet, Contains("animal")).WithHost(EqualTo("api.partner.com")).WithScheme(SchemeHTTPS).WithPort(EqualTo("8443")).Request
+		result, err := VerifyRequests(ctx, hostRequests, &Verification{Request: hostRequest, Count: Exactly(1)})
+		require.NoError(t, err)
+		require.True(t, result.Verified, result.String())
+		require.Equal(t, []int64{2}, result.MatchedIDs)
+
+		result, err = VerifyRequests(ctx, hostRequests, &Verification{Request: hostRequest, Count: Exactly(2)})
+		require.NoError(t, err)
+		require.False(t, result.Verified)
+		require.Len(t, result.NearMisses, 1)
+		require.Equal(t, []*Mismatch{
+			{Field: "scheme", Operator: OpEqualTo, Expected: SchemeHTTPS, Actual: SchemeHTTP},
+			{Field: "port", Operator: OpEqualTo, Expected: "8443", Actual: "80"},
+		}, result.NearMisses[0].Mismatches)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := VerifyRequests(ctx, requests, &Verification{Count: Exactly(1)})
+		require.Error(t, err)
+
+		_, err = VerifyRequests(ctx, requests, &Verification{Request: request})
+		require.Error(t, err)
+	})
+}
+
+func TestIncomingRequest_EvaluateGrpc(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fullMethod := "/events.v1.EventService/Upload"
+	request := &IncomingRequest{
+		Method: MethodGrpc,
+		URL:    fullMethod,
+		Header: types.Map{"x-request-id": []interface{}{"123"}, "x-tag": []interface{}{"a", "b"}},
+		Body:   []byte(`[{"id": "1", "name": "created"}, {"id": "2", "name": "updated"}]`),
+	}
+
+	matched := NewStub().
+		ForGRPC(EqualTo(fullMethod)).
+		WithHeader("X-Request-Id", EqualTo("123")).
+		WithHeader("X-Tag", EqualTo("b")).
+		WithHeaderValues("X-Tag", QuantifierValues, ContainsAll("a", "b")).
+		WithRequestBody(BodyJSONPath("$.id", EqualTo("1"))).
+		WithRequestStream(StreamLast("$.name", EqualTo("updated"))).
+		WithRequestStream(StreamCount(EqualTo(2)))
+	mismatches, err := request.Evaluate(ctx, matched.Request)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	unmatched := NewStub().
+		ForGRPC(EqualTo(fullMethod)).
+		WithHeaderValues("X-Tag", QuantifierAll, EqualTo("a")).
+		WithRequestStream(StreamAll("$.name", EqualTo("created"))).
+		WithRequestStream(StreamCount(EqualTo(3)))
+	mismatches, err = request.Evaluate(ctx, unmatched.Request)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 3)
+	require.Equal(t, []string{"a", "b"}, mismatches[0].Actual)
+	require.Equal(t, []interface{}{"created", "updated"}, mismatches[1].Actual)
+	require.Equal(t, 2, mismatches[2].Actual)
+
+	mismatches, err = request.Evaluate(ctx, NewStub().For(http.MethodGet, Contains("animal")).Request)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	require.Equal(t, "method", mismatches[0].Field)
+}
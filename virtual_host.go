@@ -0,0 +1,172 @@
+package rio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Defines supported schemes of request matching
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+)
+
+// Defines the headers which are set by proxies to forward the original host and scheme
+const (
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+)
+
+// requestOrigin is the scheme, host and port which the client used to send the request
+type requestOrigin struct {
+	Scheme string
+	Host   string
+	Port   string
+}
+
+type requestOriginContextKey struct{}
+
+// contextWithRequestOrigin saves the origin of a captured request, which cannot be derived from the restored request
+func contextWithRequestOrigin(ctx context.Context, origin *requestOrigin) context.Context {
+	return context.WithValue(ctx, requestOriginContextKey{}, origin)
+}
+
+// getRequestOrigin returns the origin in context if it is available, otherwise it is parsed from the request without forwarded headers
+func getRequestOrigin(ctx context.Context, r *http.Request) *requestOrigin {
+	if origin, ok := ctx.Value(requestOriginContextKey{}).(*requestOrigin); ok {
+		return origin
+	}
+
+	return parseRequestOrigin(r, false)
+}
+
+// parseRequestOrigin parses the origin of request
+// The forwarded headers are preferred if they are trusted, which is the case rio is behind a proxy
+// Otherwise they are ignored since any client can send them
+func parseRequestOrigin(r *http.Request, trustForwarded bool) *requestOrigin {
+	scheme := SchemeHTTP
+	switch {
+	case trustForwarded && len(r.Header.Get(HeaderXForwardedProto)) > 0:
+		scheme = firstHeaderValue(r.Header.Get(HeaderXForwardedProto))
+	case r.TLS != nil:
+		scheme = SchemeHTTPS
+	case len(r.URL.Scheme) > 0:
+		scheme = r.URL.Scheme
+	}
+
+	host := r.Host
+	if forwardedHost := r.Header.Get(HeaderXForwardedHost); trustForwarded && len(forwardedHost) > 0 {
+		host = firstHeaderValue(forwardedHost)
+	} else if len(host) == 0 {
+		host = r.URL.Host
+	}
+
+	return newRequestOrigin(scheme, host)
+}
+
+// newRequestOrigin creates origin from scheme and host which may contain port
+// The default port of scheme is used if host does not contain port
+func newRequestOrigin(scheme string, host string) *requestOrigin {
+	origin := &requestOrigin{Scheme: strings.ToLower(scheme), Host: host}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		origin.Host = h
+		origin.Port = p
+	}
+
+	origin.Host = strings.ToLower(strings.TrimSuffix(origin.Host, "."))
+	if len(origin.Port) == 0 {
+		origin.Port = defaultPort(origin.Scheme)
+	}
+
+	return origin
+}
+
+// HostPort returns host with port if port is not the default port of scheme
+func (o *requestOrigin) HostPort() string {
+	if len(o.Port) == 0 || o.Port == defaultPort(o.Scheme) {
+		return o.Host
+	}
+
+	return net.JoinHostPort(o.Host, o.Port)
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case SchemeHTTP:
+		return "80"
+	case SchemeHTTPS:
+		return "443"
+	}
+
+	return ""
+}
+
+// A forwarded header may contain a list of values if there are multiple proxies. The first one is the client's value
+func firstHeaderValue(v string) string {
+	first, _, _ := strings.Cut(v, ",")
+	return strings.TrimSpace(first)
+}
+
+func isValidScheme(scheme string) bool {
+	return strings.EqualFold(scheme, SchemeHTTP) || strings.EqualFold(scheme, SchemeHTTPS)
+}
+
+// HostBindings maps hosts to namespaces, so that a single mock server can impersonate several domains
+// The requests to a bound host are handled in the namespace without base path. For example: https://partner.com/v1/users
+// A host can be a wildcard which matches any sub domains. For example: *.partner.com
+type HostBindings map[string]string
+
+// ParseHostBindings parses the host bindings from a comma separated list of host=namespace
+// For example: partner-a.com=partner_a,*.partner-b.com=partner_b
+func ParseHostBindings(s string) (HostBindings, error) {
+	bindings := HostBindings{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		host, namespace, ok := strings.Cut(item, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		namespace = strings.TrimSpace(namespace)
+		if !ok || len(host) == 0 || len(namespace) == 0 {
+			return nil, fmt.Errorf("invalid host binding %s, expected host=namespace", item)
+		}
+
+		// A bare wildcard would bind every host including the admin host
+		if suffix, ok := strings.CutPrefix(host, "*"); ok && (!strings.HasPrefix(suffix, ".") || len(suffix) < 2) {
+			return nil, fmt.Errorf("invalid wildcard host %s, expected *.<domain>", host)
+		}
+
+		bindings[host] = namespace
+	}
+
+	return bindings, nil
+}
+
+// Namespace returns the namespace which is bound to the host of request
+// The exact host is preferred to wildcard hosts, and the longest wildcard host is preferred if there are many
+// The forwarded host header is used only if it is trusted
+func (b HostBindings) Namespace(r *http.Request, trustForwarded bool) (string, bool) {
+	host := parseRequestOrigin(r, trustForwarded).Host
+	if namespace, ok := b[host]; ok {
+		return namespace, true
+	}
+
+	matched := ""
+	for pattern := range b {
+		suffix, ok := strings.CutPrefix(pattern, "*")
+		if ok && strings.HasSuffix(host, suffix) && len(pattern) > len(matched) {
+			matched = pattern
+		}
+	}
+
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	return b[matched], true
+}
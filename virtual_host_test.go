@@ -0,0 +1,115 @@
+package rio
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestOrigin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newRequest := func(requestURL string, host string, header map[string]string) *http.Request {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		require.NoError(t, err)
+		if len(host) > 0 {
+			req.Host = host
+		}
+
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		return req
+	}
+
+	tlsRequest := newRequest("/users", "secure.com", nil)
+	tlsRequest.TLS = &tls.ConnectionState{}
+
+	testCases := []struct {
+		Name     string
+		Request  *http.Request
+		Expected *requestOrigin
+		HostPort string
+	}{
+		{"default_port", newRequest("/users", "Partner.com", nil), &requestOrigin{Scheme: "http", Host: "partner.com", Port: "80"}, "partner.com"},
+		{"explicit_port", newRequest("/users", "partner.com:8080", nil), &requestOrigin{Scheme: "http", Host: "partner.com", Port: "8080"}, "partner.com:8080"},
+		{"tls", tlsRequest, &requestOrigin{Scheme: "https", Host: "secure.com", Port: "443"}, "secure.com"},
+		{"absolute_url", newRequest("https://api.com:8443/users", "", nil), &requestOrigin{Scheme: "https", Host: "api.com", Port: "8443"}, "api.com:8443"},
+		{
+			"forwarded",
+			newRequest("/users", "rio:8896", map[string]string{HeaderXForwardedHost: "partner.com, proxy.com", HeaderXForwardedProto: "https"}),
+			&requestOrigin{Scheme: "https", Host: "partner.com", Port: "443"},
+			"partner.com",
+		},
+		{"ipv6", newRequest("/users", "[::1]:9000", nil), &requestOrigin{Scheme: "http", Host: "::1", Port: "9000"}, "[::1]:9000"},
+	}
+
+	for _, tc := range testCases {
+		origin := parseRequestOrigin(tc.Request, true)
+		require.Equal(t, tc.Expected, origin, tc.Name)
+		require.Equal(t, tc.HostPort, origin.HostPort(), tc.Name)
+		require.Equal(t, tc.Expected, newRequestOrigin(origin.Scheme, origin.HostPort()), tc.Name)
+	}
+
+	// The forwarded headers are ignored if they are not trusted
+	forwarded := newRequest("/users", "rio:8896", map[string]string{HeaderXForwardedHost: "partner.com", HeaderXForwardedProto: "https"})
+	require.Equal(t, &requestOrigin{Scheme: "http", Host: "rio", Port: "8896"}, parseRequestOrigin(forwarded, false))
+}
+
+func TestParseHostBindings(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bindings, err := ParseHostBindings(" partner-a.com=a, *.partner-b.com=b ,*.api.partner-b.com=api_b,")
+	require.NoError(t, err)
+	require.Equal(t, HostBindings{"partner-a.com": "a", "*.partner-b.com": "b", "*.api.partner-b.com": "api_b"}, bindings)
+
+	testCases := []struct {
+		Host      string
+		Namespace string
+		Found     bool
+	}{
+		{"partner-a.com", "a", true},
+		{"PARTNER-A.com:8080", "a", true},
+		{"www.partner-a.com", "", false},
+		{"www.partner-b.com", "b", true},
+		{"v1.api.partner-b.com", "api_b", true},
+		{"partner-b.com", "", false},
+	}
+
+	for _, tc := range testCases {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req.Host = tc.Host
+
+		namespace, found := bindings.Namespace(req, false)
+		require.Equal(t, tc.Found, found, tc.Host)
+		require.Equal(t, tc.Namespace, namespace, tc.Host)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	req.Host = "rio:8896"
+	req.Header.Set(HeaderXForwardedHost, "partner-a.com")
+
+	_, found := bindings.Namespace(req, false)
+	require.False(t, found)
+
+	namespace, found := bindings.Namespace(req, true)
+	require.True(t, found)
+	require.Equal(t, "a", namespace)
+
+	empty, err := ParseHostBindings("")
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	for _, s := range []string{"partner.com", "=a", "partner.com=", "*=a", "*.=a", "*partner.com=a"} {
+		_, err := ParseHostBindings(s)
+		require.Error(t, err, s)
+	}
+}
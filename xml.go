@@ -0,0 +1,137 @@
+package rio
+
+import (
+	"context"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/hungdv136/rio/internal/log"
+)
+
+var defaultXMLPathCompiler = &xmlPathCompiler{}
+
+// isXMLContentType checks whether content type is text/xml, application/xml or a xml based type such as application/soap+xml
+func isXMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == ContentTypeXML || mediaType == ContentTypeAppXML || strings.HasSuffix(mediaType, "+xml")
+}
+
+// evaluateXMLPath evaluates xpath expression of the operator against the xml document
+// The result is one of the following values
+//   - nil if no node is selected
+//   - inner text if a single node is selected
+//   - list of inner texts if many nodes are selected
+//   - string, number or boolean for other expressions such as count(//book) or boolean(//book)
+func evaluateXMLPath(ctx context.Context, doc *xmlquery.Node, op BodyOperator) (interface{}, error) {
+	expr, err := defaultXMLPathCompiler.compile(ctx, op.KeyPath, op.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	val := expr.evaluate(doc)
+	iter, ok := val.(*xpath.NodeIterator)
+	if !ok {
+		return val, nil
+	}
+
+	var values []interface{}
+	for iter.MoveNext() {
+		values = append(values, iter.Current().Value())
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0], nil
+	default:
+		return values, nil
+	}
+}
+
+// xmlPath is a compiled xpath expression
+// The expression keeps the state of evaluation, so it is not evaluated concurrently
+type xmlPath struct {
+	expr *xpath.Expr
+	l    sync.Mutex
+}
+
+// evaluate returns the result of expression. The selected nodes are iterated by a copy of the expression
+func (p *xmlPath) evaluate(doc *xmlquery.Node) interface{} {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	return p.expr.Evaluate(xmlquery.CreateXPathNavigator(doc))
+}
+
+type xmlPathCompiler struct {
+	exprs map[string]*xmlPath
+	l     sync.RWMutex
+}
+
+func (c *xmlPathCompiler) compile(ctx context.Context, keyPath string, namespaces map[string]string) (*xmlPath, error) {
+	key := xmlPathCacheKey(keyPath, namespaces)
+	if p := c.getFromCache(key); p != nil {
+		return p, nil
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	expr, err := xpath.CompileWithNS(keyPath, namespaces)
+	if err != nil {
+		log.Error(ctx, "cannot compile xml path", keyPath, err)
+		return nil, err
+	}
+
+	if c.exprs == nil {
+		c.exprs = map[string]*xmlPath{}
+	}
+
+	p := &xmlPath{expr: expr}
+	c.exprs[key] = p
+	return p, nil
+}
+
+func (c *xmlPathCompiler) getFromCache(key string) *xmlPath {
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	if p, ok := c.exprs[key]; ok {
+		return p
+	}
+
+	return nil
+}
+
+// xmlPathCacheKey returns the key of compiled expression. The same path with different namespaces is compiled separately
+func xmlPathCacheKey(keyPath string, namespaces map[string]string) string {
+	if len(namespaces) == 0 {
+		return keyPath
+	}
+
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var b strings.Builder
+	b.WriteString(keyPath)
+	for _, prefix := range prefixes {
+		b.WriteString("\n")
+		b.WriteString(prefix)
+		b.WriteString("=")
+		b.WriteString(namespaces[prefix])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package rio
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsXMLContentType(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isXMLContentType(ContentTypeXML))
+	require.True(t, isXMLContentType(ContentTypeAppXML))
+	require.True(t, isXMLContentType("application/soap+xml; charset=utf-8"))
+	require.False(t, isXMLContentType(ContentTypeJSON))
+	require.False(t, isXMLContentType(""))
+}
+
+func TestEvaluateXMLPath(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	doc, err := xmlquery.Parse(strings.NewReader(`<books><book id="1">Go</book><book id="2">Rust</book></books>`))
+	require.NoError(t, err)
+
+	val, err := evaluateXMLPath(ctx, doc, BodyXMLPath("//book[@id='2']", EqualTo("Rust"))())
+	require.NoError(t, err)
+	require.Equal(t, "Rust", val)
+
+	val, err = evaluateXMLPath(ctx, doc, BodyXMLPath("//book", EqualTo("Rust"))())
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"Go", "Rust"}, val)
+
+	val, err = evaluateXMLPath(ctx, doc, BodyXMLPath("//book/@id", EqualTo("1"))())
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"1", "2"}, val)
+
+	val, err = evaluateXMLPath(ctx, doc, BodyXMLPath("//magazine", Empty())())
+	require.NoError(t, err)
+	require.Nil(t, val)
+
+	val, err = evaluateXMLPath(ctx, doc, BodyXMLPath("count(//book)", EqualTo(2))())
+	require.NoError(t, err)
+	require.Equal(t, float64(2), val)
+
+	_, err = evaluateXMLPath(ctx, doc, BodyXMLPath("//book[", NotEmpty())())
+	require.Error(t, err)
+}
+
+func TestXMLPathCompiler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespaces := map[string]string{"m": "http://www.example.org/stock", "soap": "http://www.w3.org/2003/05/soap-envelope"}
+
+	p, err := defaultXMLPathCompiler.compile(ctx, "//m:StockName", namespaces)
+	require.NoError(t, err)
+
+	cached, err := defaultXMLPathCompiler.compile(ctx, "//m:StockName", map[string]string{"soap": namespaces["soap"], "m": namespaces["m"]})
+	require.NoError(t, err)
+	require.Same(t, p, cached)
+
+	other, err := defaultXMLPathCompiler.compile(ctx, "//m:StockName", map[string]string{"m": "http://www.example.org/other"})
+	require.NoError(t, err)
+	require.NotSame(t, p, other)
+
+	_, err = defaultXMLPathCompiler.compile(ctx, "//book[", nil)
+	require.Error(t, err)
+}